@@ -25,6 +25,7 @@ var jobInputVolumes []string
 var jobOutputVolumes []string
 var jobWorkingDir string
 var jobLabels []string
+var jobNodeSelector string
 
 func init() { //nolint:gochecknoinits
 	applyCmd.PersistentFlags().StringVarP(
@@ -41,6 +42,11 @@ func init() { //nolint:gochecknoinits
 		"labels", "l", []string{},
 		`List of jobTags for the job. In the format 'a,b,c,1'. All characters not matching /a-zA-Z0-9_:|-/ and all emojis will be stripped.`,
 	)
+
+	applyCmd.PersistentFlags().StringVarP(
+		&jobNodeSelector, "selector", "s", "",
+		`Only run on compute nodes with labels matching this selector - overrides node_selector in the job file (e.g. 'region=us-east-1').`,
+	)
 }
 
 var applyCmd = &cobra.Command{
@@ -159,6 +165,13 @@ var applyCmd = &cobra.Command{
 			return err
 		}
 
+		spec.Docker.Cmd = jobspec.Docker.Cmd
+		spec.Docker.TemplateArgs = jobspec.Docker.TemplateArgs
+		spec.NodeSelector = jobspec.NodeSelector
+		if jobNodeSelector != "" {
+			spec.NodeSelector = jobNodeSelector
+		}
+
 		job, err := getAPIClient().Submit(ctx, spec, deal, nil)
 		if err != nil {
 			return err
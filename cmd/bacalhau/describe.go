@@ -34,6 +34,8 @@ type shardNodeStateDescription struct {
 	State    string `yaml:"State"`
 	Status   string `yaml:"Status"`
 	ResultID string `yaml:"ResultID"`
+	// only set by verifiers that hash the results
+	ResultHash string `yaml:"ResultHash,omitempty"`
 }
 
 type shardStateDescription struct {
@@ -61,14 +63,16 @@ type jobSpecDescription struct {
 }
 
 type jobSpecDockerDescription struct {
-	Image       string   `yaml:"Image"`
-	Entrypoint  []string `yaml:"Entrypoint Command"`
-	Env         []string `yaml:"Submitted Env Variables"`
-	CPU         string   `yaml:"CPU Allocated"`
-	Memory      string   `yaml:"Memory Allocated"`
-	Inputs      []string `yaml:"Inputs"`
-	Outputs     []string `yaml:"Outputs"`
-	Annotations []string `yaml:"Annotations"`
+	Image        string   `yaml:"Image"`
+	Entrypoint   []string `yaml:"Entrypoint Command"`
+	Cmd          []string `yaml:"Cmd,omitempty"`
+	TemplateArgs bool     `yaml:"Template Args,omitempty"`
+	Env          []string `yaml:"Submitted Env Variables"`
+	CPU          string   `yaml:"CPU Allocated"`
+	Memory       string   `yaml:"Memory Allocated"`
+	Inputs       []string `yaml:"Inputs"`
+	Outputs      []string `yaml:"Outputs"`
+	Annotations  []string `yaml:"Annotations"`
 }
 
 type jobDealDescription struct {
@@ -117,6 +121,8 @@ var describeCmd = &cobra.Command{
 		jobDockerDesc := jobSpecDockerDescription{}
 		jobDockerDesc.Image = job.Spec.Docker.Image
 		jobDockerDesc.Entrypoint = job.Spec.Docker.Entrypoint
+		jobDockerDesc.Cmd = job.Spec.Docker.Cmd
+		jobDockerDesc.TemplateArgs = job.Spec.Docker.TemplateArgs
 		jobDockerDesc.Env = job.Spec.Docker.Env
 
 		jobDockerDesc.CPU = job.Spec.Resources.CPU
@@ -151,10 +157,11 @@ var describeCmd = &cobra.Command{
 				}
 			}
 			shardDescription.Nodes = append(shardDescription.Nodes, shardNodeStateDescription{
-				Node:     shard.NodeID,
-				State:    shard.State.String(),
-				Status:   shard.Status,
-				ResultID: shard.ResultsID,
+				Node:       shard.NodeID,
+				State:      shard.State.String(),
+				Status:     shard.Status,
+				ResultID:   shard.ResultsID,
+				ResultHash: shard.ResultsHash,
 			})
 			shardDescriptions[shard.ShardIndex] = shardDescription
 		}
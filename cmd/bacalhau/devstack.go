@@ -48,6 +48,12 @@ func init() { //nolint:gochecknoinits // Using init in cobra command is idomatic
 
 	setupJobSelectionCLIFlags(devstackCmd)
 	setupCapacityManagerCLIFlags(devstackCmd)
+	setupImagePolicyCLIFlags(devstackCmd)
+	setupContainerRuntimeCLIFlags(devstackCmd)
+	setupContainerSecurityCLIFlags(devstackCmd)
+	setupImagePullCLIFlags(devstackCmd)
+	setupPythonWasmCLIFlags(devstackCmd)
+	setupVerifierCLIFlags(devstackCmd)
 }
 
 var devstackCmd = &cobra.Command{
@@ -86,8 +92,22 @@ var devstackCmd = &cobra.Command{
 				return executor_util.NewNoopExecutors(cm, noop_executor.ExecutorConfig{})
 			}
 
+			podmanConfig, err := getPodmanConfig()
+			if err != nil {
+				return nil, err
+			}
+
+			dockerConfig, err := getDockerConfig()
+			if err != nil {
+				return nil, err
+			}
+
 			return executor_util.NewStandardExecutors(cm,
-				ipfsMultiAddress, fmt.Sprintf("devstacknode%d", nodeIndex))
+				ipfsMultiAddress, fmt.Sprintf("devstacknode%d", nodeIndex),
+				dockerConfig,
+				podmanConfig,
+				getPythonWasmConfig(),
+			)
 		}
 
 		// nodeIndex will be used in the future
@@ -98,16 +118,13 @@ var devstackCmd = &cobra.Command{
 				return verifier_util.NewNoopVerifiers(cm)
 			}
 
-			jobLoader := func(ctx context.Context, id string) (executor.Job, error) {
-				return ctrl.GetJob(ctx, id)
-			}
-			stateLoader := func(ctx context.Context, id string) (executor.JobState, error) {
-				return ctrl.GetJobState(ctx, id)
-			}
-			return verifier_util.NewIPFSVerifiers(cm, ipfsMultiAddress, jobLoader, stateLoader)
+			return verifier_util.NewStandardVerifiers(cm, ipfsMultiAddress, ctrl, getVerifierConfig())
 		}
 
-		jobSelectionPolicy := getJobSelectionConfig()
+		jobSelectionPolicy, err := getJobSelectionConfig()
+		if err != nil {
+			return err
+		}
 		totalResourceLimit, jobResourceLimit := getCapacityManagerConfig()
 
 		computeNodeConfig := computenode.ComputeNodeConfig{
@@ -116,10 +133,11 @@ var devstackCmd = &cobra.Command{
 				ResourceLimitTotal: totalResourceLimit,
 				ResourceLimitJob:   jobResourceLimit,
 			},
+			ImagePolicy: getImagePolicyConfig(),
 		}
 
 		portFileName := "/tmp/bacalhau-devstack.port"
-		_, err := os.Stat(portFileName)
+		_, err = os.Stat(portFileName)
 		if err == nil {
 			log.Fatal().Msgf("Found file %s - Devstack likely already running", portFileName)
 		}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/filecoin-project/bacalhau/pkg/devstack"
+	"github.com/filecoin-project/bacalhau/pkg/docker"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
 	"github.com/filecoin-project/bacalhau/pkg/ipfs"
 	jobutils "github.com/filecoin-project/bacalhau/pkg/job"
@@ -25,6 +26,7 @@ import (
 
 const CompleteStatus = "Complete"
 const DefaultDockerRunWaitSeconds = 600
+const ImageDigestResolveTimeoutSeconds = 10
 
 var (
 	dockerRunLong = templates.LongDesc(i18n.T(`
@@ -39,7 +41,17 @@ var (
 		-v QmeZRGhe4PmjctYVSVHuEiA9oSXnqmYa4kQubSHgWbjv72:/input_images \
 		-o results:/output_images \
 		dpokidov/imagemagick \
-		-- magick mogrify -resize 100x100 -quality 100 -path /output_images /input_images/*.jpg`))
+		-- magick mogrify -resize 100x100 -quality 100 -path /output_images /input_images/*.jpg
+
+		# Run a sharded job where each shard is told which files it has - with --template-args the command
+		# can use {{.JobID}}, {{.ShardIndex}}, {{.TotalShards}} and {{.InputPaths}} (e.g. {{join .InputPaths " "}}).
+		bacalhau docker run \
+		-v QmeZRGhe4PmjctYVSVHuEiA9oSXnqmYa4kQubSHgWbjv72:/input_images \
+		--sharding-glob-pattern '/input_images/*.jpg' \
+		--image-entrypoint \
+		--template-args \
+		registry.example.com/resizer \
+		-- --shard '{{.ShardIndex}}' '{{join .InputPaths " "}}'`))
 
 	// Set Defaults (probably a better way to do this)
 	ODR = NewDockerRunOptions()
@@ -60,9 +72,16 @@ type DockerRunOptions struct {
 	GPU           string
 	WorkingDir    string   // Working directory for docker
 	Labels        []string // Labels for the job on the Bacalhau network (for searching)
+	NodeSelector  string   // Only run on compute nodes with labels matching this selector
+	Network       string   // What the job can reach over the network (none, http or full)
+	Domains       []string // The domains http jobs can reach
 
-	Image      string   // Image to execute
-	Entrypoint []string // Entrypoint to the docker image
+	Image              string   // Image to execute
+	Entrypoint         []string // Entrypoint to the docker image
+	Cmd                []string // Arguments to the image's own entrypoint
+	ImageEntrypoint    bool     // Pass the command to the image's entrypoint rather than replacing it
+	TemplateArgs       bool     // Fill in each shard's values in the command
+	ResolveImageDigest bool     // Pin the image tag to the digest the registry has for it before submitting
 
 	SkipSyntaxChecking               bool                  // Verify the syntax using shellcheck
 	WaitForJobToFinish               bool                  // Wait for the job to execute before exiting
@@ -93,6 +112,9 @@ func NewDockerRunOptions() *DockerRunOptions {
 		SkipSyntaxChecking:               false,
 		WorkingDir:                       "",
 		Labels:                           []string{},
+		NodeSelector:                     "",
+		Network:                          "none",
+		Domains:                          []string{},
 		WaitForJobToFinish:               false,
 		WaitForJobToFinishAndPrintOutput: false,
 		WaitForJobTimeoutSecs:            DefaultDockerRunWaitSeconds,
@@ -104,6 +126,8 @@ func NewDockerRunOptions() *DockerRunOptions {
 		IPFSGetTimeOut: 10,
 		IsLocal:        false,
 
+		ResolveImageDigest: true,
+
 		ShardingGlobPattern: "",
 		ShardingBasePath:    ".",
 		ShardingBatchSize:   1,
@@ -177,6 +201,21 @@ func init() { //nolint:gochecknoinits,funlen // Using init in cobra command is i
 		`List of labels for the job. Enter multiple in the format '-l a -l 2'. All characters not matching /a-zA-Z0-9_:|-/ and all emojis will be stripped.`, //nolint:lll // Documentation, ok if long.
 	)
 
+	dockerRunCmd.PersistentFlags().StringVarP(
+		&ODR.NodeSelector, "selector", "s", ODR.NodeSelector,
+		`Only run on compute nodes with labels matching this selector (e.g. 'region=us-east-1,gpu in (a100, h100)').`,
+	)
+
+	dockerRunCmd.PersistentFlags().StringVar(
+		&ODR.Network, "network", ODR.Network,
+		`What the job can reach over the network: none, http (only the --domain domains, through a proxy) or full.`,
+	)
+
+	dockerRunCmd.PersistentFlags().StringSliceVar(
+		&ODR.Domains, "domain", ODR.Domains,
+		`A domain the job can reach with --network=http (e.g. example.com or *.example.com). Can be given more than once.`,
+	)
+
 	dockerRunCmd.PersistentFlags().BoolVar(
 		&ODR.WaitForJobToFinish, "wait", ODR.WaitForJobToFinish,
 		`Wait for the job to finish.`,
@@ -209,6 +248,21 @@ func init() { //nolint:gochecknoinits,funlen // Using init in cobra command is i
 	dockerRunCmd.Flags().StringVar(&ODR.DockerRunDownloadFlags.IPFSSwarmAddrs, "ipfs-swarm-addrs",
 		ODR.DockerRunDownloadFlags.IPFSSwarmAddrs, "Comma-separated list of IPFS nodes to connect to.")
 
+	dockerRunCmd.PersistentFlags().BoolVar(
+		&ODR.ImageEntrypoint, "image-entrypoint", ODR.ImageEntrypoint,
+		`Pass the command to the image's own entrypoint as its arguments rather than replacing the entrypoint with it.`,
+	)
+
+	dockerRunCmd.PersistentFlags().BoolVar(
+		&ODR.TemplateArgs, "template-args", ODR.TemplateArgs,
+		`Fill in each shard's values (e.g. {{.ShardIndex}}) in the command when it runs.`,
+	)
+
+	dockerRunCmd.PersistentFlags().BoolVar(
+		&ODR.ResolveImageDigest, "resolve-image-digest", ODR.ResolveImageDigest,
+		`Ask the registry for the digest of the image tag and submit the image pinned to it, so nodes run exactly that image (needed for nodes that only run pinned images).`, //nolint:lll // Documentation, ok if long.
+	)
+
 	dockerRunCmd.PersistentFlags().StringVar(
 		&ODR.ShardingGlobPattern, "sharding-glob-pattern", ODR.ShardingGlobPattern,
 		`Use this pattern to match files to be sharded.`,
@@ -251,7 +305,13 @@ var dockerRunCmd = &cobra.Command{
 		defer cm.Cleanup()
 		ctx := context.Background()
 		ODR.Image = cmdArgs[0]
-		ODR.Entrypoint = cmdArgs[1:]
+		if ODR.ImageEntrypoint {
+			ODR.Entrypoint = []string{}
+			ODR.Cmd = cmdArgs[1:]
+		} else {
+			ODR.Entrypoint = cmdArgs[1:]
+			ODR.Cmd = []string{}
+		}
 
 		ODR.DockerRunDownloadFlags = ipfs.DownloadSettings{
 			TimeoutSecs:    10,
@@ -273,6 +333,11 @@ var dockerRunCmd = &cobra.Command{
 			return err
 		}
 
+		networkType, err := executor.ParseNetwork(ODR.Network)
+		if err != nil {
+			return err
+		}
+
 		for _, i := range ODR.Inputs {
 			ODR.InputVolumes = append(ODR.InputVolumes, fmt.Sprintf("%s:/inputs", i))
 		}
@@ -284,6 +349,10 @@ var dockerRunCmd = &cobra.Command{
 			}
 		}
 
+		if ODR.ResolveImageDigest {
+			ODR.Image = resolveImageDigest(ctx, ODR.Image)
+		}
+
 		spec, deal, err := jobutils.ConstructDockerJob(
 			engineType,
 			verifierType,
@@ -311,8 +380,16 @@ var dockerRunCmd = &cobra.Command{
 			BasePath:    ODR.ShardingBasePath,
 			BatchSize:   ODR.ShardingBatchSize,
 		}
+		spec.Docker.Cmd = ODR.Cmd
+		spec.Docker.TemplateArgs = ODR.TemplateArgs
+		spec.NodeSelector = ODR.NodeSelector
+		spec.Network = executor.JobSpecNetwork{
+			Type:    networkType,
+			Domains: ODR.Domains,
+		}
 
-		if !ODR.SkipSyntaxChecking {
+		// the arguments to an image's entrypoint aren't necessarily a shell command
+		if !ODR.SkipSyntaxChecking && !ODR.ImageEntrypoint {
 			err = system.CheckBashSyntax(ODR.Entrypoint)
 			if err != nil {
 				return err
@@ -374,3 +451,17 @@ var dockerRunCmd = &cobra.Command{
 		return nil
 	},
 }
+
+// pin the image to the digest its tag currently points at - if we can't reach
+// the registry we carry on with the tag and let the nodes decide if that's ok
+func resolveImageDigest(ctx context.Context, image string) string {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*ImageDigestResolveTimeoutSeconds)
+	defer cancel()
+	pinned, err := docker.ResolveImageDigest(ctx, image)
+	if err != nil {
+		log.Warn().Msgf("Could not resolve digest for image %s, submitting the tag instead - nodes that require pinned images will not run it: %s", image, err)
+		return image
+	}
+	log.Info().Msgf("Resolved image %s to %s", image, pinned)
+	return pinned
+}
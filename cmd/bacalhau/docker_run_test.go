@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	"github.com/filecoin-project/bacalhau/pkg/computenode"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
 	"github.com/filecoin-project/bacalhau/pkg/publicapi"
 	"github.com/filecoin-project/bacalhau/pkg/system"
 	"github.com/filecoin-project/bacalhau/pkg/test/devstack"
@@ -680,6 +681,139 @@ func (suite *DockerRunSuite) TestRun_SubmitWorkdir() {
 	}
 }
 
+func (suite *DockerRunSuite) TestRun_SubmitNodeSelector() {
+	tests := []struct {
+		selector   string
+		error_code int
+	}{
+		{selector: "", error_code: 0},
+		{selector: "region=us-east-1", error_code: 0},
+		{selector: "region=us-east-1,gpu in (a100, h100),!spot", error_code: 0},
+		{selector: "region in (", error_code: 1},
+	}
+
+	for _, tc := range tests {
+		func() {
+			ctx := context.Background()
+			c, cm := publicapi.SetupTests(suite.T())
+			defer cm.Cleanup()
+
+			*ODR = *NewDockerRunOptions()
+
+			parsedBasedURI, _ := url.Parse(c.BaseURI)
+			host, port, _ := net.SplitHostPort(parsedBasedURI.Host)
+			_, out, err := ExecuteTestCobraCommand(suite.T(), suite.rootCmd, "docker", "run",
+				"--api-host", host,
+				"--api-port", port,
+				"--selector", tc.selector,
+				"ubuntu echo 'hello world'",
+			)
+
+			if tc.error_code != 0 {
+				require.Error(suite.T(), err)
+			} else {
+				require.NoError(suite.T(), err, "Error submitting job.")
+				job, _, err := c.Get(ctx, strings.TrimSpace(out))
+				require.NoError(suite.T(), err, "Error getting job.")
+				require.Equal(suite.T(), tc.selector, job.Spec.NodeSelector, "Job node selector != test selector.")
+			}
+		}()
+	}
+}
+
+func (suite *DockerRunSuite) TestRun_SubmitNetwork() {
+	tests := []struct {
+		args       []string
+		network    executor.Network
+		domains    []string
+		error_code int
+	}{
+		{args: []string{}, network: executor.NetworkNone, error_code: 0},
+		{args: []string{"--network", "full"}, network: executor.NetworkFull, error_code: 0},
+		{args: []string{"--network", "http", "--domain", "example.com", "--domain", "*.pypi.org"},
+			network: executor.NetworkHTTP, domains: []string{"example.com", "*.pypi.org"}, error_code: 0},
+		{args: []string{"--network", "http"}, error_code: 1},
+		{args: []string{"--network", "sometimes"}, error_code: 1},
+	}
+
+	for _, tc := range tests {
+		func() {
+			ctx := context.Background()
+			c, cm := publicapi.SetupTests(suite.T())
+			defer cm.Cleanup()
+
+			*ODR = *NewDockerRunOptions()
+
+			parsedBasedURI, _ := url.Parse(c.BaseURI)
+			host, port, _ := net.SplitHostPort(parsedBasedURI.Host)
+			args := append([]string{"docker", "run", "--api-host", host, "--api-port", port}, tc.args...)
+			_, out, err := ExecuteTestCobraCommand(suite.T(), suite.rootCmd, append(args, "ubuntu", "curl", "https://example.com")...)
+
+			if tc.error_code != 0 {
+				require.Error(suite.T(), err)
+			} else {
+				require.NoError(suite.T(), err, "Error submitting job.")
+				job, _, err := c.Get(ctx, strings.TrimSpace(out))
+				require.NoError(suite.T(), err, "Error getting job.")
+				require.Equal(suite.T(), tc.network, job.Spec.Network.Type)
+				require.ElementsMatch(suite.T(), tc.domains, job.Spec.Network.Domains)
+			}
+		}()
+	}
+}
+
+func (suite *DockerRunSuite) TestRun_ImageEntrypoint() {
+	tests := []struct {
+		args       []string
+		entrypoint []string
+		cmd        []string
+	}{
+		{args: []string{}, entrypoint: []string{"process", "--shard", "{{.ShardIndex}}"}, cmd: nil},
+		{args: []string{"--image-entrypoint"}, entrypoint: nil, cmd: []string{"process", "--shard", "{{.ShardIndex}}"}},
+	}
+
+	for _, tc := range tests {
+		func() {
+			ctx := context.Background()
+			c, cm := publicapi.SetupTests(suite.T())
+			defer cm.Cleanup()
+
+			*ODR = *NewDockerRunOptions()
+
+			parsedBasedURI, _ := url.Parse(c.BaseURI)
+			host, port, _ := net.SplitHostPort(parsedBasedURI.Host)
+			args := append([]string{"docker", "run", "--api-host", host, "--api-port", port}, tc.args...)
+			_, out, err := ExecuteTestCobraCommand(suite.T(), suite.rootCmd,
+				append(args, "ubuntu", "--", "process", "--shard", "{{.ShardIndex}}")...)
+			require.NoError(suite.T(), err, "Error submitting job.")
+
+			job, _, err := c.Get(ctx, strings.TrimSpace(out))
+			require.NoError(suite.T(), err, "Error getting job.")
+			require.ElementsMatch(suite.T(), tc.entrypoint, job.Spec.Docker.Entrypoint)
+			require.ElementsMatch(suite.T(), tc.cmd, job.Spec.Docker.Cmd)
+		}()
+	}
+
+	// templates that won't expand are caught before the job is submitted
+	// but only if the job asked for its arguments to be templated
+	c, cm := publicapi.SetupTests(suite.T())
+	defer cm.Cleanup()
+	*ODR = *NewDockerRunOptions()
+	parsedBasedURI, _ := url.Parse(c.BaseURI)
+	host, port, _ := net.SplitHostPort(parsedBasedURI.Host)
+	_, _, err := ExecuteTestCobraCommand(suite.T(), suite.rootCmd,
+		"docker", "run", "--api-host", host, "--api-port", port, "--template-args", "ubuntu", "echo", "{{.ShardNumber}}")
+	require.Error(suite.T(), err)
+
+	*ODR = *NewDockerRunOptions()
+	_, out, err := ExecuteTestCobraCommand(suite.T(), suite.rootCmd,
+		"docker", "run", "--api-host", host, "--api-port", port, "ubuntu", "echo", "{{.ShardNumber}}")
+	require.NoError(suite.T(), err)
+	job, _, err := c.Get(context.Background(), strings.TrimSpace(out))
+	require.NoError(suite.T(), err)
+	require.False(suite.T(), job.Spec.Docker.TemplateArgs)
+}
+
 func (suite *DockerRunSuite) TestRun_ExplodeVideos() {
 	const nodeCount = 1
 
@@ -2,12 +2,10 @@ package bacalhau
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	"github.com/filecoin-project/bacalhau/pkg/ipfs"
 	"github.com/filecoin-project/bacalhau/pkg/system"
-	"github.com/filecoin-project/bacalhau/pkg/verifier"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -18,8 +16,13 @@ var getDownloadFlags = ipfs.DownloadSettings{
 	IPFSSwarmAddrs: strings.Join(system.Envs[system.Production].IPFSSwarmAddresses, ","),
 }
 
+var getCombined bool
+
 func init() { //nolint:gochecknoinits
 	setupDownloadFlags(getCmd, &getDownloadFlags)
+	getCmd.Flags().BoolVar(&getCombined, "combined", false,
+		`Merge the results of every shard into a single IPFS directory on the requester node `+
+			`and print its CID instead of downloading the results.`)
 }
 
 var getCmd = &cobra.Command{
@@ -45,11 +48,18 @@ var getCmd = &cobra.Command{
 			return err
 		}
 
-		// todo: deal with jobs with a different verifier
-		if job.Spec.Verifier != verifier.VerifierIpfs {
-			return fmt.Errorf("job verifier not found: %s", job.Spec.Verifier)
+		if getCombined {
+			combined, err := getAPIClient().GetCombinedResults(context.Background(), job.ID)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("%s\n", combined.Cid)
+			return nil
 		}
 
+		// every verifier hands back results on IPFS apart from
+		// localfs which serves them over HTTP - the downloader
+		// deals with both
 		results, err := getAPIClient().GetResults(context.Background(), job.ID)
 		if err != nil {
 			return err
@@ -141,7 +141,13 @@ var listCmd = &cobra.Command{
 
 			if j.Spec.Engine == executor.EngineDocker {
 				jobDesc = append(jobDesc, j.Spec.Docker.Image)
-				jobDesc = append(jobDesc, strings.Join(j.Spec.Docker.Entrypoint, " "))
+				command := append(append([]string{}, j.Spec.Docker.Entrypoint...), j.Spec.Docker.Cmd...)
+				jobDesc = append(jobDesc, strings.Join(command, " "))
+			}
+
+			if j.Spec.Engine == executor.EngineWasm {
+				jobDesc = append(jobDesc, j.Spec.Wasm.EntryModule)
+				jobDesc = append(jobDesc, strings.Join(j.Spec.Wasm.Parameters, " "))
 			}
 
 			resolver := getAPIClient().GetJobStateResolver()
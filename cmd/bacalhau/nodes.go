@@ -0,0 +1,88 @@
+package bacalhau
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var nodesOutputFormat string
+
+func init() { //nolint:gochecknoinits // Using init in cobra command is idomatic
+	nodesCmd.PersistentFlags().BoolVar(&tableHideHeader, "hide-header", false,
+		`do not print the column headers.`)
+	nodesCmd.PersistentFlags().BoolVar(&tableNoStyle, "no-style", false, `remove all styling from table output.`)
+	nodesCmd.PersistentFlags().StringVar(
+		&nodesOutputFormat, "output", "text",
+		`The output format for the list of nodes (json or text)`,
+	)
+	nodesCmd.PersistentFlags().BoolVar(
+		&tableOutputWide, "wide", false,
+		`Print full values in the table results`,
+	)
+}
+
+var nodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "List the reputation of the compute nodes the requester node has given work to",
+	RunE: func(cmd *cobra.Command, cmdArgs []string) error {
+		nodes, err := getAPIClient().Nodes(context.Background())
+		if err != nil {
+			return err
+		}
+
+		if nodesOutputFormat == JSONFormat {
+			msgBytes, err := json.MarshalIndent(nodes, "", "    ")
+			if err != nil {
+				return err
+			}
+
+			cmd.Printf("%s\n", msgBytes)
+			return nil
+		}
+
+		t := table.NewWriter()
+		t.SetOutputMirror(cmd.OutOrStderr())
+		if !tableHideHeader {
+			t.AppendHeader(table.Row{"id", "score", "completed", "errored", "timed_out", "accepted", "rejected"})
+		}
+
+		for _, node := range nodes {
+			t.AppendRow(table.Row{
+				shortenString(node.NodeID),
+				fmt.Sprintf("%.2f", node.Score),
+				node.Completed,
+				node.Errored,
+				node.TimedOut,
+				node.Accepted,
+				node.Rejected,
+			})
+		}
+
+		if tableNoStyle {
+			t.SetStyle(table.Style{
+				Name:   "StyleDefault",
+				Box:    table.StyleBoxDefault,
+				Color:  table.ColorOptionsDefault,
+				Format: table.FormatOptionsDefault,
+				HTML:   table.DefaultHTMLOptions,
+				Options: table.Options{
+					DrawBorder:      false,
+					SeparateColumns: false,
+					SeparateFooter:  false,
+					SeparateHeader:  false,
+					SeparateRows:    false,
+				},
+				Title: table.TitleOptionsDefault,
+			})
+		} else {
+			t.SetStyle(table.StyleColoredGreenWhiteOnBlack)
+		}
+		t.Render()
+
+		return nil
+	},
+}
@@ -29,6 +29,7 @@ func init() { //nolint:gochecknoinits // Using init in cobra command is idomatic
 	RootCmd.AddCommand(getCmd)
 	RootCmd.AddCommand(listCmd)
 	RootCmd.AddCommand(describeCmd)
+	RootCmd.AddCommand(nodesCmd)
 	RootCmd.AddCommand(devstackCmd)
 	RootCmd.PersistentFlags().StringVar(
 		&apiHost, "api-host", system.Envs[system.Production].APIHost,
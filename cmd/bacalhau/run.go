@@ -9,6 +9,9 @@ import (
 //nolint:gochecknoinits
 func init() {
 	runCmd.AddCommand(runPythonCmd)
+	runCmd.AddCommand(runNodeCmd)
+	runCmd.AddCommand(runRCmd)
+	runCmd.AddCommand(runBashCmd)
 }
 
 var runCmd = &cobra.Command{
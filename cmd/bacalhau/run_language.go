@@ -0,0 +1,27 @@
+package bacalhau
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// the languages other than python all run in docker (so they're never
+// deterministic) and only need the common flags
+var runNodeCmd = newLanguageRunCmd("node", "18", "Run a javascript job on the network with node")
+var runRCmd = newLanguageRunCmd("r", "4.2.2", "Run an R job on the network")
+var runBashCmd = newLanguageRunCmd("bash", "5.2", "Run a bash script on the network")
+
+func newLanguageRunCmd(language, defaultVersion, short string) *cobra.Command {
+	options := &LanguageRunOptions{}
+	cmd := &cobra.Command{
+		Use:     language,
+		Short:   short,
+		Long:    languageRunLong,
+		Example: languageRunExample,
+		Args:    cobra.MinimumNArgs(0),
+		RunE: func(cmd *cobra.Command, cmdArgs []string) error {
+			return runLanguage(cmd, cmdArgs, language, options)
+		},
+	}
+	setupLanguageRunCLIFlags(cmd, options, defaultVersion)
+	return cmd
+}
@@ -0,0 +1,67 @@
+package bacalhau
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/publicapi"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type RunLanguageSuite struct {
+	suite.Suite
+	rootCmd *cobra.Command
+}
+
+func TestRunLanguageSuite(t *testing.T) {
+	suite.Run(t, new(RunLanguageSuite))
+}
+
+// Before each test
+func (suite *RunLanguageSuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+	suite.rootCmd = RootCmd
+}
+
+func (suite *RunLanguageSuite) TestRun_Languages() {
+	tests := []struct {
+		args     []string
+		language string
+		version  string
+	}{
+		{args: []string{"node", "-c", "console.log(1)"}, language: "node", version: "18"},
+		{args: []string{"r", "-c", "print(1)", "--language-version", "4.1.0"}, language: "r", version: "4.1.0"},
+		{args: []string{"bash", "-c", "echo 1"}, language: "bash", version: "5.2"},
+		{args: []string{"python", "-c", "print(1)", "--deterministic=false"}, language: "python", version: "3.10"},
+	}
+
+	for _, tc := range tests {
+		func() {
+			ctx := context.Background()
+			c, cm := publicapi.SetupTests(suite.T())
+			defer cm.Cleanup()
+
+			parsedBasedURI, _ := url.Parse(c.BaseURI)
+			host, port, _ := net.SplitHostPort(parsedBasedURI.Host)
+			args := append([]string{"run"}, tc.args...)
+			args = append(args, "--api-host", host, "--api-port", port, "--context-path", "")
+			_, out, err := ExecuteTestCobraCommand(suite.T(), suite.rootCmd, args...)
+			require.NoError(suite.T(), err, tc.language)
+
+			job, _, err := c.Get(ctx, strings.TrimSpace(out))
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), executor.EngineLanguage, job.Spec.Engine)
+			require.Equal(suite.T(), tc.language, job.Spec.Language.Language)
+			require.Equal(suite.T(), tc.version, job.Spec.Language.LanguageVersion)
+			require.False(suite.T(), job.Spec.Language.Deterministic)
+			require.Equal(suite.T(), tc.args[2], job.Spec.Language.Command)
+		}()
+	}
+}
@@ -20,7 +20,9 @@ import (
 
 var (
 	languageRunLong = templates.LongDesc(i18n.T(`
-		Runs a job by compiling language file to WASM on the node.
+		Runs a program (a file in the context, or inline with --command) on
+		the network. Deterministic python is compiled to WASM on the node,
+		everything else runs on the language's official docker image.
 		`))
 
 	languageRunExample = templates.Examples(i18n.T(`
@@ -40,7 +42,9 @@ type LanguageRunOptions struct {
 	Env           []string // Array of environment variables
 	Concurrency   int      // Number of concurrent jobs to run
 	Labels        []string // Labels for the job on the Bacalhau network (for searching)
+	NodeSelector  string   // Only run on compute nodes with labels matching this selector
 
+	LanguageVersion  string // Version of the language (the tag of its docker image)
 	Command          string // Command to execute
 	RequirementsPath string // Path for requirements.txt for executing with Python
 	ContextPath      string // ContextPath (code) for executing with Python
@@ -65,57 +69,75 @@ func init() {
 	runPythonCmd.PersistentFlags().BoolVar(
 		&OLR.Deterministic, "deterministic", true,
 		`Enforce determinism: run job in a single-threaded wasm runtime with `+
-			`no sources of entropy. NB: this will make the python runtime execute`+
-			`in an environment where only some librarie are supported, see `+
-			`https://pyodide.org/en/stable/usage/packages-in-pyodide.html`,
+			`no sources of entropy. NB: this will make the python runtime execute `+
+			`in an environment where only some libraries are supported, see `+
+			`https://pyodide.org/en/stable/usage/packages-in-pyodide.html - nodes `+
+			`that run python in-process only install pure python wheels that are `+
+			`in the context and listed in the requirements file. Without it the job `+
+			`runs on the official python docker image with requirements installed `+
+			`from the packages in the context`,
 	)
-	runPythonCmd.PersistentFlags().StringSliceVarP(
-		&OLR.Inputs, "inputs", "i", []string{},
+	setupLanguageRunCLIFlags(runPythonCmd, OLR, "3.10")
+}
+
+// the flags every language's run command has
+func setupLanguageRunCLIFlags(cmd *cobra.Command, options *LanguageRunOptions, defaultVersion string) {
+	cmd.PersistentFlags().StringVar(
+		&options.LanguageVersion, "language-version", defaultVersion,
+		`The version of the language to run the job with.`,
+	)
+	cmd.PersistentFlags().StringSliceVarP(
+		&options.Inputs, "inputs", "i", []string{},
 		`CIDs to use on the job. Mounts them at '/inputs' in the execution.`,
 	)
 
-	runPythonCmd.PersistentFlags().StringSliceVarP(
-		&OLR.InputVolumes, "input-volumes", "v", []string{},
+	cmd.PersistentFlags().StringSliceVarP(
+		&options.InputVolumes, "input-volumes", "v", []string{},
 		`CID:path of the input data volumes`,
 	)
-	runPythonCmd.PersistentFlags().StringSliceVarP(
-		&OLR.OutputVolumes, "output-volumes", "o", []string{},
+	cmd.PersistentFlags().StringSliceVarP(
+		&options.OutputVolumes, "output-volumes", "o", []string{},
 		`name:path of the output data volumes`,
 	)
-	runPythonCmd.PersistentFlags().StringSliceVarP(
-		&OLR.Env, "env", "e", []string{},
+	cmd.PersistentFlags().StringSliceVarP(
+		&options.Env, "env", "e", []string{},
 		`The environment variables to supply to the job (e.g. --env FOO=bar --env BAR=baz)`,
 	)
 	// TODO: concurrency should be factored out (at least up to run, maybe
 	// shared with docker and wasm raw commands too)
-	runPythonCmd.PersistentFlags().IntVar(
-		&OLR.Concurrency, "concurrency", 1,
+	cmd.PersistentFlags().IntVar(
+		&options.Concurrency, "concurrency", 1,
 		`How many nodes should run the job`,
 	)
-	runPythonCmd.PersistentFlags().StringVarP(
-		&OLR.Command, "command", "c", "",
+	cmd.PersistentFlags().StringVarP(
+		&options.Command, "command", "c", "",
 		`Program passed in as string (like python)`,
 	)
-	runPythonCmd.PersistentFlags().StringVarP(
-		&OLR.RequirementsPath, "requirement", "r", "",
-		`Install from the given requirements file. (like pip)`, // TODO: This option can be used multiple times.
+	cmd.PersistentFlags().StringVarP(
+		&options.RequirementsPath, "requirement", "r", "",
+		// TODO: This option can be used multiple times.
+		`Install from the given requirements file. (like pip for python, or a package tarball in the context per line for node and r)`,
 	)
-	runPythonCmd.PersistentFlags().StringVar(
+	cmd.PersistentFlags().StringVar(
 		// TODO: consider replacing this with context-glob, default to
 		// "./**/*.py|./requirements.txt", OR .bacalhau_ignore
-		&OLR.ContextPath, "context-path", ".",
+		&options.ContextPath, "context-path", ".",
 		"Path to context (e.g. python code) to send to server (via public IPFS network) "+
 			"for execution (max 10MiB). Set to empty string to disable",
 	)
-	runPythonCmd.PersistentFlags().StringVar(
-		&OLR.Verifier, "verifier", "ipfs",
+	cmd.PersistentFlags().StringVar(
+		&options.Verifier, "verifier", "ipfs",
 		`What verification engine to use to run the job`,
 	)
 
-	runPythonCmd.PersistentFlags().StringSliceVarP(
-		&OLR.Labels, "labels", "l", []string{},
+	cmd.PersistentFlags().StringSliceVarP(
+		&options.Labels, "labels", "l", []string{},
 		`List of labels for the job. Enter multiple in the format '-l a -l 2'. All characters not matching /a-zA-Z0-9_:|-/ and all emojis will be stripped.`, //nolint:lll // Documentation, ok if long.
 	)
+	cmd.PersistentFlags().StringVarP(
+		&options.NodeSelector, "selector", "s", "",
+		`Only run on compute nodes with labels matching this selector (e.g. 'region=us-east-1,gpu in (a100, h100)').`,
+	)
 }
 
 // TODO: move the adapter code (from wasm to docker) into a wasm executor, so
@@ -129,87 +151,84 @@ var runPythonCmd = &cobra.Command{
 	Long:    languageRunLong,
 	Example: languageRunExample,
 	Args:    cobra.MinimumNArgs(0),
-	RunE: func(cmd *cobra.Command, cmdArgs []string) error { //nolint
+	RunE: func(cmd *cobra.Command, cmdArgs []string) error {
+		return runLanguage(cmd, cmdArgs, "python", OLR)
+	},
+}
 
-		// error if determinism is false
-		if !OLR.Deterministic {
-			return fmt.Errorf("determinism=false not supported yet " +
-				"(python only supports wasm backend with forced determinism)")
-		}
+//nolint:funlen
+func runLanguage(cmd *cobra.Command, cmdArgs []string, language string, options *LanguageRunOptions) error {
+	var programPath string
+	if len(cmdArgs) > 0 {
+		programPath = cmdArgs[0]
+	}
+
+	if options.Command == "" && programPath == "" {
+		return fmt.Errorf("must specify an inline command or a path to a %s file", language)
+	}
 
-		// TODO: prepare context
+	for _, i := range options.Inputs {
+		options.InputVolumes = append(options.InputVolumes, fmt.Sprintf("%s:/inputs", i))
+	}
 
-		var programPath string
-		if len(cmdArgs) > 0 {
-			programPath = cmdArgs[0]
-		}
+	//nolint:lll // it's ok to be long
+	// TODO: #450 These two code paths make me nervous - the fact that we have ConstructLanguageJob and ConstructDockerJob as separate means manually keeping them in sync.
+	spec, deal, err := job.ConstructLanguageJob(
+		options.InputVolumes,
+		options.InputUrls,
+		options.OutputVolumes,
+		[]string{}, // no env vars (yet)
+		options.Concurrency,
+		language,
+		options.LanguageVersion,
+		options.Command,
+		programPath,
+		options.RequirementsPath,
+		options.ContextPath,
+		options.Deterministic,
+		options.Labels,
+		doNotTrack,
+	)
+	if err != nil {
+		return err
+	}
+	spec.NodeSelector = options.NodeSelector
 
-		if OLR.Command == "" && programPath == "" {
-			return fmt.Errorf("must specify an inline command or a path to a python file")
-		}
+	var buf bytes.Buffer
 
-		for _, i := range ODR.Inputs {
-			OLR.InputVolumes = append(OLR.InputVolumes, fmt.Sprintf("%s:/inputs", i))
-		}
+	if options.ContextPath == "." && options.RequirementsPath == "" && programPath == "" {
+		log.Info().Msgf("no program or requirements specified, not uploading context - set --context-path to full path to force context upload")
+		options.ContextPath = ""
+	}
 
-		//nolint:lll // it's ok to be long
-		// TODO: #450 These two code paths make me nervous - the fact that we have ConstructLanguageJob and ConstructDockerJob as separate means manually keeping them in sync.
-		spec, deal, err := job.ConstructLanguageJob(
-			OLR.InputVolumes,
-			OLR.InputUrls,
-			OLR.OutputVolumes,
-			[]string{}, // no env vars (yet)
-			OLR.Concurrency,
-			"python",
-			"3.10",
-			OLR.Command,
-			programPath,
-			OLR.RequirementsPath,
-			OLR.ContextPath,
-			OLR.Deterministic,
-			OLR.Labels,
-			doNotTrack,
-		)
+	if options.ContextPath != "" {
+		// construct a tar file from the contextPath directory
+		// tar + gzip
+		log.Info().Msgf("uploading %s to server to execute command in context, press Ctrl+C to cancel", options.ContextPath)
+		time.Sleep(1 * time.Second)
+		err = compress(options.ContextPath, &buf)
 		if err != nil {
 			return err
 		}
 
-		var buf bytes.Buffer
-
-		if OLR.ContextPath == "." && OLR.RequirementsPath == "" && programPath == "" {
-			log.Info().Msgf("no program or requirements specified, not uploading context - set --context-path to full path to force context upload")
-			OLR.ContextPath = ""
+		// check size of buf
+		if buf.Len() > 10*1024*1024 {
+			return fmt.Errorf("context tar file is too large (>10MiB)")
 		}
 
-		if OLR.ContextPath != "" {
-			// construct a tar file from the contextPath directory
-			// tar + gzip
-			log.Info().Msgf("uploading %s to server to execute command in context, press Ctrl+C to cancel", OLR.ContextPath)
-			time.Sleep(1 * time.Second)
-			err = compress(OLR.ContextPath, &buf)
-			if err != nil {
-				return err
-			}
-
-			// check size of buf
-			if buf.Len() > 10*1024*1024 {
-				return fmt.Errorf("context tar file is too large (>10MiB)")
-			}
-
-		}
+	}
 
-		ctx := context.Background()
-		job, err := getAPIClient().Submit(ctx, spec, deal, &buf)
-		if err != nil {
-			return err
-		}
+	ctx := context.Background()
+	job, err := getAPIClient().Submit(ctx, spec, deal, &buf)
+	if err != nil {
+		return err
+	}
 
-		log.Debug().Msgf(
-			"submitting job with spec %+v", spec)
+	log.Debug().Msgf(
+		"submitting job with spec %+v", spec)
 
-		cmd.Printf("%s\n", job.ID)
-		return nil
-	},
+	cmd.Printf("%s\n", job.ID)
+	return nil
 }
 
 // from https://github.com/mimoo/eureka/blob/master/folders.go under Apache 2
@@ -2,19 +2,30 @@ package bacalhau
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/filecoin-project/bacalhau/pkg/capacitymanager"
 	computenode "github.com/filecoin-project/bacalhau/pkg/computenode"
 	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/docker"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	docker_executor "github.com/filecoin-project/bacalhau/pkg/executor/docker"
+	"github.com/filecoin-project/bacalhau/pkg/executor/podman"
+	pythonwasm "github.com/filecoin-project/bacalhau/pkg/executor/python_wasm"
 	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
+	"github.com/filecoin-project/bacalhau/pkg/ipfs"
 	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
 	"github.com/filecoin-project/bacalhau/pkg/publicapi"
 	"github.com/filecoin-project/bacalhau/pkg/requesternode"
 	"github.com/filecoin-project/bacalhau/pkg/system"
 	"github.com/filecoin-project/bacalhau/pkg/transport/libp2p"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/localfs"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/optimistic"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/resulthash"
 	verifier_util "github.com/filecoin-project/bacalhau/pkg/verifier/util"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -28,6 +39,8 @@ var jobSelectionDataLocality string
 var jobSelectionDataRejectStateless bool
 var jobSelectionProbeHTTP string
 var jobSelectionProbeExec string
+var jobSelectionRulesFile string
+var jobSelectionNetwork string
 var metricsPort = 2112
 var limitTotalCPU string
 var limitTotalMemory string
@@ -35,6 +48,37 @@ var limitTotalGPU string
 var limitJobCPU string
 var limitJobMemory string
 var limitJobGPU string
+var imagePolicyAllow []string
+var imagePolicyDeny []string
+var imagePolicyRequireDigest bool
+var containerRuntime string
+var podmanSocket string
+var relaxContainerSecurity bool
+var seccompProfile string
+var registryAuthFile string
+var imagePullTimeout time.Duration
+var maxImageSize string
+var prePullImages []string
+var imageCacheSize string
+var pythonWasmRuntime string
+var pythonWasmLib string
+var nodeLabels map[string]string
+var bidCollectionWindow time.Duration
+var maxNodeDisagreements int
+var minNodeReputation float64
+var shardTimeout time.Duration
+var bidStrategy string
+var verifierSampleRate float64
+var verifierIgnoreFiles []string
+var localfsResultsDir string
+var localfsResultsURL string
+var quotaJobsPerHour int
+var quotaMaxConcurrentJobs int
+var quotaMaxCPU string
+var quotaMaxMemory string
+var jobRetention time.Duration
+var jobGCInterval time.Duration
+var jobGCUnpin bool
 
 var DefaultBootstrapAddresses = []string{
 	"/ip4/35.245.115.191/tcp/1235/p2p/QmdZQ7ZbhnvWY1J12XYKGHApJ6aufKyLNSvf8jZBrBaAVL",
@@ -60,6 +104,14 @@ func setupJobSelectionCLIFlags(cmd *cobra.Command) {
 		&jobSelectionProbeExec, "job-selection-probe-exec", "",
 		`Use the result of a exec an external program to decide if we should take on the job.`,
 	)
+	cmd.PersistentFlags().StringVar(
+		&jobSelectionRulesFile, "job-selection-rules-file", "",
+		`A yaml file of rules that a job must pass for us to take it on.`,
+	)
+	cmd.PersistentFlags().StringVar(
+		&jobSelectionNetwork, "job-selection-network", "none",
+		`The most network access we'll give jobs: none, http (to the domains the job lists, through a proxy) or full.`,
+	)
 }
 
 func setupCapacityManagerCLIFlags(cmd *cobra.Command) {
@@ -89,7 +141,123 @@ func setupCapacityManagerCLIFlags(cmd *cobra.Command) {
 	)
 }
 
-func getJobSelectionConfig() computenode.JobSelectionPolicy {
+func setupImagePolicyCLIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(
+		&imagePolicyAllow, "allow-image", []string{},
+		`Only run docker images matching these patterns (e.g. 'ubuntu', 'registry.example.com/*').`,
+	)
+	cmd.PersistentFlags().StringSliceVar(
+		&imagePolicyDeny, "deny-image", []string{},
+		`Never run docker images matching these patterns (e.g. '*:latest').`,
+	)
+	cmd.PersistentFlags().BoolVar(
+		&imagePolicyRequireDigest, "require-image-digest", false,
+		`Only run docker images that are pinned to a digest (e.g. ubuntu@sha256:...).`,
+	)
+}
+
+func setupContainerRuntimeCLIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&containerRuntime, "container-runtime", "docker",
+		`What runs docker jobs: docker, or podman (which doesn't need root if it's run as an unprivileged user).`,
+	)
+	cmd.PersistentFlags().StringVar(
+		&podmanSocket, "podman-socket", "",
+		`The podman API socket (defaults to the socket 'podman system service' makes for the current user).`,
+	)
+}
+
+func setupContainerSecurityCLIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(
+		&relaxContainerSecurity, "relax-container-security", false,
+		`Run job containers with docker's defaults (root, a writable filesystem, default capabilities) rather than locked down.`,
+	)
+	cmd.PersistentFlags().StringVar(
+		&seccompProfile, "seccomp-profile", "",
+		`A seccomp profile (JSON file) for job containers (defaults to docker's default profile).`,
+	)
+}
+
+func setupImagePullCLIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&registryAuthFile, "registry-auth-file", "",
+		`Credentials for pulling images from private registries (in the format of docker's config.json).`,
+	)
+	cmd.PersistentFlags().DurationVar(
+		&imagePullTimeout, "image-pull-timeout", 10*time.Minute, //nolint:gomnd
+		`Give up on pulling a job's image after this long (0 for no limit).`,
+	)
+	cmd.PersistentFlags().StringVar(
+		&maxImageSize, "max-image-size", "",
+		`Refuse to pull images bigger than this (e.g. 500Mb, 2Gb, 8Gb).`,
+	)
+	cmd.PersistentFlags().StringSliceVar(
+		&prePullImages, "pre-pull-image", []string{},
+		`Images to pull at startup (and never remove) so jobs using them start straight away.`,
+	)
+	cmd.PersistentFlags().StringVar(
+		&imageCacheSize, "image-cache-size", "",
+		`Remove the least recently used images once the images jobs have used take up more than this (e.g. 20Gb).`,
+	)
+}
+
+func setupPythonWasmCLIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&pythonWasmRuntime, "python-wasm-runtime", "",
+		`A CPython build for WASI to run deterministic python jobs in-process (without it they run pyodide in docker).`,
+	)
+	cmd.PersistentFlags().StringVar(
+		&pythonWasmLib, "python-wasm-lib", "",
+		`The python standard library for --python-wasm-runtime (the directory with python3.x in it).`,
+	)
+}
+
+func setupQuotaCLIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().IntVar(
+		&quotaJobsPerHour, "quota-jobs-per-hour", 0,
+		`How many jobs each client can submit in an hour (0 is no limit).`,
+	)
+	cmd.PersistentFlags().IntVar(
+		&quotaMaxConcurrentJobs, "quota-max-concurrent-jobs", 0,
+		`How many of each client's jobs can be running at once (0 is no limit).`,
+	)
+	cmd.PersistentFlags().StringVar(
+		&quotaMaxCPU, "quota-max-cpu", "",
+		`Total CPU each client's running jobs can ask for, counting every execution (e.g. 500m, 2, 8).`,
+	)
+	cmd.PersistentFlags().StringVar(
+		&quotaMaxMemory, "quota-max-memory", "",
+		`Total memory each client's running jobs can ask for, counting every execution (e.g. 500Mb, 2Gb, 8Gb).`,
+	)
+}
+
+func setupJobGCCLIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().DurationVar(
+		&jobRetention, "job-retention", 0,
+		`How long to keep finished jobs before forgetting about them and removing their results (0 is forever).`,
+	)
+	cmd.PersistentFlags().DurationVar(
+		&jobGCInterval, "job-gc-interval", requesternode.DefaultJobGCInterval,
+		`How often to look for finished jobs that are older than --job-retention.`,
+	)
+	cmd.PersistentFlags().BoolVar(
+		&jobGCUnpin, "job-gc-unpin", true,
+		`Unpin the build contexts and results of forgotten jobs from the IPFS node.`,
+	)
+}
+
+func setupVerifierCLIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Float64Var(
+		&verifierSampleRate, "verifier-sample-rate", optimistic.DefaultSampleRate,
+		`The fraction of shards (0 - 1) the optimistic verifier runs on a second node to compare results.`,
+	)
+	cmd.PersistentFlags().StringSliceVar(
+		&verifierIgnoreFiles, "verifier-ignore-files", resulthash.DefaultIgnoreFiles,
+		`Files (glob patterns) the result hash verifier leaves out when comparing results.`,
+	)
+}
+
+func getJobSelectionConfig() (computenode.JobSelectionPolicy, error) {
 	// construct the job selection policy from the CLI args
 	typedJobSelectionDataLocality := computenode.Anywhere
 
@@ -104,7 +272,29 @@ func getJobSelectionConfig() computenode.JobSelectionPolicy {
 		ProbeExec:           jobSelectionProbeExec,
 	}
 
-	return jobSelectionPolicy
+	network, err := executor.ParseNetwork(jobSelectionNetwork)
+	if err != nil {
+		return jobSelectionPolicy, err
+	}
+	jobSelectionPolicy.Network = network
+
+	if jobSelectionRulesFile != "" {
+		rules, err := computenode.LoadJobSelectionRules(jobSelectionRulesFile)
+		if err != nil {
+			return jobSelectionPolicy, err
+		}
+		jobSelectionPolicy.Rules = rules
+	}
+
+	return jobSelectionPolicy, nil
+}
+
+func getBidStrategy() (requesternode.BidStrategy, error) {
+	typ, err := requesternode.ParseBidStrategyType(bidStrategy)
+	if err != nil {
+		return nil, err
+	}
+	return requesternode.NewBidStrategy(typ)
 }
 
 func getCapacityManagerConfig() (totalLimits, jobLimits capacitymanager.ResourceUsageConfig) {
@@ -125,6 +315,137 @@ func getCapacityManagerConfig() (totalLimits, jobLimits capacitymanager.Resource
 	return totalResourceLimit, jobResourceLimit
 }
 
+func getQuotaConfig() requesternode.QuotaConfig {
+	return requesternode.QuotaConfig{
+		JobsPerHour:       quotaJobsPerHour,
+		MaxConcurrentJobs: quotaMaxConcurrentJobs,
+		MaxCPU:            quotaMaxCPU,
+		MaxMemory:         quotaMaxMemory,
+	}
+}
+
+func getJobGCConfig() (requesternode.JobGCConfig, error) {
+	config := requesternode.JobGCConfig{
+		Retention: jobRetention,
+		Interval:  jobGCInterval,
+	}
+	if jobRetention > 0 && jobGCUnpin {
+		ipfsClient, err := ipfs.NewClient(ipfsConnect)
+		if err != nil {
+			return config, err
+		}
+		config.Unpinner = ipfsClient
+	}
+	return config, nil
+}
+
+func getPodmanConfig() (podman.ExecutorConfig, error) {
+	switch containerRuntime {
+	case "docker":
+		return podman.ExecutorConfig{}, nil
+	case "podman":
+		return podman.ExecutorConfig{
+			Enabled: true,
+			Socket:  podmanSocket,
+		}, nil
+	default:
+		return podman.ExecutorConfig{}, fmt.Errorf("unknown container runtime: %s", containerRuntime)
+	}
+}
+
+func getContainerSecurityConfig() (docker_executor.SecurityProfile, error) {
+	profile := docker_executor.DefaultSecurityProfile()
+	if relaxContainerSecurity {
+		profile = docker_executor.SecurityProfile{}
+	}
+	if seccompProfile != "" {
+		data, err := os.ReadFile(seccompProfile)
+		if err != nil {
+			return profile, err
+		}
+		if !json.Valid(data) {
+			return profile, fmt.Errorf("seccomp profile %s is not valid JSON", seccompProfile)
+		}
+		profile.SeccompProfile = string(data)
+	}
+	return profile, nil
+}
+
+func getDockerConfig() (docker_executor.ExecutorConfig, error) {
+	securityProfile, err := getContainerSecurityConfig()
+	if err != nil {
+		return docker_executor.ExecutorConfig{}, err
+	}
+	config := docker_executor.ExecutorConfig{
+		ImagePolicy: getImagePolicyConfig(),
+		Security:    securityProfile,
+		PullTimeout: imagePullTimeout,
+	}
+	if maxImageSize != "" {
+		config.MaxImageSize = capacitymanager.ConvertMemoryString(maxImageSize)
+		if config.MaxImageSize == 0 {
+			return config, fmt.Errorf("invalid max image size: %s", maxImageSize)
+		}
+	}
+	config.ImageCache.PrePull = prePullImages
+	if imageCacheSize != "" {
+		config.ImageCache.DiskBudget = capacitymanager.ConvertMemoryString(imageCacheSize)
+		if config.ImageCache.DiskBudget == 0 {
+			return config, fmt.Errorf("invalid image cache size: %s", imageCacheSize)
+		}
+	}
+	if registryAuthFile != "" {
+		config.RegistryAuth, err = docker.LoadRegistryAuth(registryAuthFile)
+		if err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+func getPythonWasmConfig() pythonwasm.ExecutorConfig {
+	return pythonwasm.ExecutorConfig{
+		Runtime: pythonWasmRuntime,
+		Lib:     pythonWasmLib,
+	}
+}
+
+func getVerifierConfig() verifier_util.VerifierConfig {
+	return verifier_util.VerifierConfig{
+		Optimistic: optimistic.VerifierConfig{
+			SampleRate: verifierSampleRate,
+		},
+		ResultHash: resulthash.VerifierConfig{
+			IgnoreFiles: verifierIgnoreFiles,
+		},
+	}
+}
+
+// results are served from our api server so unless we are told otherwise
+// we assume clients can reach it on the address we listen on
+func getLocalFSVerifierConfig() localfs.VerifierConfig {
+	resultsURL := localfsResultsURL
+	if resultsURL == "" {
+		host := hostAddress
+		if host == "0.0.0.0" {
+			host = "127.0.0.1"
+		}
+		resultsURL = fmt.Sprintf("http://%s:%d", host, apiPort)
+	}
+	return localfs.VerifierConfig{
+		ResultsDir: localfsResultsDir,
+		URL:        resultsURL,
+	}
+}
+
+func getImagePolicyConfig() docker.ImagePolicy {
+	return docker.ImagePolicy{
+		Allow:         imagePolicyAllow,
+		Deny:          imagePolicyDeny,
+		RequireDigest: imagePolicyRequireDigest,
+	}
+}
+
 func init() { //nolint:gochecknoinits // Using init in cobra command is idomatic
 	serveCmd.PersistentFlags().StringVar(
 		&peerConnect, "peer", "",
@@ -146,9 +467,49 @@ func init() { //nolint:gochecknoinits // Using init in cobra command is idomatic
 		&metricsPort, "metrics-port", metricsPort,
 		`The port to serve prometheus metrics on.`,
 	)
+	serveCmd.PersistentFlags().DurationVar(
+		&bidCollectionWindow, "bid-collection-window", requesternode.DefaultBidCollectionWindow,
+		`How long to collect bids on a job before choosing between them with the bid strategy (0 accepts bids as they arrive).`,
+	)
+	serveCmd.PersistentFlags().StringVar(
+		&bidStrategy, "bid-strategy", string(requesternode.BidStrategyPrice),
+		`How to choose between the bids collected for a job (price, reputation, locality or load).`,
+	)
+	serveCmd.PersistentFlags().IntVar(
+		&maxNodeDisagreements, "max-node-disagreements", 0,
+		`Stop accepting bids from nodes whose results have disagreed with other nodes this many times (0 never stops).`,
+	)
+	serveCmd.PersistentFlags().Float64Var(
+		&minNodeReputation, "min-node-reputation", 0,
+		`Stop accepting bids from nodes whose reputation score (0 - 1) falls below this (0 accepts bids from any node).`,
+	)
+	serveCmd.PersistentFlags().DurationVar(
+		&shardTimeout, "shard-timeout", 0,
+		`Count it against a node's reputation if a shard given to it has not finished after this long (0 never times out).`,
+	)
+	serveCmd.PersistentFlags().StringVar(
+		&localfsResultsDir, "localfs-results-dir", "",
+		`The folder the localfs verifier keeps results in (defaults to a temporary folder).`,
+	)
+	serveCmd.PersistentFlags().StringVar(
+		&localfsResultsURL, "localfs-results-url", "",
+		`The URL clients can reach this node's api server on to download localfs results (defaults to the host and api port).`,
+	)
+	serveCmd.PersistentFlags().StringToStringVar(
+		&nodeLabels, "labels", map[string]string{},
+		`Labels for this compute node that jobs can select on (e.g. --labels region=us-east-1,gpu=a100).`,
+	)
 
 	setupJobSelectionCLIFlags(serveCmd)
 	setupCapacityManagerCLIFlags(serveCmd)
+	setupImagePolicyCLIFlags(serveCmd)
+	setupContainerRuntimeCLIFlags(serveCmd)
+	setupContainerSecurityCLIFlags(serveCmd)
+	setupImagePullCLIFlags(serveCmd)
+	setupPythonWasmCLIFlags(serveCmd)
+	setupVerifierCLIFlags(serveCmd)
+	setupQuotaCLIFlags(serveCmd)
+	setupJobGCCLIFlags(serveCmd)
 }
 
 var serveCmd = &cobra.Command{
@@ -210,29 +571,39 @@ var serveCmd = &cobra.Command{
 			return err
 		}
 
+		podmanConfig, err := getPodmanConfig()
+		if err != nil {
+			return err
+		}
+
+		dockerConfig, err := getDockerConfig()
+		if err != nil {
+			return err
+		}
+
 		executors, err := executor_util.NewStandardExecutors(
 			cm,
 			ipfsConnect,
 			fmt.Sprintf("bacalhau-%s", hostID),
+			dockerConfig,
+			podmanConfig,
+			getPythonWasmConfig(),
 		)
 		if err != nil {
 			return err
 		}
 
-		jobLoader := func(ctx context.Context, id string) (executor.Job, error) {
-			return controller.GetJob(ctx, id)
-		}
-
-		stateLoader := func(ctx context.Context, id string) (executor.JobState, error) {
-			return controller.GetJobState(ctx, id)
+		verifierConfig := getVerifierConfig()
+		verifierConfig.LocalFS = getLocalFSVerifierConfig()
+		verifiers, err := verifier_util.NewStandardVerifiers(cm, ipfsConnect, controller, verifierConfig)
+		if err != nil {
+			return err
 		}
 
-		verifiers, err := verifier_util.NewIPFSVerifiers(cm, ipfsConnect, jobLoader, stateLoader)
+		jobSelectionPolicy, err := getJobSelectionConfig()
 		if err != nil {
 			return err
 		}
-
-		jobSelectionPolicy := getJobSelectionConfig()
 		totalResourceLimit, jobResourceLimit := getCapacityManagerConfig()
 
 		computeNodeConfig := computenode.ComputeNodeConfig{
@@ -241,11 +612,35 @@ var serveCmd = &cobra.Command{
 				ResourceLimitTotal: totalResourceLimit,
 				ResourceLimitJob:   jobResourceLimit,
 			},
+			ImagePolicy: getImagePolicyConfig(),
+			Labels:      nodeLabels,
+		}
+		// results are only orphaned once the job has been forgotten
+		if jobRetention > 0 {
+			computeNodeConfig.ResultsGCInterval = jobGCInterval
 		}
 
-		requesterNodeConfig := requesternode.RequesterNodeConfig{}
+		requesterBidStrategy, err := getBidStrategy()
+		if err != nil {
+			return err
+		}
+
+		jobGCConfig, err := getJobGCConfig()
+		if err != nil {
+			return err
+		}
+
+		requesterNodeConfig := requesternode.RequesterNodeConfig{
+			BidCollectionWindow:  bidCollectionWindow,
+			BidStrategy:          requesterBidStrategy,
+			MaxNodeDisagreements: maxNodeDisagreements,
+			MinNodeReputation:    minNodeReputation,
+			ShardTimeout:         shardTimeout,
+			Quotas:               getQuotaConfig(),
+			JobGC:                jobGCConfig,
+		}
 
-		_, err = requesternode.NewRequesterNode(
+		requesterNode, err := requesternode.NewRequesterNode(
 			cm,
 			controller,
 			verifiers,
@@ -270,6 +665,8 @@ var serveCmd = &cobra.Command{
 			apiPort,
 			controller,
 			verifiers,
+			requesterNode.GetReputationStore(),
+			requesterNode.GetQuotaManager(),
 		)
 
 		// Context ensures main goroutine waits until killed with ctrl+c:
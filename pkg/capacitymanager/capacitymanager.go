@@ -2,6 +2,7 @@ package capacitymanager
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 )
 
@@ -20,6 +21,10 @@ type Config struct {
 	// if a job does not state how much CPU or Memory is used
 	// what values should we assume?
 	ResourceRequirementsDefault ResourceUsageConfig
+	// how we discover the individual GPU devices on this node
+	// if this is not given we ask nvidia-container-cli
+	// (tests use this to fake GPUs that are not really there)
+	GPUDeviceEnumerator GPUDeviceEnumerator
 }
 
 type CapacityManagerItem struct {
@@ -57,6 +62,9 @@ type CapacityManager struct {
 	// that is based on a data store
 	// https://github.com/filecoin-project/bacalhau/issues/327
 	active *ItemMap
+
+	// the individual GPU devices we can assign to active items
+	gpuDevices *DeviceInventory
 }
 
 func NewCapacityManager( //nolint:funlen,gocyclo
@@ -79,7 +87,16 @@ func NewCapacityManager( //nolint:funlen,gocyclo
 		useConfig.ResourceRequirementsDefault.GPU = DefaultJobGPU
 	}
 
-	resourceLimitsTotal, err := getSystemResources(useConfig.ResourceLimitTotal)
+	if useConfig.GPUDeviceEnumerator == nil {
+		useConfig.GPUDeviceEnumerator = getSystemGPUDeviceIDs
+	}
+
+	gpuDeviceIDs, err := useConfig.GPUDeviceEnumerator()
+	if err != nil {
+		return nil, err
+	}
+
+	resourceLimitsTotal, err := getSystemResources(useConfig.ResourceLimitTotal, gpuDeviceIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -176,6 +193,8 @@ func NewCapacityManager( //nolint:funlen,gocyclo
 		resourceRequirementsJobDefault: resourceRequirementsJobDefault,
 		backlog:                        NewItemList(),
 		active:                         NewItemMap(),
+		// if the user has limited the total GPUs we only hand out that many devices
+		gpuDevices: NewDeviceInventory(gpuDeviceIDs[:resourceLimitsTotal.GPU]),
 	}, nil
 }
 
@@ -232,11 +251,16 @@ func (manager *CapacityManager) AddShardsToBacklog(id string, shardCount int, re
 	return nil
 }
 
+// moving an item to active also reserves the specific GPU devices it will use
 func (manager *CapacityManager) MoveToActive(id string) error {
 	item := manager.backlog.Get(id)
 	if item == nil {
 		return fmt.Errorf("job %s not in backlog", id)
 	}
+	_, err := manager.gpuDevices.Allocate(id, item.Requirements.GPU)
+	if err != nil {
+		return err
+	}
 	manager.backlog.Remove(id)
 	manager.active.Add(*item)
 	return nil
@@ -245,6 +269,12 @@ func (manager *CapacityManager) MoveToActive(id string) error {
 func (manager *CapacityManager) Remove(id string) {
 	manager.backlog.Remove(id)
 	manager.active.Remove(id)
+	manager.gpuDevices.Release(id)
+}
+
+// the IDs of the GPU devices reserved for the given active item
+func (manager *CapacityManager) GetGPUDevices(id string) []string {
+	return manager.gpuDevices.Get(id)
 }
 
 func (manager *CapacityManager) GetFreeSpace() ResourceUsageData {
@@ -260,6 +290,20 @@ func (manager *CapacityManager) GetFreeSpace() ResourceUsageData {
 	return subtractResourceUsage(currentResourceUsage, manager.resourceLimitsTotal)
 }
 
+// the fraction (0 - 1) of our CPU or memory limit that active items are
+// using - whichever is the more used
+func (manager *CapacityManager) GetLoad() float64 {
+	freeSpace := manager.GetFreeSpace()
+	load := 0.0
+	if manager.resourceLimitsTotal.CPU > 0 {
+		load = 1 - freeSpace.CPU/manager.resourceLimitsTotal.CPU
+	}
+	if manager.resourceLimitsTotal.Memory > 0 {
+		load = math.Max(load, 1-float64(freeSpace.Memory)/float64(manager.resourceLimitsTotal.Memory))
+	}
+	return load
+}
+
 // get the jobs we have capacity to bid on
 // this is done FIFO order from the order jobs have arrived
 //   - calculate "remaining resources"
@@ -537,3 +537,78 @@ func TestGetFreeSpace(t *testing.T) {
 		t.Errorf("Should be using all GPU, but got %d", res.GPU)
 	}
 }
+
+func TestGetLoad(t *testing.T) {
+	m, err := NewCapacityManager(Config{
+		ResourceLimitTotal: ResourceUsageConfig{
+			CPU:    "1",
+			Memory: "1Gi",
+			GPU:    "0",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0.0, m.GetLoad())
+
+	m.active.Add(CapacityManagerItem{
+		ID: "test",
+		Requirements: ResourceUsageData{
+			CPU:    0.25,
+			Memory: 536870912,
+		},
+	})
+	// half the memory is more than a quarter of the cpu
+	require.Equal(t, 0.5, m.GetLoad())
+}
+
+func TestGPUDeviceAssignment(t *testing.T) {
+	os.Setenv("BACALHAU_CAPACITY_MANAGER_OVER_COMMIT", "1")
+	defer os.Setenv("BACALHAU_CAPACITY_MANAGER_OVER_COMMIT", "")
+
+	// pretend we have 3 GPUs even though the test machine has none
+	m, err := NewCapacityManager(Config{
+		ResourceLimitTotal: ResourceUsageConfig{
+			CPU:    "10",
+			Memory: "10Gb",
+		},
+		GPUDeviceEnumerator: func() ([]string, error) {
+			return []string{"0", "1", "2"}, nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), m.resourceLimitsTotal.GPU)
+
+	requirements := ResourceUsageData{CPU: 1, Memory: 1024, GPU: 1}
+	require.NoError(t, m.AddToBacklog("job1:0", requirements))
+	require.NoError(t, m.AddToBacklog("job2:0", ResourceUsageData{CPU: 1, Memory: 1024, GPU: 2}))
+	require.NoError(t, m.AddToBacklog("job3:0", requirements))
+
+	// only the first two items fit onto our 3 GPUs
+	nextItems := m.GetNextItems()
+	require.Equal(t, []string{"job1:0", "job2:0"}, nextItems)
+	for _, id := range nextItems {
+		require.NoError(t, m.MoveToActive(id))
+	}
+
+	require.Equal(t, []string{"0"}, m.GetGPUDevices("job1:0"))
+	require.Equal(t, []string{"1", "2"}, m.GetGPUDevices("job2:0"))
+	require.Equal(t, []string{}, m.GetGPUDevices("job3:0"))
+
+	// once the first job finishes its device is handed to the next one
+	m.Remove("job1:0")
+	require.Equal(t, []string{"job3:0"}, m.GetNextItems())
+	require.NoError(t, m.MoveToActive("job3:0"))
+	require.Equal(t, []string{"0"}, m.GetGPUDevices("job3:0"))
+}
+
+func TestGPUDeviceLimit(t *testing.T) {
+	m, err := NewCapacityManager(Config{
+		ResourceLimitTotal: ResourceUsageConfig{
+			GPU: "1",
+		},
+		GPUDeviceEnumerator: func() ([]string, error) {
+			return []string{"0", "1"}, nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, m.gpuDevices.Count())
+}
@@ -0,0 +1,102 @@
+package capacitymanager
+
+import (
+	"fmt"
+	"time"
+
+	sync "github.com/lukemarsden/golang-mutex-tracer"
+)
+
+// keeps track of which individual GPU devices are assigned to which items
+// so that two concurrently running shards are never handed the same device
+type DeviceInventory struct {
+	// the device IDs we are allowed to hand out in the order we hand them out
+	devices []string
+	// item ID -> the device IDs allocated to that item
+	allocations map[string][]string
+	mu          sync.Mutex
+}
+
+func NewDeviceInventory(devices []string) *DeviceInventory {
+	inventory := &DeviceInventory{
+		devices:     devices,
+		allocations: map[string][]string{},
+	}
+	inventory.mu.EnableTracerWithOpts(sync.Opts{
+		Threshold: 10 * time.Millisecond,
+		Id:        "DeviceInventory.mu",
+	})
+	return inventory
+}
+
+// reserve count devices for the given item
+// if the item already has devices allocated we return those
+func (inventory *DeviceInventory) Allocate(id string, count uint64) ([]string, error) {
+	inventory.mu.Lock()
+	defer inventory.mu.Unlock()
+
+	if existing, ok := inventory.allocations[id]; ok {
+		return existing, nil
+	}
+
+	if count == 0 {
+		return []string{}, nil
+	}
+
+	free := inventory.getFreeDevices()
+	if uint64(len(free)) < count {
+		return nil, fmt.Errorf(
+			"cannot allocate %d devices for %s: only %d of %d are free",
+			count, id, len(free), len(inventory.devices),
+		)
+	}
+
+	allocated := free[:count]
+	inventory.allocations[id] = allocated
+	return allocated, nil
+}
+
+// give back any devices that were allocated to the given item
+func (inventory *DeviceInventory) Release(id string) {
+	inventory.mu.Lock()
+	defer inventory.mu.Unlock()
+	delete(inventory.allocations, id)
+}
+
+// the devices allocated to the given item (empty if there are none)
+func (inventory *DeviceInventory) Get(id string) []string {
+	inventory.mu.Lock()
+	defer inventory.mu.Unlock()
+	allocated, ok := inventory.allocations[id]
+	if !ok {
+		return []string{}
+	}
+	return allocated
+}
+
+func (inventory *DeviceInventory) GetFree() []string {
+	inventory.mu.Lock()
+	defer inventory.mu.Unlock()
+	return inventory.getFreeDevices()
+}
+
+func (inventory *DeviceInventory) Count() int {
+	return len(inventory.devices)
+}
+
+// must be called with the lock held
+func (inventory *DeviceInventory) getFreeDevices() []string {
+	used := map[string]bool{}
+	for _, allocated := range inventory.allocations {
+		for _, device := range allocated {
+			used[device] = true
+		}
+	}
+	free := []string{}
+	for _, device := range inventory.devices {
+		if !used[device] {
+			free = append(free, device)
+		}
+	}
+	return free
+}
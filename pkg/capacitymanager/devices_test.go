@@ -0,0 +1,40 @@
+package capacitymanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceInventory(t *testing.T) {
+	inventory := NewDeviceInventory([]string{"0", "1", "2"})
+	require.Equal(t, 3, inventory.Count())
+
+	first, err := inventory.Allocate("a", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0", "1"}, first)
+
+	// asking again for the same item gives the same devices
+	again, err := inventory.Allocate("a", 2)
+	require.NoError(t, err)
+	require.Equal(t, first, again)
+
+	second, err := inventory.Allocate("b", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2"}, second)
+
+	_, err = inventory.Allocate("c", 1)
+	require.Error(t, err)
+	require.Equal(t, []string{}, inventory.Get("c"))
+
+	inventory.Release("a")
+	require.Equal(t, []string{"0", "1"}, inventory.GetFree())
+
+	third, err := inventory.Allocate("c", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0"}, third)
+
+	none, err := inventory.Allocate("d", 0)
+	require.NoError(t, err)
+	require.Empty(t, none)
+}
@@ -33,3 +33,6 @@ type ResourceUsageProfile struct {
 	// what is the total amount of resources available to the system
 	SystemTotal ResourceUsageData `json:"system_total"`
 }
+
+// returns the IDs of the GPU devices present on this node
+type GPUDeviceEnumerator func() ([]string, error)
@@ -94,15 +94,16 @@ func getFreeDiskSpace(path string) (uint64, error) {
 	return fs.Bfree * uint64(fs.Bsize), nil
 }
 
-// numSystemGPUs wraps nvidia-container-cli to get the number of GPUs
-func numSystemGPUs() (uint64, error) {
+// getSystemGPUDeviceIDs wraps nvidia-container-cli to get the index of each GPU
+// these are the IDs we hand to docker when assigning devices to a job
+func getSystemGPUDeviceIDs() ([]string, error) {
 	nvidiaPath, err := exec.LookPath(NvidiaCLI)
 	if err != nil {
 		// If the NVIDIA CLI is not installed, we can't know the number of GPUs, assume zero
 		if (err.(*exec.Error)).Unwrap() == exec.ErrNotFound {
-			return 0, nil
+			return []string{}, nil
 		}
-		return 0, err
+		return nil, err
 	}
 	args := []string{
 		"info",
@@ -111,13 +112,19 @@ func numSystemGPUs() (uint64, error) {
 	cmd := exec.Command(nvidiaPath, args...)
 	resp, err := cmd.Output()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	// Parse output of nvidia-container-cli command
-	lines := strings.Split(string(resp), "\n")
+	return parseNvidiaCLIDeviceIDs(string(resp)), nil
+}
+
+// Parse output of nvidia-container-cli info --csv
+// the device table starts with a "Device Index" header row and the
+// first column of every row after that is the device index
+func parseNvidiaCLIDeviceIDs(output string) []string {
+	lines := strings.Split(output, "\n")
 	deviceInfoFlag := false
-	numDevices := uint64(0)
+	deviceIDs := []string{}
 	for _, line := range lines {
 		if strings.TrimSpace(line) == "" {
 			continue
@@ -127,16 +134,15 @@ func numSystemGPUs() (uint64, error) {
 			continue
 		}
 		if deviceInfoFlag {
-			numDevices += 1
+			deviceIDs = append(deviceIDs, strings.TrimSpace(strings.Split(line, ",")[0]))
 		}
 	}
-
-	fmt.Println(numDevices)
-	return numDevices, nil
+	return deviceIDs
 }
 
 // what resources does this compute node actually have?
-func getSystemResources(limitConfig ResourceUsageConfig) (ResourceUsageData, error) {
+// gpuDeviceIDs is the list of GPU devices we discovered on this node
+func getSystemResources(limitConfig ResourceUsageConfig, gpuDeviceIDs []string) (ResourceUsageData, error) {
 	// this is used mainly for tests to be deterministic
 	allowOverCommit := os.Getenv("BACALHAU_CAPACITY_MANAGER_OVER_COMMIT") != ""
 
@@ -144,17 +150,12 @@ func getSystemResources(limitConfig ResourceUsageConfig) (ResourceUsageData, err
 	if err != nil {
 		return ResourceUsageData{}, err
 	}
-	gpus, err := numSystemGPUs()
-	if err != nil {
-		return ResourceUsageData{}, err
-	}
-
 	// the actual resources we have
 	physcialResources := ResourceUsageData{
 		CPU:    float64(runtime.NumCPU()),
 		Memory: memory.TotalMemory(),
 		Disk:   diskSpace,
-		GPU:    gpus,
+		GPU:    uint64(len(gpuDeviceIDs)),
 	}
 
 	parsedLimitConfig := ParseResourceUsageConfig(limitConfig)
@@ -124,19 +124,19 @@ func (suite *ResourceUsageUtilsSuite) TestSystemResources() {
 			name:        "should return what the system has",
 			shouldError: false,
 			input:       c("", "", ""),
-			expected:    d(float64(runtime.NumCPU()), memory.TotalMemory(), numSystemGPUsNoError()),
+			expected:    d(float64(runtime.NumCPU()), memory.TotalMemory(), uint64(len(systemGPUDeviceIDsNoError()))),
 		},
 		{
 			name:        "should return the configured CPU amount",
 			shouldError: false,
 			input:       c("100m", "", ""),
-			expected:    d(float64(0.1), memory.TotalMemory(), numSystemGPUsNoError()),
+			expected:    d(float64(0.1), memory.TotalMemory(), uint64(len(systemGPUDeviceIDsNoError()))),
 		},
 		{
 			name:        "should return the configured Memory amount",
 			shouldError: false,
 			input:       c("", "100Mb", ""),
-			expected:    d(float64(runtime.NumCPU()), ConvertMemoryString("100Mb"), numSystemGPUsNoError()),
+			expected:    d(float64(runtime.NumCPU()), ConvertMemoryString("100Mb"), uint64(len(systemGPUDeviceIDsNoError()))),
 		},
 		{
 			name:        "should error with too many CPUs asked for",
@@ -158,7 +158,7 @@ func (suite *ResourceUsageUtilsSuite) TestSystemResources() {
 	for _, test := range tests {
 
 		suite.Run(test.name, func() {
-			resources, err := getSystemResources(test.input)
+			resources, err := getSystemResources(test.input, systemGPUDeviceIDsNoError())
 
 			if test.shouldError {
 				require.Error(suite.T(), err, "an error was expected")
@@ -263,10 +263,22 @@ func TestCheckResourceUsage(t *testing.T) {
 	}
 }
 
-func numSystemGPUsNoError() uint64 {
-	numGPUs, err := numSystemGPUs()
+func TestParseNvidiaCLIDeviceIDs(t *testing.T) {
+	output := `NVRM version,CUDA version
+470.129.06,11.4
+
+Device Index,Device Minor,Model,Brand,GPU UUID,Bus Location,Architecture
+0,0,Tesla T4,Nvidia,GPU-2d2a0a49-5b8c-3c2c-b1f4-8a3b5e8b7b1e,00000000:00:04.0,7.5
+1,1,Tesla T4,Nvidia,GPU-8a4f3e2c-0c1b-7c0a-2f7e-5e0b5e3b6a2d,00000000:00:05.0,7.5
+`
+	require.Equal(t, []string{"0", "1"}, parseNvidiaCLIDeviceIDs(output))
+	require.Equal(t, []string{}, parseNvidiaCLIDeviceIDs(""))
+}
+
+func systemGPUDeviceIDsNoError() []string {
+	deviceIDs, err := getSystemGPUDeviceIDs()
 	if err != nil {
-		return 0
+		return []string{}
 	}
-	return numGPUs
+	return deviceIDs
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/filecoin-project/bacalhau/pkg/capacitymanager"
 	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/docker"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
 	jobutils "github.com/filecoin-project/bacalhau/pkg/job"
 	"github.com/filecoin-project/bacalhau/pkg/system"
@@ -34,6 +35,17 @@ type ComputeNodeConfig struct {
 	// configure the resource capacity we are allowing for
 	// this compute node
 	CapacityManagerConfig capacitymanager.Config
+
+	// which docker images we are willing to run
+	ImagePolicy docker.ImagePolicy
+
+	// key/value labels describing this node (e.g. region=us-east-1)
+	// that jobs can target with a node selector
+	Labels map[string]string
+
+	// how often to remove the results executors have left on disk for
+	// jobs this node no longer knows about - zero means we never do
+	ResultsGCInterval time.Duration
 }
 
 type ComputeNode struct {
@@ -73,6 +85,9 @@ func NewComputeNode(
 
 	computeNode.subscriptionSetup()
 	go computeNode.controlLoopSetup(cm)
+	if config.ResultsGCInterval > 0 {
+		go computeNode.resultsGCLoopSetup(cm)
+	}
 
 	return computeNode, nil
 }
@@ -96,6 +111,11 @@ func constructComputeNode(
 		return nil, err
 	}
 
+	err = jobutils.ValidateNodeLabels(config.Labels)
+	if err != nil {
+		return nil, err
+	}
+
 	computeNode := &ComputeNode{
 		id:                      nodeID,
 		config:                  config,
@@ -176,8 +196,13 @@ func (node *ComputeNode) controlLoopBidOnJobs(debug string) {
 		}
 
 		hasAlreadyBid := false
+		// the terms we decided on when we selected the job
+		bid := executor.JobBid{}
 
 		for _, localEvent := range jobLocalEvents {
+			if localEvent.EventName == executor.JobLocalEventSelected {
+				bid = localEvent.JobBid
+			}
 			if localEvent.EventName == executor.JobLocalEventBid && localEvent.ShardIndex == shardIndex {
 				hasAlreadyBid = true
 				break
@@ -208,7 +233,9 @@ func (node *ComputeNode) controlLoopBidOnJobs(debug string) {
 			continue
 		}
 
-		err = node.BidOnJob(context.Background(), job, shardIndex)
+		bid = node.addBidNodeInfo(context.Background(), job, bid)
+
+		err = node.BidOnJob(context.Background(), job, shardIndex, bid)
 		if err != nil {
 			node.capacityManager.Remove(flatID)
 			continue
@@ -284,7 +311,7 @@ func (node *ComputeNode) subscriptionEventCreated(ctx context.Context, jobEvent
 	time.Sleep(time.Millisecond * time.Duration(jobNodeDistanceDelayMs)) //nolint:gosec
 
 	// A new job has arrived - decide if we want to bid on it:
-	selected, processedRequirements, err := node.SelectJob(ctx, JobSelectionPolicyProbeData{
+	selected, processedRequirements, bid, err := node.SelectJob(ctx, JobSelectionPolicyProbeData{
 		NodeID:        node.id,
 		JobID:         jobEvent.JobID,
 		Spec:          jobEvent.JobSpec,
@@ -296,7 +323,7 @@ func (node *ComputeNode) subscriptionEventCreated(ctx context.Context, jobEvent
 	}
 
 	if selected {
-		err = node.controller.SelectJob(ctx, jobEvent.JobID)
+		err = node.controller.SelectJob(ctx, jobEvent.JobID, bid)
 		if err != nil {
 			log.Error().Msgf("Error selecting job on host %s: %v", node.id, err)
 			return
@@ -387,7 +414,7 @@ func (node *ComputeNode) subscriptionEventBidAccepted(ctx context.Context, jobEv
 		node.controlLoopBidOnJobs("defer in bidAccepted")
 	}()
 
-	results, err := node.RunShard(ctx, job, jobEvent.ShardIndex)
+	results, resultsHash, err := node.RunShard(ctx, job, jobEvent.ShardIndex)
 	if err == nil {
 		err = node.controller.CompleteJob(
 			ctx,
@@ -395,6 +422,7 @@ func (node *ComputeNode) subscriptionEventBidAccepted(ctx context.Context, jobEv
 			jobEvent.ShardIndex,
 			fmt.Sprintf("Got job result: %s", results),
 			results,
+			resultsHash,
 		)
 
 		if err != nil {
@@ -435,19 +463,57 @@ func (node *ComputeNode) subscriptionEventBidRejected(ctx context.Context, jobEv
 */
 // ask the job selection policy if we would consider running this job
 // we return the processed resourceusage.ResourceUsageData for the job
-func (node *ComputeNode) SelectJob(ctx context.Context, data JobSelectionPolicyProbeData) (bool, capacitymanager.ResourceUsageData, error) {
+// and the terms we would bid with
+func (node *ComputeNode) SelectJob(
+	ctx context.Context,
+	data JobSelectionPolicyProbeData,
+) (bool, capacitymanager.ResourceUsageData, executor.JobBid, error) {
 	requirements := capacitymanager.ResourceUsageData{}
 
 	// check that we have the executor and it's installed
 	e, err := node.getExecutor(ctx, data.Spec.Engine)
 	if err != nil {
-		return false, requirements, fmt.Errorf("getExecutor: %v", err)
+		return false, requirements, executor.JobBid{}, fmt.Errorf("getExecutor: %v", err)
 	}
 
 	// check that we have the verifier and it's installed
 	_, err = node.getVerifier(ctx, data.Spec.Verifier)
 	if err != nil {
-		return false, requirements, fmt.Errorf("getVerifier: %v", err)
+		return false, requirements, executor.JobBid{}, fmt.Errorf("getVerifier: %v", err)
+	}
+
+	// a job that targets other nodes is not for us
+	matchesSelector, err := jobutils.MatchNodeSelector(data.Spec.NodeSelector, node.config.Labels)
+	if err != nil {
+		return false, requirements, executor.JobBid{}, err
+	}
+	if !matchesSelector {
+		log.Debug().Msgf("Compute node %s skipped bidding on job because labels do not match selector: %s",
+			node.id, data.Spec.NodeSelector)
+		return false, requirements, executor.JobBid{}, nil
+	}
+
+	// check the image before we think about bidding so we never end up
+	// pulling something the operator has not allowed
+	// - language jobs end up in images their executor picks
+	image := ""
+	if data.Spec.Engine == executor.EngineDocker {
+		image = data.Spec.Docker.Image
+	} else if resolver, ok := e.(executor.ImageResolver); ok {
+		image, err = resolver.GetJobImage(ctx, executor.Job{ID: data.JobID, Spec: data.Spec})
+		if err != nil {
+			log.Debug().Msgf("Compute node %s skipped bidding on job because we can't tell what image it needs: %s",
+				node.id, err)
+			return false, requirements, executor.JobBid{}, nil
+		}
+	}
+	if image != "" {
+		err = node.config.ImagePolicy.Check(image)
+		if err != nil {
+			log.Debug().Msgf("Compute node %s skipped bidding on job because of image policy: %s",
+				node.id, err)
+			return false, requirements, executor.JobBid{}, nil
+		}
 	}
 
 	// caculate resource requirements for this job
@@ -458,7 +524,7 @@ func (node *ComputeNode) SelectJob(ctx context.Context, data JobSelectionPolicyP
 	// this is asking the executor for GetVolumeSize
 	diskSpace, err := node.getJobDiskspaceRequirements(ctx, data.Spec)
 	if err != nil {
-		return false, requirements, fmt.Errorf("error getting job disk space requirements: %v", err)
+		return false, requirements, executor.JobBid{}, fmt.Errorf("error getting job disk space requirements: %v", err)
 	}
 
 	// TODO: think about the fact that each shard might be different sizes
@@ -475,12 +541,12 @@ func (node *ComputeNode) SelectJob(ctx context.Context, data JobSelectionPolicyP
 	if !withinCapacityLimits {
 		log.Debug().Msgf("Compute node %s skipped bidding on job because resource requirements were too much: %+v",
 			node.id, data.Spec)
-		return false, processedRequirements, nil
+		return false, processedRequirements, executor.JobBid{}, nil
 	}
 
 	// decide if we want to take on the job based on
 	// our selection policy
-	acceptedByPolicy, err := ApplyJobSelectionPolicy(
+	acceptedByPolicy, bid, err := ApplyJobSelectionPolicy(
 		ctx,
 		node.config.JobSelectionPolicy,
 		e,
@@ -488,23 +554,60 @@ func (node *ComputeNode) SelectJob(ctx context.Context, data JobSelectionPolicyP
 	)
 
 	if err != nil {
-		return false, processedRequirements, fmt.Errorf("error selecting job by policy: %v", err)
+		return false, processedRequirements, executor.JobBid{}, fmt.Errorf("error selecting job by policy: %v", err)
 	}
 
 	if !acceptedByPolicy {
 		log.Debug().Msgf("Compute node %s skipped bidding on job because policy did not pass: %s",
 			node.id, data.JobID)
-		return false, processedRequirements, nil
+		return false, processedRequirements, executor.JobBid{}, nil
 	}
 
-	return true, processedRequirements, nil
+	return true, processedRequirements, bid, nil
+}
+
+// tell the requester node how much of the job's input (and whether its image)
+// we already have and how busy we are so it can choose between the bids it gets
+// - and what we would run the job in so it can keep the executions of a shard
+// in the same runtime
+func (node *ComputeNode) addBidNodeInfo(ctx context.Context, job executor.Job, bid executor.JobBid) executor.JobBid {
+	bid.Load = node.capacityManager.GetLoad()
+	e, err := node.getExecutor(ctx, job.Spec.Engine)
+	if err != nil {
+		return bid
+	}
+	for _, input := range job.Spec.Inputs {
+		hasStorage, err := e.HasStorageLocally(ctx, input)
+		if err != nil {
+			log.Debug().Msgf("Error checking for storage resource locality: %s", err.Error())
+			continue
+		}
+		if hasStorage {
+			bid.LocalInputs++
+		}
+	}
+	if keeper, ok := e.(executor.ImageKeeper); ok && job.Spec.Engine == executor.EngineDocker {
+		hasImage, err := keeper.HasImageLocally(ctx, job.Spec.Docker.Image)
+		if err != nil {
+			log.Debug().Msgf("Error checking for image locality: %s", err.Error())
+		}
+		bid.HasImage = hasImage
+	}
+	if namer, ok := e.(executor.RuntimeNamer); ok {
+		runtime, err := namer.GetJobRuntime(ctx, job)
+		if err != nil {
+			log.Debug().Msgf("Error getting the runtime for the job: %s", err.Error())
+		}
+		bid.Runtime = runtime
+	}
+	return bid
 }
 
 // by bidding on a job - we are moving it from "backlog" to "active"
 // in the capacity manager
-func (node *ComputeNode) BidOnJob(ctx context.Context, job executor.Job, shardIndex int) error {
-	log.Debug().Msgf("Compute node %s bidding on: %s %d", node.id, job.ID, shardIndex)
-	return node.controller.BidJob(ctx, job.ID, shardIndex)
+func (node *ComputeNode) BidOnJob(ctx context.Context, job executor.Job, shardIndex int, bid executor.JobBid) error {
+	log.Debug().Msgf("Compute node %s bidding on: %s %d %+v", node.id, job.ID, shardIndex, bid)
+	return node.controller.BidJob(ctx, job.ID, shardIndex, bid)
 }
 
 /*
@@ -516,14 +619,24 @@ func (node *ComputeNode) ExecuteJobShard(ctx context.Context, job executor.Job,
 	if err != nil {
 		return "", err
 	}
-	return e.RunShard(ctx, job, shardIndex)
+	// hand over the GPUs we reserved for this shard when we bid on it
+	gpuDevices := node.capacityManager.GetGPUDevices(capacitymanager.FlattenShardID(job.ID, shardIndex))
+	// let the requester know what's going on before the job starts
+	reportProgress := func(status string) {
+		err := node.controller.RunJob(ctx, job.ID, shardIndex, status)
+		if err != nil {
+			log.Warn().Msgf("Error reporting progress on shard %s %d: %s", job.ID, shardIndex, err)
+		}
+	}
+	shardCtx := executor.ContextWithProgress(executor.ContextWithGPUDevices(ctx, gpuDevices), reportProgress)
+	return e.RunShard(shardCtx, job, shardIndex)
 }
 
 func (node *ComputeNode) RunShard(
 	ctx context.Context,
 	job executor.Job,
 	shardIndex int,
-) (string, error) {
+) (resultsID, resultsHash string, err error) {
 	resultFolder, containerRunError := node.ExecuteJobShard(ctx, job, shardIndex)
 	if containerRunError != nil {
 		jobsFailed.With(prometheus.Labels{
@@ -539,21 +652,28 @@ func (node *ComputeNode) RunShard(
 		}).Inc()
 	}
 	if resultFolder == "" {
-		err := fmt.Errorf("missing results folder for job %s", job.ID)
+		err = fmt.Errorf("missing results folder for job %s", job.ID)
 		if containerRunError != nil {
 			err = fmt.Errorf("runJob error %s: %s", job.ID, containerRunError)
 		}
-		return "", err
+		return "", "", err
 	}
-	verifier, err := node.getVerifier(ctx, job.Spec.Verifier)
+	v, err := node.getVerifier(ctx, job.Spec.Verifier)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	resultValue, err := verifier.ProcessShardResults(ctx, job.ID, shardIndex, resultFolder)
+	resultsID, err = v.ProcessShardResults(ctx, job.ID, shardIndex, resultFolder)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	// some verifiers compare a hash of the results rather than the results id
+	if hasher, ok := v.(verifier.ResultsHasher); ok {
+		resultsHash, err = hasher.HashShardResults(ctx, job.ID, shardIndex, resultFolder)
+		if err != nil {
+			return "", "", err
+		}
 	}
-	return resultValue, containerRunError
+	return resultsID, resultsHash, containerRunError
 }
 
 //nolint:dupl // methods are not duplicates
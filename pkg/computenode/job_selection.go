@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"os/exec"
 	"strings"
 
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	jobutils "github.com/filecoin-project/bacalhau/pkg/job"
 	"github.com/rs/zerolog/log"
 )
 
@@ -32,6 +34,13 @@ type JobSelectionPolicy struct {
 	// if either of these are given they will override the data locality settings
 	ProbeHTTP string `json:"probe_http,omitempty"`
 	ProbeExec string `json:"probe_exec,omitempty"`
+	// the most network access we'll give a job - jobs that want more are
+	// rejected whatever the rest of the policy says (none by default)
+	Network executor.Network `json:"network,omitempty"`
+	// expressions evaluated against the probe data that must all be true
+	// for us to take on the job - these are checked before any of the above
+	// (see job_selection_rules.go)
+	Rules []JobSelectionRule `json:"rules,omitempty"`
 }
 
 // the JSON data we send to http or exec probes
@@ -47,58 +56,111 @@ func NewDefaultJobSelectionPolicy() JobSelectionPolicy {
 	return JobSelectionPolicy{}
 }
 
+// the JSON http or exec probes can reply with
+// every field is optional and a probe that replies with something
+// other than JSON is judged on its exit code or http status alone
+type JobSelectionPolicyProbeResponse struct {
+	// overrides the exit code or http status if given
+	Accept *bool `json:"accept,omitempty"`
+	// what we will ask the requester for if we bid on the job
+	BidPrice float64 `json:"bid_price,omitempty"`
+	// how many seconds until we expect to start the job
+	ETASeconds int64 `json:"eta_seconds,omitempty"`
+	// why the job was accepted or rejected
+	Reason string `json:"reason,omitempty"`
+}
+
+// turn what a probe said into a decision and the terms we will bid with
+func processJobSelectionPolicyProbeResponse(
+	probe string,
+	accepted bool,
+	output []byte,
+) (bool, executor.JobBid) {
+	response := JobSelectionPolicyProbeResponse{}
+	err := json.Unmarshal(output, &response)
+	if err != nil {
+		// not JSON - so this is a plain accept / reject probe
+		return accepted, executor.JobBid{}
+	}
+	if response.Accept != nil {
+		accepted = *response.Accept
+	}
+	if response.Reason != "" {
+		log.Debug().Msgf("Job selection probe %s accepted=%t: %s", probe, accepted, response.Reason)
+	}
+	return accepted, executor.JobBid{
+		Price:      response.BidPrice,
+		ETASeconds: response.ETASeconds,
+		Reason:     response.Reason,
+	}
+}
+
 //nolint:unparam // will fix
 func applyJobSelectionPolicyExecProbe(
 	ctx context.Context,
 	command string,
 	data JobSelectionPolicyProbeData, //nolint:gocritic
-) (bool, error) {
+) (bool, executor.JobBid, error) {
 	// TODO: Use context to trace exec call
 
 	jsonData, err := json.Marshal(data)
 
 	if err != nil {
 		log.Error().Msgf("error marshaling job selection policy probe data: %s", err.Error())
-		return false, err
+		return false, executor.JobBid{}, err
 	}
 
+	var stdout bytes.Buffer
 	cmd := exec.Command("bash", "-c", command)
 	cmd.Env = []string{
 		"BACALHAU_JOB_SELECTION_PROBE_DATA=" + string(jsonData),
 	}
 	cmd.Stdin = strings.NewReader(string(jsonData))
+	cmd.Stdout = &stdout
 	err = cmd.Run()
 	if err != nil {
 		// we ignore this error because it might be the script exiting 1 on purpose
 		log.Debug().Msgf("We got an error back from a job selection probe exec: %s %s", command, err.Error())
 	}
 
-	return cmd.ProcessState.ExitCode() == 0, nil
+	accepted, bid := processJobSelectionPolicyProbeResponse(command, cmd.ProcessState.ExitCode() == 0, stdout.Bytes())
+	return accepted, bid, nil
 }
 
-func applyJobSelectionPolicyHTTPProbe(ctx context.Context, url string, data JobSelectionPolicyProbeData) (bool, error) {
+func applyJobSelectionPolicyHTTPProbe(
+	ctx context.Context,
+	url string,
+	data JobSelectionPolicyProbeData, //nolint:gocritic
+) (bool, executor.JobBid, error) {
 	jsonData, err := json.Marshal(data)
 
 	if err != nil {
 		log.Error().Msgf("error marshaling job selection policy probe data: %s", err.Error())
-		return false, err
+		return false, executor.JobBid{}, err
 	}
 
 	body := bytes.NewBuffer(jsonData)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
-	req.Header.Add("Content-Type", "application/json")
 	if err != nil {
 		log.Error().Msgf("could not create http request with context: %s", url)
+		return false, executor.JobBid{}, err
 	}
+	req.Header.Add("Content-Type", "application/json")
 	resp, err := http.DefaultClient.Do(req)
-	resp.Body.Close()
-
 	if err != nil {
 		log.Error().Msgf("error http POST job selection policy probe data: %s %s", url, err.Error())
-		return false, err
+		return false, executor.JobBid{}, err
+	}
+	defer resp.Body.Close()
+
+	output, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error().Msgf("error reading job selection policy probe response: %s %s", url, err.Error())
+		return false, executor.JobBid{}, err
 	}
 
-	return resp.StatusCode == http.StatusOK, nil
+	accepted, bid := processJobSelectionPolicyProbeResponse(url, resp.StatusCode == http.StatusOK, output)
+	return accepted, bid, nil
 }
 
 func applyJobSelectionPolicySettings(
@@ -152,17 +214,36 @@ func applyJobSelectionPolicySettings(
 // the compute node "SelectJob" function will call out to this to handle
 // applying the policy to the incoming job
 // we are also given the executor so we can enquire about data locality
+// if a probe is configured it can also tell us what terms to bid with
 func ApplyJobSelectionPolicy(
 	ctx context.Context,
 	policy JobSelectionPolicy,
 	e executor.Executor,
 	data JobSelectionPolicyProbeData,
-) (bool, error) {
+) (bool, executor.JobBid, error) {
+	if data.Spec.Network.Type > policy.Network {
+		log.Trace().Msgf("Job wants %s networking and we only allow %s - rejecting job", data.Spec.Network.Type, policy.Network)
+		return false, executor.JobBid{}, nil
+	}
+	// the requester should have checked this but we can't rely on it
+	if err := jobutils.ValidateNetwork(data.Spec.Network); err != nil {
+		log.Trace().Msgf("Job has an invalid network - rejecting job: %s", err)
+		return false, executor.JobBid{}, nil
+	}
+
+	if len(policy.Rules) > 0 {
+		acceptedByRules, err := applyJobSelectionPolicyRules(ctx, policy.Rules, data)
+		if err != nil || !acceptedByRules {
+			return false, executor.JobBid{}, err
+		}
+	}
+
 	if policy.ProbeExec != "" {
 		return applyJobSelectionPolicyExecProbe(ctx, policy.ProbeExec, data)
 	} else if policy.ProbeHTTP != "" {
 		return applyJobSelectionPolicyHTTPProbe(ctx, policy.ProbeHTTP, data)
 	} else {
-		return applyJobSelectionPolicySettings(ctx, policy, e, data.Spec)
+		accepted, err := applyJobSelectionPolicySettings(ctx, policy, e, data.Spec)
+		return accepted, executor.JobBid{}, err
 	}
 }
@@ -0,0 +1,141 @@
+package computenode
+
+/*
+Job selection rules let an operator describe which jobs a compute node will
+take on without running an external probe. Each rule is a boolean expression
+(see https://github.com/antonmedv/expr for the syntax) evaluated against the
+same JobSelectionPolicyProbeData we send to the http and exec probes, e.g.
+
+	rules:
+	  - Spec.Docker.Image startsWith "registry.example.com/"
+	  - cpu(Spec.Resources.CPU) <= 4
+	  - "team:x" in Spec.Annotations
+
+Every rule must evaluate to true for the job to be selected.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/filecoin-project/bacalhau/pkg/capacitymanager"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// the format of the file passed to --job-selection-rules-file
+type JobSelectionRulesFile struct {
+	Rules []string `json:"rules" yaml:"rules"`
+}
+
+// a rule along with the program it compiles to so we only
+// compile it once rather than for every job we are offered
+type JobSelectionRule struct {
+	Rule    string
+	program *vm.Program
+}
+
+// rules are written out as the expression they were compiled from
+func (rule JobSelectionRule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rule.Rule)
+}
+
+func (rule *JobSelectionRule) UnmarshalJSON(data []byte) error {
+	var source string
+	err := json.Unmarshal(data, &source)
+	if err != nil {
+		return err
+	}
+	compiled, err := compileJobSelectionRule(source)
+	if err != nil {
+		return err
+	}
+	*rule = compiled
+	return nil
+}
+
+// load and compile the rules from a yaml file so we find
+// mistakes when the node starts rather than when a job arrives
+func LoadJobSelectionRules(path string) ([]JobSelectionRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading job selection rules file %s: %w", path, err)
+	}
+	rulesFile := JobSelectionRulesFile{}
+	err = yaml.Unmarshal(data, &rulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing job selection rules file %s: %w", path, err)
+	}
+	return CompileJobSelectionRules(rulesFile.Rules)
+}
+
+func CompileJobSelectionRules(rules []string) ([]JobSelectionRule, error) {
+	compiled := []JobSelectionRule{}
+	for _, rule := range rules {
+		compiledRule, err := compileJobSelectionRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, compiledRule)
+	}
+	return compiled, nil
+}
+
+// the variables and functions a rule can use
+// the probe data fields are at the top level so rules read like "Spec.Docker.Image"
+func getJobSelectionRuleEnv(data JobSelectionPolicyProbeData) map[string]interface{} {
+	return map[string]interface{}{
+		"NodeID":        data.NodeID,
+		"JobID":         data.JobID,
+		"Spec":          data.Spec,
+		"ExecutionPlan": data.ExecutionPlan,
+
+		// resources are strings in the job spec (e.g. "500m" or "1Gb")
+		// so give rules a way to compare them as numbers
+		"cpu":    capacitymanager.ConvertCPUString,
+		"memory": capacitymanager.ConvertMemoryString,
+		"disk":   capacitymanager.ConvertMemoryString,
+		"gpu":    capacitymanager.ConvertGPUString,
+	}
+}
+
+func compileJobSelectionRule(rule string) (JobSelectionRule, error) {
+	program, err := expr.Compile(
+		rule,
+		expr.Env(getJobSelectionRuleEnv(JobSelectionPolicyProbeData{})),
+		expr.AsBool(),
+	)
+	if err != nil {
+		return JobSelectionRule{}, fmt.Errorf("invalid job selection rule %q: %w", rule, err)
+	}
+	return JobSelectionRule{
+		Rule:    rule,
+		program: program,
+	}, nil
+}
+
+func applyJobSelectionPolicyRules(
+	ctx context.Context,
+	rules []JobSelectionRule,
+	data JobSelectionPolicyProbeData, //nolint:gocritic
+) (bool, error) {
+	env := getJobSelectionRuleEnv(data)
+	for _, rule := range rules {
+		if rule.program == nil {
+			return false, fmt.Errorf("job selection rule %q has not been compiled", rule.Rule)
+		}
+		result, err := expr.Run(rule.program, env)
+		if err != nil {
+			return false, fmt.Errorf("error evaluating job selection rule %q: %w", rule.Rule, err)
+		}
+		if !result.(bool) {
+			log.Trace().Msgf("Job selection rule did not pass - rejecting job: %s", rule.Rule)
+			return false, nil
+		}
+	}
+	return true, nil
+}
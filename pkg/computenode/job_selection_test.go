@@ -3,10 +3,14 @@ package computenode
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/filecoin-project/bacalhau/pkg/capacitymanager"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
 	"github.com/filecoin-project/bacalhau/pkg/storage"
 	"github.com/filecoin-project/bacalhau/pkg/test/tooling"
@@ -28,6 +32,13 @@ func getProbeDataWithVolume() JobSelectionPolicyProbeData {
 	}
 }
 
+func getProbeDataWithNetwork(network executor.Network, domains ...string) JobSelectionPolicyProbeData {
+	data := getProbeDataWithVolume()
+	data.Spec.Network.Type = network
+	data.Spec.Network.Domains = domains
+	return data
+}
+
 func TestJobSelectionPolicy(t *testing.T) {
 
 	testCases := []struct {
@@ -106,6 +117,59 @@ func TestJobSelectionPolicy(t *testing.T) {
 			},
 			getProbeDataWithVolume(),
 		},
+
+		// the job wants the network - we don't allow it - we should reject
+		{
+			"no network -> job wants http -> should reject",
+			false,
+			true,
+			JobSelectionPolicy{},
+			getProbeDataWithNetwork(executor.NetworkHTTP, "example.com"),
+		},
+
+		// the job wants full network access - we only allow http - we should reject
+		{
+			"http network -> job wants full -> should reject",
+			false,
+			true,
+			JobSelectionPolicy{
+				Network: executor.NetworkHTTP,
+			},
+			getProbeDataWithNetwork(executor.NetworkFull),
+		},
+
+		// the job wants http - we allow full network access - we should accept
+		{
+			"full network -> job wants http -> should accept",
+			true,
+			true,
+			JobSelectionPolicy{
+				Network: executor.NetworkFull,
+			},
+			getProbeDataWithNetwork(executor.NetworkHTTP, "example.com"),
+		},
+
+		// the job's domains would let it write its own proxy config - we should reject
+		{
+			"http network -> job has invalid domains -> should reject",
+			false,
+			true,
+			JobSelectionPolicy{
+				Network: executor.NetworkHTTP,
+			},
+			getProbeDataWithNetwork(executor.NetworkHTTP, "example.com\nhttp_access allow all"),
+		},
+
+		// the job wants to reach an IP address - we should reject
+		{
+			"http network -> job wants an IP address -> should reject",
+			false,
+			true,
+			JobSelectionPolicy{
+				Network: executor.NetworkHTTP,
+			},
+			getProbeDataWithNetwork(executor.NetworkHTTP, "169.254.169.254"),
+		},
 	}
 
 	for _, test := range testCases {
@@ -113,7 +177,7 @@ func TestJobSelectionPolicy(t *testing.T) {
 			suite := tooling.NewTestSuite()
 			executor, err := tooling.NewNoopExecutor(suite.Cm, tooling.HasStorageNoopExecutorConfig(test.hasStorageLocally))
 			require.NoError(t, err)
-			result, err := ApplyJobSelectionPolicy(
+			result, _, err := ApplyJobSelectionPolicy(
 				context.Background(),
 				test.policy,
 				executor,
@@ -171,7 +235,7 @@ func TestJobSelectionHttp(t *testing.T) {
 			}))
 			defer svr.Close()
 			require.NoError(t, err)
-			result, err := ApplyJobSelectionPolicy(
+			result, _, err := ApplyJobSelectionPolicy(
 				context.Background(),
 				JobSelectionPolicy{
 					ProbeHTTP: svr.URL,
@@ -215,7 +279,7 @@ func TestJobSelectionExec(t *testing.T) {
 			if test.failMode {
 				command = "exit 1"
 			}
-			result, err := ApplyJobSelectionPolicy(
+			result, _, err := ApplyJobSelectionPolicy(
 				context.Background(),
 				JobSelectionPolicy{
 					ProbeExec: command,
@@ -228,3 +292,208 @@ func TestJobSelectionExec(t *testing.T) {
 		})
 	}
 }
+
+func TestJobSelectionRules(t *testing.T) {
+	data := JobSelectionPolicyProbeData{
+		NodeID: "node-id",
+		JobID:  "job-id",
+		Spec: executor.JobSpec{
+			Docker: executor.JobSpecDocker{
+				Image: "registry.example.com/ubuntu:latest",
+			},
+			Resources: capacitymanager.ResourceUsageConfig{
+				CPU:    "500m",
+				Memory: "1Gb",
+			},
+			Annotations: []string{"team:x"},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		rules          []string
+		expectedResult bool
+	}{
+		{
+			"image prefix matches -> should accept",
+			[]string{`Spec.Docker.Image startsWith "registry.example.com/"`},
+			true,
+		},
+		{
+			"image prefix does not match -> should reject",
+			[]string{`Spec.Docker.Image startsWith "docker.io/"`},
+			false,
+		},
+		{
+			"cpu under limit -> should accept",
+			[]string{`cpu(Spec.Resources.CPU) <= 1`},
+			true,
+		},
+		{
+			"memory over limit -> should reject",
+			[]string{`memory(Spec.Resources.Memory) <= memory("512Mb")`},
+			false,
+		},
+		{
+			"all rules pass -> should accept",
+			[]string{`"team:x" in Spec.Annotations`, `NodeID == "node-id"`},
+			true,
+		},
+		{
+			"one rule fails -> should reject",
+			[]string{`"team:x" in Spec.Annotations`, `"team:y" in Spec.Annotations`},
+			false,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := CompileJobSelectionRules(test.rules)
+			require.NoError(t, err)
+			suite := tooling.NewTestSuite()
+			executor, err := tooling.NewNoopExecutor(suite.Cm, tooling.BlankNoopExecutorConfig())
+			require.NoError(t, err)
+			result, _, err := ApplyJobSelectionPolicy(
+				context.Background(),
+				JobSelectionPolicy{
+					Rules: rules,
+				},
+				executor,
+				data,
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedResult, result)
+		})
+	}
+}
+
+func TestJobSelectionRulesInvalid(t *testing.T) {
+	for _, rule := range []string{`Spec.Docker.Image +`, `Spec.Docker.Image`, `NoSuchField == 1`} {
+		_, err := CompileJobSelectionRules([]string{rule})
+		require.Error(t, err, rule)
+	}
+}
+
+func TestJobSelectionRulesJSON(t *testing.T) {
+	rules, err := CompileJobSelectionRules([]string{`NodeID == "node-id"`})
+	require.NoError(t, err)
+	data, err := json.Marshal(JobSelectionPolicy{Rules: rules})
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"rules":["NodeID == \"node-id\""]`)
+
+	// rules are compiled again when a policy is read back in
+	policy := JobSelectionPolicy{}
+	require.NoError(t, json.Unmarshal(data, &policy))
+	accepted, err := applyJobSelectionPolicyRules(context.Background(), policy.Rules, JobSelectionPolicyProbeData{NodeID: "node-id"})
+	require.NoError(t, err)
+	require.True(t, accepted)
+
+	require.Error(t, json.Unmarshal([]byte(`{"rules":["NoSuchField == 1"]}`), &policy))
+}
+
+func TestLoadJobSelectionRules(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "rules.yaml")
+	err := os.WriteFile(path, []byte(`rules:
+  - Spec.Docker.Image startsWith "ubuntu"
+  - cpu(Spec.Resources.CPU) <= 2
+`), 0600)
+	require.NoError(t, err)
+	rules, err := LoadJobSelectionRules(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(rules))
+
+	badPath := filepath.Join(dir, "bad.yaml")
+	err = os.WriteFile(badPath, []byte(`rules:
+  - Spec.Docker.Image startsWith
+`), 0600)
+	require.NoError(t, err)
+	_, err = LoadJobSelectionRules(badPath)
+	require.Error(t, err)
+}
+
+func TestJobSelectionProbeResponse(t *testing.T) {
+	testCases := []struct {
+		name           string
+		failMode       bool
+		response       string
+		expectedResult bool
+		expectedBid    executor.JobBid
+	}{
+		{
+			"plain text response is judged on status",
+			false,
+			"ok",
+			true,
+			executor.JobBid{},
+		},
+		{
+			"json response carries the bid",
+			false,
+			`{"bid_price": 2.5, "eta_seconds": 30, "reason": "idle"}`,
+			true,
+			executor.JobBid{Price: 2.5, ETASeconds: 30, Reason: "idle"},
+		},
+		{
+			"json response can reject despite the status",
+			false,
+			`{"accept": false, "reason": "too busy"}`,
+			false,
+			executor.JobBid{Reason: "too busy"},
+		},
+		{
+			"json response can accept despite the status",
+			true,
+			`{"accept": true, "bid_price": 7}`,
+			true,
+			executor.JobBid{Price: 7},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			suite := tooling.NewTestSuite()
+			executor, err := tooling.NewNoopExecutor(suite.Cm, tooling.BlankNoopExecutorConfig())
+			require.NoError(t, err)
+
+			svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.failMode {
+					w.WriteHeader(http.StatusInternalServerError)
+				} else {
+					w.WriteHeader(http.StatusOK)
+				}
+				w.Write([]byte(test.response))
+			}))
+			defer svr.Close()
+
+			result, bid, err := ApplyJobSelectionPolicy(
+				context.Background(),
+				JobSelectionPolicy{
+					ProbeHTTP: svr.URL,
+				},
+				executor,
+				getProbeDataWithVolume(),
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedResult, result)
+			require.Equal(t, test.expectedBid, bid)
+
+			exitCode := 0
+			if test.failMode {
+				exitCode = 1
+			}
+			result, bid, err = ApplyJobSelectionPolicy(
+				context.Background(),
+				JobSelectionPolicy{
+					ProbeExec: fmt.Sprintf("echo '%s'; exit %d", test.response, exitCode),
+				},
+				executor,
+				getProbeDataWithVolume(),
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedResult, result)
+			require.Equal(t, test.expectedBid, bid)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package computenode
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/rs/zerolog/log"
+)
+
+func (node *ComputeNode) resultsGCLoopSetup(cm *system.CleanupManager) {
+	ticker := time.NewTicker(node.config.ResultsGCInterval)
+	ctx, cancelFunction := context.WithCancel(context.Background())
+
+	cm.RegisterCallback(func() error {
+		cancelFunction()
+		return nil
+	})
+
+	for {
+		select {
+		case <-ticker.C:
+			node.collectResults(ctx)
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// remove the shard results executors have kept for jobs that the
+// controller has forgotten about (i.e. the requester garbage collected)
+func (node *ComputeNode) collectResults(ctx context.Context) {
+	for engine, e := range node.executors {
+		keeper, ok := e.(executor.ResultsKeeper)
+		if !ok {
+			continue
+		}
+		jobIDs, err := keeper.GetResultsJobIDs(ctx)
+		if err != nil {
+			log.Warn().Msgf("error listing %s results to garbage collect: %s", engine, err)
+			continue
+		}
+		for _, jobID := range jobIDs {
+			_, err := node.controller.GetJob(ctx, jobID)
+			if err == nil {
+				continue
+			}
+			log.Debug().Msgf("Compute node %s removing %s results for job %s", node.id, engine, jobID)
+			err = keeper.CleanupJobResults(ctx, jobID)
+			if err != nil {
+				log.Warn().Msgf("error removing %s results for job %s: %s", engine, jobID, err)
+			}
+		}
+	}
+}
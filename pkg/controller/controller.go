@@ -136,6 +136,23 @@ func (ctrl *Controller) GetJobs(ctx context.Context, query localdb.JobQuery) ([]
 /*
 REQUESTER NODE
 */
+// forget everything this node knows about a job - events for the job that
+// arrive afterwards are dropped because there is no job to apply them to
+func (ctrl *Controller) DeleteJob(ctx context.Context, jobID string) error {
+	err := ctrl.localdb.DeleteJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	ctrl.endJobContext(jobID)
+	ctrl.contextMutex.Lock()
+	defer ctrl.contextMutex.Unlock()
+	if jobNodeCtx, ok := ctrl.jobNodeContexts[jobID]; ok {
+		trace.SpanFromContext(jobNodeCtx).End()
+		delete(ctrl.jobNodeContexts, jobID)
+	}
+	return nil
+}
+
 func (ctrl *Controller) SubmitJob(
 	ctx context.Context,
 	data executor.JobCreatePayload,
@@ -194,6 +211,7 @@ func (ctrl *Controller) AcceptJobBid(
 	ctx context.Context,
 	jobID, nodeID string,
 	shardIndex int,
+	bid executor.JobBid,
 ) error {
 	if jobID == "" {
 		return fmt.Errorf("AcceptJobBid: jobID cannot be empty")
@@ -207,6 +225,7 @@ func (ctrl *Controller) AcceptJobBid(
 		JobID:        jobID,
 		TargetNodeID: nodeID,
 		ShardIndex:   shardIndex,
+		JobBid:       bid,
 	})
 	if err != nil {
 		return err
@@ -242,7 +261,12 @@ func (ctrl *Controller) RejectJobBid(
 	return ctrl.writeEvent(jobCtx, ev)
 }
 
-func (ctrl *Controller) AcceptResults(ctx context.Context, jobID, nodeID string) error {
+// can only be done by the requestor node that is responsible for the job
+func (ctrl *Controller) AcceptResults(
+	ctx context.Context,
+	jobID, nodeID string,
+	shardIndex int,
+) error {
 	if jobID == "" {
 		return fmt.Errorf("AcceptResults: jobID cannot be empty")
 	}
@@ -253,12 +277,18 @@ func (ctrl *Controller) AcceptResults(ctx context.Context, jobID, nodeID string)
 	ctrl.addJobLifecycleEvent(jobCtx, jobID, "write_AcceptResults")
 	ev := ctrl.constructEvent(jobID, executor.JobEventResultsAccepted)
 	// the target node is the "nodeID" because the requester node calls this
-	// function and so knows which node it is rejecting the bid for
+	// function and so knows which node it is accepting the results for
 	ev.TargetNodeID = nodeID
+	ev.ShardIndex = shardIndex
 	return ctrl.writeEvent(jobCtx, ev)
 }
 
-func (ctrl *Controller) RejectResults(ctx context.Context, jobID, nodeID string) error {
+// can only be done by the requestor node that is responsible for the job
+func (ctrl *Controller) RejectResults(
+	ctx context.Context,
+	jobID, nodeID string,
+	shardIndex int,
+) error {
 	if jobID == "" {
 		return fmt.Errorf("RejectResults: jobID cannot be empty")
 	}
@@ -269,25 +299,40 @@ func (ctrl *Controller) RejectResults(ctx context.Context, jobID, nodeID string)
 	ctrl.addJobLifecycleEvent(jobCtx, jobID, "write_RejectResults")
 	ev := ctrl.constructEvent(jobID, executor.JobEventResultsRejected)
 	// the target node is the "nodeID" because the requester node calls this
-	// function and so knows which node it is rejecting the bid for
+	// function and so knows which node it is rejecting the results for
 	ev.TargetNodeID = nodeID
+	ev.ShardIndex = shardIndex
 	return ctrl.writeEvent(jobCtx, ev)
 }
 
 /*
 COMPUTE NODE
 */
-func (ctrl *Controller) SelectJob(ctx context.Context, jobID string) error {
+// we remember the terms we will bid with because the
+// bids themselves happen later as we have capacity
+func (ctrl *Controller) SelectJob(ctx context.Context, jobID string, bid executor.JobBid) error {
 	jobCtx := ctrl.getJobNodeContext(ctx, jobID)
 	err := ctrl.localdb.AddLocalEvent(jobCtx, jobID, executor.JobLocalEvent{
 		EventName: executor.JobLocalEventSelected,
 		JobID:     jobID,
+		JobBid:    bid,
 	})
 	return err
 }
 
+// remember the root of the job's combined results so it
+// can be unpinned when the job is garbage collected
+func (ctrl *Controller) RecordCombinedResults(ctx context.Context, jobID, resultsID string) error {
+	jobCtx := ctrl.getJobNodeContext(ctx, jobID)
+	return ctrl.localdb.AddLocalEvent(jobCtx, jobID, executor.JobLocalEvent{
+		EventName: executor.JobLocalEventResultsCombined,
+		JobID:     jobID,
+		ResultsID: resultsID,
+	})
+}
+
 // done by compute nodes when they hear about the job
-func (ctrl *Controller) BidJob(ctx context.Context, jobID string, shardIndex int) error {
+func (ctrl *Controller) BidJob(ctx context.Context, jobID string, shardIndex int, bid executor.JobBid) error {
 	jobCtx := ctrl.getJobNodeContext(ctx, jobID)
 	err := ctrl.localdb.AddLocalEvent(jobCtx, jobID, executor.JobLocalEvent{
 		EventName:  executor.JobLocalEventBid,
@@ -300,6 +345,7 @@ func (ctrl *Controller) BidJob(ctx context.Context, jobID string, shardIndex int
 	ctrl.addJobLifecycleEvent(jobCtx, jobID, "write_BidJob")
 	ev := ctrl.constructEvent(jobID, executor.JobEventBid)
 	ev.ShardIndex = shardIndex
+	ev.JobBid = bid
 	return ctrl.writeEvent(jobCtx, ev)
 }
 
@@ -313,10 +359,11 @@ func (ctrl *Controller) CancelJobBid(ctx context.Context, jobID string) error {
 
 // this can be used both to indicate the job has started to run
 // and also to update the status half way through running it
-func (ctrl *Controller) RunJob(ctx context.Context, jobID, status string) error {
+func (ctrl *Controller) RunJob(ctx context.Context, jobID string, shardIndex int, status string) error {
 	jobCtx := ctrl.getJobNodeContext(ctx, jobID)
 	ctrl.addJobLifecycleEvent(jobCtx, jobID, "write_RunJob")
 	ev := ctrl.constructEvent(jobID, executor.JobEventRunning)
+	ev.ShardIndex = shardIndex
 	ev.Status = status
 	return ctrl.writeEvent(jobCtx, ev)
 }
@@ -327,12 +374,14 @@ func (ctrl *Controller) CompleteJob(
 	shardIndex int,
 	status string,
 	resultsID string,
+	resultsHash string,
 ) error {
 	jobCtx := ctrl.getJobNodeContext(ctx, jobID)
 	ctrl.addJobLifecycleEvent(jobCtx, jobID, "write_CompleteJob")
 	ev := ctrl.constructEvent(jobID, executor.JobEventCompleted)
 	ev.Status = status
 	ev.ResultsID = resultsID
+	ev.ResultsHash = resultsHash
 	ev.ShardIndex = shardIndex
 	return ctrl.writeEvent(jobCtx, ev)
 }
@@ -443,6 +492,12 @@ func (ctrl *Controller) mutateDatastore(ctx context.Context, ev executor.JobEven
 	}
 
 	if executor.IsValidJobState(executionState) {
+		// the clock of the node that sent the event could be anything
+		// so we use our own to say when the shard finished
+		finishedAt := time.Time{}
+		if executionState.IsTerminal() {
+			finishedAt = time.Now()
+		}
 		// update the state for this job shard
 		err = ctrl.localdb.UpdateShardState(
 			ctx,
@@ -450,11 +505,15 @@ func (ctrl *Controller) mutateDatastore(ctx context.Context, ev executor.JobEven
 			useNodeID,
 			ev.ShardIndex,
 			executor.JobShardState{
-				NodeID:     useNodeID,
-				ShardIndex: ev.ShardIndex,
-				State:      executionState,
-				Status:     ev.Status,
-				ResultsID:  ev.ResultsID,
+				NodeID:      useNodeID,
+				ShardIndex:  ev.ShardIndex,
+				State:       executionState,
+				Status:      ev.Status,
+				ResultsID:   ev.ResultsID,
+				ResultsHash: ev.ResultsHash,
+				// only meaningful once the state is finalized
+				ResultsAccepted: ev.EventName == executor.JobEventResultsAccepted,
+				FinishedAt:      finishedAt,
 			},
 		)
 		if err != nil {
@@ -14,6 +14,9 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/config"
 	"github.com/filecoin-project/bacalhau/pkg/controller"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	docker_executor "github.com/filecoin-project/bacalhau/pkg/executor/docker"
+	"github.com/filecoin-project/bacalhau/pkg/executor/podman"
+	pythonwasm "github.com/filecoin-project/bacalhau/pkg/executor/python_wasm"
 	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
 	"github.com/filecoin-project/bacalhau/pkg/ipfs"
 	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
@@ -24,6 +27,7 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/system"
 	"github.com/filecoin-project/bacalhau/pkg/transport/libp2p"
 	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/localfs"
 	verifier_util "github.com/filecoin-project/bacalhau/pkg/verifier/util"
 	"github.com/ipfs/go-datastore"
 	"github.com/phayes/freeport"
@@ -96,6 +100,9 @@ func NewDevStackForRunLocal(
 			cm,
 			ipfsMultiAddress,
 			fmt.Sprintf("devstacknode%d-%s", nodeIndex, ipfsSuffix),
+			docker_executor.ExecutorConfig{},
+			podman.ExecutorConfig{},
+			pythonwasm.ExecutorConfig{},
 		)
 	}
 	getVerifiers := func(
@@ -106,13 +113,7 @@ func NewDevStackForRunLocal(
 		map[verifier.VerifierType]verifier.Verifier,
 		error,
 	) {
-		jobLoader := func(ctx context.Context, id string) (executor.Job, error) {
-			return ctrl.GetJob(ctx, id)
-		}
-		stateLoader := func(ctx context.Context, id string) (executor.JobState, error) {
-			return ctrl.GetJobState(ctx, id)
-		}
-		return verifier_util.NewIPFSVerifiers(cm, ipfsMultiAddress, jobLoader, stateLoader)
+		return verifier_util.NewStandardVerifiers(cm, ipfsMultiAddress, ctrl, verifier_util.VerifierConfig{})
 	}
 
 	return NewDevStack(
@@ -264,6 +265,23 @@ func NewDevStack(
 			return nil, err
 		}
 
+		// predictable port for API
+		var apiPort int
+		if os.Getenv("PREDICTABLE_API_PORT") != "" {
+			apiPort = 20000 + i
+		} else {
+			apiPort, err = freeport.GetFreePort()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// the localfs verifier serves results from this node's api server
+		// which we only now know the port of
+		if localfsVerifier, ok := verifiers[verifier.VerifierLocalFS].(*localfs.Verifier); ok && localfsVerifier.Config.URL == "" {
+			localfsVerifier.Config.URL = fmt.Sprintf("http://127.0.0.1:%d", apiPort)
+		}
+
 		//////////////////////////////////////
 		// Requestor node
 		//////////////////////////////////////
@@ -295,22 +313,13 @@ func NewDevStack(
 		// JSON RPC
 		//////////////////////////////////////
 
-		// predictable port for API
-		var apiPort int
-		if os.Getenv("PREDICTABLE_API_PORT") != "" {
-			apiPort = 20000 + i
-		} else {
-			apiPort, err = freeport.GetFreePort()
-			if err != nil {
-				return nil, err
-			}
-		}
-
 		apiServer := publicapi.NewServer(
 			"0.0.0.0",
 			apiPort,
 			ctrl,
 			verifiers,
+			requesterNode.GetReputationStore(),
+			requesterNode.GetQuotaManager(),
 		)
 		go func(ctx context.Context) {
 			var gerr error // don't capture outer scope
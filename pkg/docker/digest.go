@@ -0,0 +1,156 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+)
+
+// the manifest types we ask the registry for - asking for the lists / indexes
+// means we get the same digest that "docker pull" reports for multi-arch images
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// ask the registry what digest the tag of an image currently points at and
+// return the image reference pinned to that digest (e.g. ubuntu:latest@sha256:...)
+// this talks to the registry directly so does not need a local docker daemon
+// images that are already pinned are returned as is
+func ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	return resolveImageDigest(ctx, http.DefaultClient, "https", image)
+}
+
+func resolveImageDigest(ctx context.Context, client *http.Client, scheme, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %w", image, err)
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		return image, nil
+	}
+	named = reference.TagNameOnly(named)
+	tagged, ok := named.(reference.Tagged)
+	if !ok {
+		return "", fmt.Errorf("image %s has no tag", image)
+	}
+
+	registry := reference.Domain(named)
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s",
+		scheme, registry, reference.Path(named), tagged.Tag())
+
+	res, err := headManifest(ctx, client, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	// most registries (including docker hub) want an anonymous token even for
+	// public images - the 401 tells us where to get one
+	if res.StatusCode == http.StatusUnauthorized {
+		var token string
+		token, err = getRegistryToken(ctx, client, res.Header.Get("Www-Authenticate"))
+		if err != nil {
+			return "", err
+		}
+		res, err = headManifest(ctx, client, manifestURL, token)
+		if err != nil {
+			return "", err
+		}
+		res.Body.Close()
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error resolving digest for %s: registry returned %s", image, res.Status)
+	}
+
+	digest := res.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("error resolving digest for %s: registry did not return a digest", image)
+	}
+
+	return fmt.Sprintf("%s@%s", reference.FamiliarString(named), digest), nil
+}
+
+func headManifest(ctx context.Context, client *http.Client, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting manifest %s: %w", manifestURL, err)
+	}
+	return res, nil
+}
+
+// parse a header like:
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/ubuntu:pull"
+// and fetch an anonymous token from the realm
+func getRegistryToken(ctx context.Context, client *http.Client, challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("unsupported registry auth challenge: %s", challenge)
+	}
+	params := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(challenge, "Bearer "), ",") {
+		keyValue := strings.SplitN(strings.TrimSpace(part), "=", 2) //nolint:gomnd
+		if len(keyValue) != 2 {                                     //nolint:gomnd
+			continue
+		}
+		params[keyValue[0]] = strings.Trim(keyValue[1], `"`)
+	}
+	realm, ok := params["realm"]
+	if !ok {
+		return "", fmt.Errorf("registry auth challenge has no realm: %s", challenge)
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if value, ok := params[key]; ok {
+			query.Set(key, value)
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching registry token: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error fetching registry token: %s", res.Status)
+	}
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&tokenResponse)
+	if err != nil {
+		return "", fmt.Errorf("error decoding registry token: %w", err)
+	}
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+	return tokenResponse.AccessToken, nil
+}
@@ -0,0 +1,52 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveImageDigest(t *testing.T) {
+	var svr *httptest.Server
+	svr = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			require.Equal(t, "repository:team/app:pull", r.URL.Query().Get("scope"))
+			w.Write([]byte(`{"token": "anonymous"}`))
+		case "/v2/team/app/manifests/v1":
+			require.Equal(t, http.MethodHead, r.Method)
+			if r.Header.Get("Authorization") != "Bearer anonymous" {
+				w.Header().Set("Www-Authenticate", fmt.Sprintf(
+					`Bearer realm="%s/token",service="test",scope="repository:team/app:pull"`, svr.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", testDigest)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer svr.Close()
+
+	host := strings.TrimPrefix(svr.URL, "http://")
+	ctx := context.Background()
+
+	pinned, err := resolveImageDigest(ctx, svr.Client(), "http", host+"/team/app:v1")
+	require.NoError(t, err)
+	require.Equal(t, host+"/team/app:v1@"+testDigest, pinned)
+	require.Equal(t, testDigest, GetImageDigest(pinned))
+
+	// already pinned images are left alone
+	pinned, err = resolveImageDigest(ctx, svr.Client(), "http", host+"/team/app@"+testDigest)
+	require.NoError(t, err)
+	require.Equal(t, host+"/team/app@"+testDigest, pinned)
+
+	_, err = resolveImageDigest(ctx, svr.Client(), "http", host+"/team/missing:v1")
+	require.Error(t, err)
+}
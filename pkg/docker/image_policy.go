@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+)
+
+// which images a compute node is willing to pull and run
+// patterns can use * to match any characters (including /) and are matched
+// against the short name (ubuntu), the fully qualified name
+// (docker.io/library/ubuntu) and the full reference (ubuntu:latest - which
+// is what a bare name means)
+type ImagePolicy struct {
+	// if non-empty an image must match one of these patterns
+	Allow []string `json:"allow,omitempty"`
+	// an image that matches any of these patterns is always refused
+	Deny []string `json:"deny,omitempty"`
+	// refuse images that are not pinned to a digest
+	// (e.g. ubuntu@sha256:...)
+	RequireDigest bool `json:"require_digest,omitempty"`
+}
+
+func (policy ImagePolicy) IsEmpty() bool {
+	return len(policy.Allow) == 0 && len(policy.Deny) == 0 && !policy.RequireDigest
+}
+
+// returns an error explaining why the image is not allowed
+// or nil if it is
+func (policy ImagePolicy) Check(image string) error {
+	if policy.IsEmpty() {
+		return nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %s: %w", image, err)
+	}
+
+	// a bare name is :latest so it has to match patterns for that too
+	tagged := reference.TagNameOnly(named)
+	candidates := []string{
+		image,
+		reference.FamiliarName(named),
+		reference.FamiliarString(named),
+		named.Name(),
+		named.String(),
+		reference.FamiliarString(tagged),
+		tagged.String(),
+	}
+
+	for _, pattern := range policy.Deny {
+		if matchImagePattern(pattern, candidates) {
+			return fmt.Errorf("image %s is denied by pattern %s", image, pattern)
+		}
+	}
+
+	if len(policy.Allow) > 0 {
+		allowed := false
+		for _, pattern := range policy.Allow {
+			if matchImagePattern(pattern, candidates) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("image %s does not match any allowed pattern", image)
+		}
+	}
+
+	if policy.RequireDigest {
+		if _, ok := named.(reference.Canonical); !ok {
+			return fmt.Errorf("image %s is not pinned to a digest", image)
+		}
+	}
+
+	return nil
+}
+
+// return the digest an image reference is pinned to (empty if it is not)
+func GetImageDigest(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	canonical, ok := named.(reference.Canonical)
+	if !ok {
+		return ""
+	}
+	return canonical.Digest().String()
+}
+
+func matchImagePattern(pattern string, candidates []string) bool {
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range candidates {
+		if re.MatchString(candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDigest = "sha256:26c68657ccce2cb0a31b330cb0be2b5e108d467f641c62e13ab40cbec258c68d"
+
+func TestImagePolicy(t *testing.T) {
+	testCases := []struct {
+		name    string
+		policy  ImagePolicy
+		image   string
+		allowed bool
+	}{
+		{"empty policy allows anything", ImagePolicy{}, "ubuntu", true},
+		{"short name allowed", ImagePolicy{Allow: []string{"ubuntu"}}, "ubuntu:22.04", true},
+		{"full name allowed", ImagePolicy{Allow: []string{"docker.io/library/ubuntu"}}, "ubuntu", true},
+		{"wildcard allowed", ImagePolicy{Allow: []string{"registry.example.com/*"}}, "registry.example.com/team/app:v1", true},
+		{"not in allow list", ImagePolicy{Allow: []string{"registry.example.com/*"}}, "ubuntu", false},
+		{"denied", ImagePolicy{Deny: []string{"ubuntu"}}, "ubuntu", false},
+		{"deny wins over allow", ImagePolicy{Allow: []string{"*"}, Deny: []string{"*:latest"}}, "ubuntu:latest", false},
+		{"bare name is latest", ImagePolicy{Deny: []string{"*:latest"}}, "ubuntu", false},
+		{"bare name is latest in full", ImagePolicy{Deny: []string{"docker.io/library/ubuntu:latest"}}, "ubuntu", false},
+		{"deny does not match", ImagePolicy{Deny: []string{"*:latest"}}, "ubuntu:22.04", true},
+		{"digest required but missing", ImagePolicy{RequireDigest: true}, "ubuntu:22.04", false},
+		{"digest required and present", ImagePolicy{RequireDigest: true}, "ubuntu@" + testDigest, true},
+		{"tag and digest", ImagePolicy{RequireDigest: true, Allow: []string{"ubuntu"}}, "ubuntu:22.04@" + testDigest, true},
+		{"invalid reference", ImagePolicy{Allow: []string{"*"}}, "UPPERCASE", false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Check(test.image)
+			if test.allowed {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGetImageDigest(t *testing.T) {
+	require.Equal(t, "", GetImageDigest("ubuntu"))
+	require.Equal(t, testDigest, GetImageDigest("ubuntu@"+testDigest))
+	require.Equal(t, testDigest, GetImageDigest("ubuntu:22.04@"+testDigest))
+}
@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/docker/docker/api/types"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/rs/zerolog/log"
+)
+
+type PullOptions struct {
+	// the encoded credentials for the image's registry (see RegistryAuth.Encode)
+	RegistryAuth string
+	// refuse images bigger than this many bytes - no limit if 0
+	MaxSize uint64
+	// told how the pull is getting on when it starts and as each layer finishes
+	Progress func(status string)
+}
+
+// pull the image through the API rather than the docker CLI so that it works
+// with whatever is at the other end of the client (e.g. podman) - cancelling
+// the context cancels the pull
+func PullImage(ctx context.Context, dockerClient dockerclient.APIClient, image string, options PullOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	imagePullStream, err := dockerClient.ImagePull(ctx, image, types.ImagePullOptions{
+		RegistryAuth: options.RegistryAuth,
+	})
+	if err != nil {
+		return err
+	}
+	defer imagePullStream.Close()
+
+	progress := newPullProgress(image)
+	progress.report(options.Progress)
+
+	// the pull is cancelled if we stop reading before it's finished so we
+	// read to the end even though we only care about some of the messages
+	decoder := json.NewDecoder(imagePullStream)
+	for {
+		var message jsonmessage.JSONMessage
+		err = decoder.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("error reading pull progress: %w", err)
+		}
+		// e.g. the image doesn't exist or we aren't allowed to pull it
+		if message.Error != nil {
+			return message.Error
+		}
+		if message.ErrorMessage != "" {
+			return errors.New(message.ErrorMessage)
+		}
+		log.Trace().Msgf("Pulling %s: %s %s", image, message.ID, message.Status)
+
+		layerDone := progress.update(message)
+		if options.MaxSize > 0 && progress.size() > options.MaxSize {
+			return fmt.Errorf("image %s is bigger than the limit of %s",
+				image, datasize.ByteSize(options.MaxSize).HR())
+		}
+		if layerDone {
+			progress.report(options.Progress)
+		}
+	}
+
+	// what we download is compressed so check what it came to as well
+	if options.MaxSize > 0 {
+		inspect, _, err := dockerClient.ImageInspectWithRaw(ctx, image)
+		if err != nil {
+			return err
+		}
+		if uint64(inspect.Size) > options.MaxSize {
+			_, err = dockerClient.ImageRemove(ctx, inspect.ID, types.ImageRemoveOptions{})
+			if err != nil {
+				log.Warn().Msgf("Error removing image %s that is too big: %s", image, err)
+			}
+			return fmt.Errorf("image %s is %s which is bigger than the limit of %s", image,
+				datasize.ByteSize(inspect.Size).HR(), datasize.ByteSize(options.MaxSize).HR())
+		}
+	}
+
+	return nil
+}
+
+type layerProgress struct {
+	downloaded int64
+	total      int64
+	done       bool
+}
+
+type pullProgress struct {
+	image  string
+	layers map[string]*layerProgress
+}
+
+func newPullProgress(image string) *pullProgress {
+	return &pullProgress{
+		image:  image,
+		layers: map[string]*layerProgress{},
+	}
+}
+
+// returns true if the message means a layer just finished
+func (p *pullProgress) update(message jsonmessage.JSONMessage) bool {
+	switch message.Status {
+	case "Pulling fs layer", "Waiting", "Downloading", "Verifying Checksum",
+		"Download complete", "Extracting", "Pull complete", "Already exists":
+	default:
+		// not about a layer (e.g. "Pulling from library/ubuntu" or the digest)
+		return false
+	}
+
+	layer, ok := p.layers[message.ID]
+	if !ok {
+		layer = &layerProgress{}
+		p.layers[message.ID] = layer
+	}
+	if message.Status == "Downloading" && message.Progress != nil {
+		layer.downloaded = message.Progress.Current
+		layer.total = message.Progress.Total
+	}
+	if message.Status == "Download complete" {
+		layer.downloaded = layer.total
+	}
+	if !layer.done && (message.Status == "Pull complete" || message.Status == "Already exists") {
+		layer.done = true
+		return true
+	}
+	return false
+}
+
+// how much we are downloading - layers we already had don't count
+func (p *pullProgress) size() uint64 {
+	var size int64
+	for _, layer := range p.layers {
+		size += layer.total
+	}
+	return uint64(size)
+}
+
+func (p *pullProgress) String() string {
+	if len(p.layers) == 0 {
+		return fmt.Sprintf("Pulling image %s", p.image)
+	}
+	done := 0
+	var downloaded int64
+	for _, layer := range p.layers {
+		if layer.done {
+			done++
+		}
+		downloaded += layer.downloaded
+	}
+	return fmt.Sprintf("Pulling image %s: %d/%d layers, downloaded %s of %s", p.image, done, len(p.layers),
+		datasize.ByteSize(downloaded).HR(), datasize.ByteSize(p.size()).HR())
+}
+
+func (p *pullProgress) report(fn func(status string)) {
+	if fn != nil {
+		fn(p.String())
+	}
+}
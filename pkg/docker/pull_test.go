@@ -0,0 +1,126 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/stretchr/testify/require"
+)
+
+// streams the messages a daemon sends while pulling an image
+type fakePullClient struct {
+	dockerclient.APIClient
+	messages     []jsonmessage.JSONMessage
+	imageSize    int64
+	hang         bool
+	registryAuth string
+	removed      []string
+}
+
+func (c *fakePullClient) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	c.registryAuth = options.RegistryAuth
+	reader, writer := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(writer)
+		for _, message := range c.messages {
+			_ = encoder.Encode(message)
+		}
+		if c.hang {
+			<-ctx.Done()
+			writer.CloseWithError(ctx.Err())
+			return
+		}
+		writer.Close()
+	}()
+	return reader, nil
+}
+
+func (c *fakePullClient) ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error) {
+	return types.ImageInspect{ID: "sha256:abc", Size: c.imageSize}, nil, nil
+}
+
+func (c *fakePullClient) ImageRemove(
+	ctx context.Context, image string, options types.ImageRemoveOptions,
+) ([]types.ImageDeleteResponseItem, error) {
+	c.removed = append(c.removed, image)
+	return nil, nil
+}
+
+func downloading(id string, current, total int64) jsonmessage.JSONMessage {
+	return jsonmessage.JSONMessage{
+		ID:       id,
+		Status:   "Downloading",
+		Progress: &jsonmessage.JSONProgress{Current: current, Total: total},
+	}
+}
+
+var pullMessages = []jsonmessage.JSONMessage{
+	{ID: "v1", Status: "Pulling from team/app"},
+	{ID: "layer1", Status: "Already exists"},
+	{ID: "layer2", Status: "Pulling fs layer"},
+	downloading("layer2", 1024, 2048),
+	downloading("layer2", 2048, 2048),
+	{ID: "layer2", Status: "Download complete"},
+	{ID: "layer2", Status: "Pull complete"},
+	{Status: "Digest: sha256:abc"},
+}
+
+func TestPullImage(t *testing.T) {
+	client := &fakePullClient{messages: pullMessages, imageSize: 4096}
+	statuses := []string{}
+	err := PullImage(context.Background(), client, "team/app:v1", PullOptions{
+		RegistryAuth: "credentials",
+		MaxSize:      8192,
+		Progress: func(status string) {
+			statuses = append(statuses, status)
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "credentials", client.registryAuth)
+	require.Equal(t, []string{
+		"Pulling image team/app:v1",
+		"Pulling image team/app:v1: 1/1 layers, downloaded 0 B of 0 B",
+		"Pulling image team/app:v1: 2/2 layers, downloaded 2.0 KB of 2.0 KB",
+	}, statuses)
+	require.Empty(t, client.removed)
+}
+
+func TestPullImageError(t *testing.T) {
+	client := &fakePullClient{messages: []jsonmessage.JSONMessage{
+		{ID: "v1", Status: "Pulling from team/app"},
+		{Error: &jsonmessage.JSONError{Message: "pull access denied for team/app"}},
+	}}
+	err := PullImage(context.Background(), client, "team/app:v1", PullOptions{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pull access denied")
+}
+
+func TestPullImageTooBig(t *testing.T) {
+	// the layers we download are too big
+	client := &fakePullClient{messages: pullMessages, hang: true}
+	err := PullImage(context.Background(), client, "team/app:v1", PullOptions{MaxSize: 1024})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bigger than the limit")
+
+	// the download was small enough but not once it was uncompressed
+	client = &fakePullClient{messages: pullMessages, imageSize: 8192}
+	err = PullImage(context.Background(), client, "team/app:v1", PullOptions{MaxSize: 4096})
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "image team/app:v1 is 8.0 KB"), err.Error())
+	require.Equal(t, []string{"sha256:abc"}, client.removed)
+}
+
+func TestPullImageTimeout(t *testing.T) {
+	client := &fakePullClient{messages: pullMessages[:3], hang: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := PullImage(ctx, client, "team/app:v1", PullOptions{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+)
+
+// credentials for private registries keyed by registry host (docker.io for
+// docker hub)
+type RegistryAuth map[string]types.AuthConfig
+
+// read the credentials from a file in the format of docker's config.json:
+// {"auths": {"registry.example.com": {"auth": "<base64 of user:password>"}}}
+// credential helpers (credsStore) are not supported
+func LoadRegistryAuth(path string) (RegistryAuth, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configFile struct {
+		Auths map[string]types.AuthConfig `json:"auths"`
+	}
+	err = json.Unmarshal(data, &configFile)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing registry auth %s: %w", path, err)
+	}
+
+	auth := RegistryAuth{}
+	for server, authConfig := range configFile.Auths {
+		// the daemon wants the username and password rather than the auth
+		// string the docker CLI stores
+		if authConfig.Auth != "" {
+			var decoded []byte
+			decoded, err = base64.StdEncoding.DecodeString(authConfig.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth for registry %s: %w", server, err)
+			}
+			username, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid auth for registry %s: expected username:password", server)
+			}
+			authConfig.Username = username
+			authConfig.Password = password
+			authConfig.Auth = ""
+		}
+		authConfig.ServerAddress = server
+		auth[registryHost(server)] = authConfig
+	}
+	return auth, nil
+}
+
+// docker's config has urls for some registries (e.g. https://index.docker.io/v1/)
+func registryHost(server string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host = strings.SplitN(host, "/", 2)[0] //nolint:gomnd
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return "docker.io"
+	}
+	return host
+}
+
+// the encoded credentials (the X-Registry-Auth header) for pulling the
+// image - empty if we have none for its registry
+func (auth RegistryAuth) Encode(image string) (string, error) {
+	if len(auth) == 0 {
+		return "", nil
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %w", image, err)
+	}
+	authConfig, ok := auth[reference.Domain(named)]
+	if !ok {
+		return "", nil
+	}
+	data, err := json.Marshal(authConfig)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
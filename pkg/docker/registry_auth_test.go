@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("hubuser:hubpass"))+`"},
+			"registry.example.com": {"identitytoken": "token"}
+		}
+	}`), 0600)
+	require.NoError(t, err)
+
+	auth, err := LoadRegistryAuth(path)
+	require.NoError(t, err)
+
+	decode := func(image string) types.AuthConfig {
+		encoded, err := auth.Encode(image)
+		require.NoError(t, err)
+		require.NotEmpty(t, encoded, image)
+		data, err := base64.URLEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		var authConfig types.AuthConfig
+		require.NoError(t, json.Unmarshal(data, &authConfig))
+		return authConfig
+	}
+
+	hub := decode("someone/private:v1")
+	require.Equal(t, "hubuser", hub.Username)
+	require.Equal(t, "hubpass", hub.Password)
+	require.Empty(t, hub.Auth)
+	require.Equal(t, "https://index.docker.io/v1/", hub.ServerAddress)
+
+	private := decode("registry.example.com/team/app:v1")
+	require.Equal(t, "token", private.IdentityToken)
+
+	// nothing for registries we have no credentials for
+	encoded, err := auth.Encode("ghcr.io/team/app")
+	require.NoError(t, err)
+	require.Empty(t, encoded)
+}
+
+func TestInvalidRegistryAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"auths": {"registry.example.com": {"auth": "`+
+		base64.StdEncoding.EncodeToString([]byte("no-password"))+`"}}}`), 0600)
+	require.NoError(t, err)
+
+	_, err = LoadRegistryAuth(path)
+	require.Error(t, err)
+}
@@ -4,14 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	dockerclient "github.com/docker/docker/client"
-	"github.com/filecoin-project/bacalhau/pkg/config"
 	"github.com/filecoin-project/bacalhau/pkg/system"
 	"github.com/moby/moby/pkg/stdcopy"
 	"github.com/rs/zerolog/log"
@@ -25,12 +22,21 @@ func NewDockerClient() (*dockerclient.Client, error) {
 	return dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
 }
 
-func IsInstalled(dockerClient *dockerclient.Client) bool {
+// for docker compatible APIs that aren't at DOCKER_HOST (e.g. podman)
+func NewDockerClientWithHost(host string) (*dockerclient.Client, error) {
+	return dockerclient.NewClientWithOpts(
+		dockerclient.FromEnv,
+		dockerclient.WithHost(host),
+		dockerclient.WithAPIVersionNegotiation(),
+	)
+}
+
+func IsInstalled(dockerClient dockerclient.APIClient) bool {
 	_, err := dockerClient.Info(context.Background())
 	return err == nil
 }
 
-func GetContainer(dockerClient *dockerclient.Client, nameOrID string) (*types.Container, error) {
+func GetContainer(dockerClient dockerclient.APIClient, nameOrID string) (*types.Container, error) {
 	containers, err := dockerClient.ContainerList(context.Background(), types.ContainerListOptions{
 		All: true,
 	})
@@ -59,7 +65,7 @@ func GetContainer(dockerClient *dockerclient.Client, nameOrID string) (*types.Co
 	return nil, nil
 }
 
-func GetContainersWithLabel(dockerClient *dockerclient.Client, labelName, labelValue string) ([]types.Container, error) {
+func GetContainersWithLabel(dockerClient dockerclient.APIClient, labelName, labelValue string) ([]types.Container, error) {
 	results := []types.Container{}
 	containers, err := dockerClient.ContainerList(context.Background(), types.ContainerListOptions{
 		All: true,
@@ -82,7 +88,7 @@ func GetContainersWithLabel(dockerClient *dockerclient.Client, labelName, labelV
 	return results, nil
 }
 
-func GetLogs(dockerClient *dockerclient.Client, nameOrID string) (stdout, stderr string, err error) {
+func GetLogs(dockerClient dockerclient.APIClient, nameOrID string) (stdout, stderr string, err error) {
 	container, err := GetContainer(dockerClient, nameOrID)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to get container: %w", err)
@@ -115,7 +121,7 @@ func GetLogs(dockerClient *dockerclient.Client, nameOrID string) (stdout, stderr
 	return stdoutBuffer.String(), stderrBuffer.String(), nil
 }
 
-func RemoveContainer(dockerClient *dockerclient.Client, nameOrID string) error {
+func RemoveContainer(dockerClient dockerclient.APIClient, nameOrID string) error {
 	ctx := context.Background()
 
 	container, err := GetContainer(dockerClient, nameOrID)
@@ -141,7 +147,7 @@ func RemoveContainer(dockerClient *dockerclient.Client, nameOrID string) error {
 	return nil
 }
 
-func WaitForContainer(client *dockerclient.Client, id string, maxAttempts int, delay time.Duration) error {
+func WaitForContainer(client dockerclient.APIClient, id string, maxAttempts int, delay time.Duration) error {
 	waiter := &system.FunctionWaiter{
 		Name:        fmt.Sprintf("wait for container to be running: %s", id),
 		MaxAttempts: maxAttempts,
@@ -160,7 +166,7 @@ func WaitForContainer(client *dockerclient.Client, id string, maxAttempts int, d
 	return waiter.Wait()
 }
 
-func WaitForContainerLogs(client *dockerclient.Client, id string, maxAttempts int, delay time.Duration, findString string) (string, error) {
+func WaitForContainerLogs(client dockerclient.APIClient, id string, maxAttempts int, delay time.Duration, findString string) (string, error) {
 	lastLogs := ""
 	waiter := &system.FunctionWaiter{
 		Name:        fmt.Sprintf("wait for container to be running: %s", id),
@@ -188,24 +194,3 @@ func WaitForContainerLogs(client *dockerclient.Client, id string, maxAttempts in
 	err := waiter.Wait()
 	return lastLogs, err
 }
-
-func PullImage(dockerClient *dockerclient.Client, image string) error {
-	imagePullStream, err := dockerClient.ImagePull(
-		context.Background(),
-		image,
-		types.ImagePullOptions{},
-	)
-
-	if err != nil {
-		return err
-	}
-
-	if config.IsDebug() {
-		_, err = io.Copy(os.Stdout, imagePullStream)
-		if err != nil {
-			return err
-		}
-	}
-
-	return imagePullStream.Close()
-}
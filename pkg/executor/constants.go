@@ -12,7 +12,7 @@ const (
 	engineUnknown EngineType = iota // must be first
 	EngineNoop
 	EngineDocker
-	EngineWasm       // raw wasm executor not implemented yet
+	EngineWasm       // runs wasi modules in-process
 	EngineLanguage   // wraps python_wasm
 	EnginePythonWasm // wraps docker
 	engineDone       // must be last
@@ -38,6 +38,42 @@ func EngineTypes() []EngineType {
 	return res
 }
 
+//go:generate stringer -type=Network --trimprefix=Network
+type Network int
+
+// in order of how much access the job gets
+const (
+	NetworkNone Network = iota // the default
+	NetworkHTTP                // http(s) to the job's domains through a proxy
+	NetworkFull
+	networkDone // must be last
+)
+
+func ParseNetwork(str string) (Network, error) {
+	for typ := NetworkNone; typ < networkDone; typ++ {
+		if equal(typ.String(), str) {
+			return typ, nil
+		}
+	}
+
+	return NetworkNone, fmt.Errorf(
+		"executor: unknown network type '%s'", str)
+}
+
+// so job specs say "http" rather than 1
+func (n Network) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(n.String())), nil
+}
+
+func (n *Network) UnmarshalText(text []byte) error {
+	network, err := ParseNetwork(string(text))
+	if err != nil {
+		return err
+	}
+	*n = network
+	return nil
+}
+
 //go:generate stringer -type=JobEventType --trimprefix=JobEvent
 type JobEventType int
 
@@ -128,6 +164,7 @@ const (
 	JobLocalEventSelected
 	JobLocalEventBidAccepted
 	JobLocalEventBid
+	JobLocalEventResultsCombined
 
 	jobLocalEventDone // must be last
 )
@@ -172,18 +209,20 @@ const (
 
 // IsTerminal returns true if the given job type signals the end of the
 // lifecycle of that job on a particular node. After this, the job can be
-// safely ignored by the node.
+// safely ignored by the node. Complete is not terminal because the
+// requester node still has to verify the results and finalize the shard.
 func (state JobStateType) IsTerminal() bool {
-	return state == JobStateComplete || state == JobStateError || state == JobStateCancelled
+	return state == JobStateError || state == JobStateCancelled || state == JobStateFinalized
 }
 
 // IsComplete returns true if the given job has succeeded at the bid stage
 // and has finished running the job - this is used to calculate if a job
 // has completed across all nodes because a cancelation does not count
 // towards actually "running" the job whereas an error does (even though it failed
-// it still "ran")
+// it still "ran") - finalized shards have also been run and then had their
+// results verified by the requester node
 func (state JobStateType) IsComplete() bool {
-	return state == JobStateComplete || state == JobStateError
+	return state == JobStateComplete || state == JobStateError || state == JobStateFinalized
 }
 
 func (state JobStateType) IsError() bool {
@@ -0,0 +1,43 @@
+package executor
+
+import "context"
+
+type contextKey int
+
+const (
+	gpuDevicesContextKey contextKey = iota
+	progressContextKey
+)
+
+// the compute node assigns specific GPU devices to each shard it runs
+// and passes them to the executor alongside the job
+func ContextWithGPUDevices(ctx context.Context, deviceIDs []string) context.Context {
+	return context.WithValue(ctx, gpuDevicesContextKey, deviceIDs)
+}
+
+// the GPU devices the compute node assigned to this shard
+// this is empty if no devices were assigned (e.g. running outside of a compute node)
+func GPUDevicesFromContext(ctx context.Context) []string {
+	deviceIDs, ok := ctx.Value(gpuDevicesContextKey).([]string)
+	if !ok {
+		return []string{}
+	}
+	return deviceIDs
+}
+
+// the compute node passes this to the executor so it can tell the requester
+// what it's doing before the job actually runs (e.g. pulling an image)
+type ProgressFunc func(status string)
+
+func ContextWithProgress(ctx context.Context, progress ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressContextKey, progress)
+}
+
+// report progress on the shard being run - does nothing if nobody is
+// listening (e.g. running outside of a compute node)
+func ReportProgress(ctx context.Context, status string) {
+	progress, ok := ctx.Value(progressContextKey).(ProgressFunc)
+	if ok && progress != nil {
+		progress(status)
+	}
+}
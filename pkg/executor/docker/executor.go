@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime/debug"
+	"time"
 
 	dockertypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -28,6 +30,27 @@ import (
 
 const NanoCPUCoefficient = 1000000000
 
+type ExecutorConfig struct {
+	// which images we are willing to pull and run
+	ImagePolicy docker.ImagePolicy
+	// the docker compatible API to run containers with - defaults to
+	// DOCKER_HOST (or the local docker daemon)
+	Host string
+	// the squid image that proxies requests for jobs with http networking
+	// (see network.go) - defaults to DefaultProxyImage
+	ProxyImage string
+	// applied to every job container (see security.go)
+	Security SecurityProfile
+	// credentials for pulling images from private registries
+	RegistryAuth docker.RegistryAuth
+	// give up on pulling an image after this long - no limit if 0
+	PullTimeout time.Duration
+	// refuse to pull images bigger than this many bytes - no limit if 0
+	MaxImageSize uint64
+	// which images to have ready and how much space images can take up
+	ImageCache ImageCacheConfig
+}
+
 type Executor struct {
 	// used to allow multiple docker executors to run against the same docker server
 	ID string
@@ -35,32 +58,65 @@ type Executor struct {
 	// where do we copy the results from jobs temporarily?
 	ResultsDir string
 
+	// where the configs for the proxies of jobs with http networking go
+	proxyConfigDir string
+
 	// the storage providers we can implement for a job
 	StorageProviders map[storage.StorageSourceType]storage.StorageProvider
 
-	Client *dockerclient.Client
+	// the images jobs have used (see image_cache.go)
+	images *imageCache
+
+	Client dockerclient.APIClient
+
+	Config ExecutorConfig
 }
 
 func NewExecutor(
 	cm *system.CleanupManager,
 	id string,
 	storageProviders map[storage.StorageSourceType]storage.StorageProvider,
+	config ExecutorConfig,
 ) (*Executor, error) {
-	dockerClient, err := docker.NewDockerClient()
+	var dockerClient *dockerclient.Client
+	var err error
+	if config.Host != "" {
+		dockerClient, err = docker.NewDockerClientWithHost(config.Host)
+	} else {
+		dockerClient, err = docker.NewDockerClient()
+	}
 	if err != nil {
 		return nil, err
 	}
+	return NewExecutorWithClient(cm, id, storageProviders, config, dockerClient)
+}
 
+// for when something else is at the other end of the API (e.g. a fake in tests)
+func NewExecutorWithClient(
+	cm *system.CleanupManager,
+	id string,
+	storageProviders map[storage.StorageSourceType]storage.StorageProvider,
+	config ExecutorConfig,
+	dockerClient dockerclient.APIClient,
+) (*Executor, error) {
 	dir, err := ioutil.TempDir("", "bacalhau-docker-executor")
 	if err != nil {
 		return nil, err
 	}
 
+	proxyConfigDir, err := ioutil.TempDir("", "bacalhau-docker-proxy")
+	if err != nil {
+		return nil, err
+	}
+
 	de := &Executor{
 		ID:               id,
 		ResultsDir:       dir,
+		proxyConfigDir:   proxyConfigDir,
 		StorageProviders: storageProviders,
+		images:           newImageCache(config.ImageCache.DiskBudget),
 		Client:           dockerClient,
+		Config:           config,
 	}
 
 	cm.RegisterCallback(func() error {
@@ -68,6 +124,16 @@ func NewExecutor(
 		return nil
 	})
 
+	// in the background so the node can start taking jobs straight away
+	if len(config.ImageCache.PrePull) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		cm.RegisterCallback(func() error {
+			cancel()
+			return nil
+		})
+		go de.prePullImages(ctx)
+	}
+
 	return de, nil
 }
 
@@ -107,6 +173,13 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 
 	log.Debug().Msgf("Running job %s on executor %s", j.ID, e.ID)
 
+	// we already checked this when we bid on the job but the policy
+	// might have been bypassed (e.g. by a language executor rewriting the job)
+	err := e.Config.ImagePolicy.Check(j.Spec.Docker.Image)
+	if err != nil {
+		return "", err
+	}
+
 	jobResultsDir, err := e.ensureShardResultsDir(j, shardIndex)
 	if err != nil {
 		return "", err
@@ -121,6 +194,20 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 		return "", err
 	}
 
+	// so each shard knows which slice of the job it is
+	entrypoint, cmd := j.Spec.Docker.Entrypoint, j.Spec.Docker.Cmd
+	if j.Spec.Docker.TemplateArgs {
+		templateData := jobutils.NewShardTemplateData(j.ID, shardIndex, j.ExecutionPlan.TotalShards, shard)
+		entrypoint, err = jobutils.ExpandArgTemplates(entrypoint, templateData)
+		if err != nil {
+			return "", err
+		}
+		cmd, err = jobutils.ExpandArgTemplates(cmd, templateData)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	// reusable between the input shards and the input context
 	addInputStorageHandler := func(spec storage.StorageSpec) error {
 		var storageProvider storage.StorageProvider
@@ -184,6 +271,13 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 		if err != nil {
 			return "", err
 		}
+		// the job doesn't run as us so needs to be let in
+		if e.Config.Security.User != "" {
+			err = os.Chmod(srcd, util.OS_ALL_RWX)
+			if err != nil {
+				return "", err
+			}
+		}
 
 		log.Trace().Msgf("Output Volume: %+v", output)
 
@@ -202,26 +296,11 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 		})
 	}
 
-	if os.Getenv("SKIP_IMAGE_PULL") == "" {
-		// TODO: #283 work out why this does not work in github actions
-		// err = docker.PullImage(e.Client, job.Spec.Vm.Image)
-		var im dockertypes.ImageInspect
-		im, _, err = e.Client.ImageInspectWithRaw(ctx, j.Spec.Docker.Image)
-		if err == nil {
-			log.Debug().Msgf("Not pulling image %s, already have %s", j.Spec.Docker.Image, im.ID)
-		} else if dockerclient.IsErrNotFound(err) {
-			stdout, err := system.RunCommandGetResults( //nolint:govet // shadowing ok
-				"docker",
-				[]string{"pull", j.Spec.Docker.Image},
-			)
-			if err != nil {
-				return "", fmt.Errorf("error pulling %s: %s, %s", j.Spec.Docker.Image, err, stdout)
-			}
-			log.Trace().Msgf("Pull image output: %s\n%s", j.Spec.Docker.Image, stdout)
-		} else {
-			return "", fmt.Errorf("error checking if we have %s locally: %s", j.Spec.Docker.Image, err)
-		}
+	releaseImage, err := e.ensureImage(ctx, j.Spec.Docker.Image)
+	if err != nil {
+		return "", err
 	}
+	defer releaseImage()
 
 	// json the job spec and pass it into all containers
 	// TODO: check if this will overwrite a user supplied version of this value
@@ -233,13 +312,23 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 
 	useEnv := append(j.Spec.Docker.Env, fmt.Sprintf("BACALHAU_JOB_SPEC=%s", string(jsonJobSpec))) //nolint:gocritic
 
+	// the network is cleaned up with the container
+	defer e.cleanupJob(j, shardIndex)
+
+	jobNet, err := e.setupNetwork(ctx, j, shardIndex)
+	if err != nil {
+		return "", err
+	}
+	useEnv = append(useEnv, jobNet.env...)
+
 	containerConfig := &container.Config{
 		Image:           j.Spec.Docker.Image,
 		Tty:             false,
 		Env:             useEnv,
-		Entrypoint:      j.Spec.Docker.Entrypoint,
+		Entrypoint:      entrypoint,
+		Cmd:             cmd,
 		Labels:          e.jobContainerLabels(j),
-		NetworkDisabled: true,
+		NetworkDisabled: jobNet.disabled,
 		WorkingDir:      j.Spec.Docker.WorkingDir,
 	}
 
@@ -248,28 +337,26 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 	resourceRequirements := capacitymanager.ParseResourceUsageConfig(j.Spec.Resources)
 
 	// Create GPU request if the job requests it
-	var deviceRequests []container.DeviceRequest
-	if resourceRequirements.GPU > 0 {
-		deviceRequests = append(deviceRequests,
-			container.DeviceRequest{
-				DeviceIDs:    []string{"0"}, // TODO: how do we know which device ID to use?
-				Capabilities: [][]string{{"gpu"}},
-			},
-		)
-		log.Trace().Msgf("Adding %d GPUs to request", resourceRequirements.GPU)
+	deviceRequests, err := getGPUDeviceRequests(ctx, resourceRequirements.GPU)
+	if err != nil {
+		return "", err
+	}
+
+	hostConfig := &container.HostConfig{
+		Mounts:      mounts,
+		NetworkMode: jobNet.mode,
+		Resources: container.Resources{
+			Memory:         int64(resourceRequirements.Memory),
+			NanoCPUs:       int64(resourceRequirements.CPU * NanoCPUCoefficient),
+			DeviceRequests: deviceRequests,
+		},
 	}
+	e.Config.Security.apply(containerConfig, hostConfig)
 
 	jobContainer, err := e.Client.ContainerCreate(
 		ctx,
 		containerConfig,
-		&container.HostConfig{
-			Mounts: mounts,
-			Resources: container.Resources{
-				Memory:         int64(resourceRequirements.Memory),
-				NanoCPUs:       int64(resourceRequirements.CPU * NanoCPUCoefficient),
-				DeviceRequests: deviceRequests,
-			},
-		},
+		hostConfig,
 		&network.NetworkingConfig{},
 		nil,
 		e.jobContainerName(j, shardIndex),
@@ -291,8 +378,6 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 		log.Trace().Msgf("Started container: %s", jobContainer.ID)
 	}
 
-	defer e.cleanupJob(j, shardIndex)
-
 	// the idea here is even if the container errors
 	// we want to capture stdout, stderr and feed it back to the user
 	var containerError error
@@ -318,14 +403,7 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 		log.Info().Msgf("container error %s", containerError)
 	}
 
-	stdout, stderr, err := system.RunCommandGetStdoutAndStderr(
-		"docker",
-		[]string{
-			"logs",
-			"-f",
-			jobContainer.ID,
-		},
-	)
+	stdout, stderr, err := docker.GetLogs(e.Client, jobContainer.ID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get logs: %w", err)
 	}
@@ -366,6 +444,156 @@ func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int)
 	return jobResultsDir, containerError
 }
 
+// pull the image if we don't have it already - images we pulled are kept in
+// the image cache (and won't be evicted) until release is called
+func (e *Executor) ensureImage(ctx context.Context, image string) (release func(), err error) {
+	if os.Getenv("SKIP_IMAGE_PULL") != "" {
+		return func() {}, nil
+	}
+	im, _, err := e.Client.ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		log.Debug().Msgf("Not pulling image %s, already have %s", image, im.ID)
+		// it was on the host before we pulled anything so it isn't ours
+		// to remove
+		if !e.images.has(im.ID) {
+			return func() {}, nil
+		}
+	} else if dockerclient.IsErrNotFound(err) {
+		err = e.pullImage(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		im, _, err = e.Client.ImageInspectWithRaw(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("error inspecting %s: %w", image, err)
+		}
+	} else {
+		return nil, fmt.Errorf("error checking if we have %s locally: %s", image, err)
+	}
+
+	id := e.images.acquire(image, im.ID, im.Size)
+	e.evictImages(ctx)
+	return func() {
+		e.images.release(id)
+		e.evictImages(context.Background())
+	}, nil
+}
+
+func (e *Executor) pullImage(ctx context.Context, image string) error {
+	registryAuth, err := e.Config.RegistryAuth.Encode(image)
+	if err != nil {
+		return err
+	}
+	if e.Config.PullTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Config.PullTimeout)
+		defer cancel()
+	}
+	err = docker.PullImage(ctx, e.Client, image, docker.PullOptions{
+		RegistryAuth: registryAuth,
+		MaxSize:      e.Config.MaxImageSize,
+		Progress: func(status string) {
+			executor.ReportProgress(ctx, status)
+		},
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("error pulling %s: gave up after %s", image, e.Config.PullTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("error pulling %s: %w", image, err)
+	}
+	imagePulls.WithLabelValues(e.ID).Inc()
+	log.Trace().Msgf("Pulled image %s", image)
+	return nil
+}
+
+// remove the least recently used images if they take up more than the budget
+func (e *Executor) evictImages(ctx context.Context) {
+	for _, image := range e.images.evict() {
+		log.Debug().Msgf("Removing image %s (%d bytes, used by %d shards) to stay within the image cache budget",
+			image.id, image.size, image.uses)
+		// removing the last reference removes the image - if nothing refers
+		// to it any more (e.g. :latest was pulled again) we remove it by id
+		refs := []string{}
+		for ref := range image.refs {
+			refs = append(refs, ref)
+		}
+		if len(refs) == 0 {
+			refs = append(refs, image.id)
+		}
+		for _, ref := range refs {
+			_, err := e.Client.ImageRemove(ctx, ref, dockertypes.ImageRemoveOptions{PruneChildren: true})
+			if err != nil && !dockerclient.IsErrNotFound(err) {
+				log.Warn().Msgf("Error removing image %s: %s", ref, err)
+			}
+		}
+	}
+	count, size := e.images.usage()
+	cachedImages.WithLabelValues(e.ID).Set(float64(count))
+	cachedImageBytes.WithLabelValues(e.ID).Set(float64(size))
+}
+
+// pull the images we were told to have ready and never evict them
+func (e *Executor) prePullImages(ctx context.Context) {
+	for _, image := range e.Config.ImageCache.PrePull {
+		err := e.Config.ImagePolicy.Check(image)
+		if err != nil {
+			log.Warn().Msgf("Not pre-pulling image %s: %s", image, err)
+			continue
+		}
+		release, err := e.ensureImage(ctx, image)
+		if err != nil {
+			log.Warn().Msgf("Error pre-pulling image %s: %s", image, err)
+			continue
+		}
+		e.images.pin(image)
+		release()
+		log.Debug().Msgf("Pre-pulled image %s", image)
+	}
+}
+
+// HasImageLocally is used to tell requesters we can run a job without
+// pulling its image first.
+func (e *Executor) HasImageLocally(ctx context.Context, image string) (bool, error) {
+	_, _, err := e.Client.ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		return true, nil
+	}
+	if dockerclient.IsErrNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// the compute node will have assigned specific devices to this shard
+// if we are being run without a compute node (e.g. in tests) then we
+// just ask docker for any devices
+func getGPUDeviceRequests(ctx context.Context, gpus uint64) ([]container.DeviceRequest, error) {
+	if gpus == 0 {
+		return nil, nil
+	}
+	deviceIDs := executor.GPUDevicesFromContext(ctx)
+	if len(deviceIDs) == 0 {
+		log.Trace().Msgf("Adding %d unassigned GPUs to request", gpus)
+		return []container.DeviceRequest{
+			{
+				Count:        int(gpus),
+				Capabilities: [][]string{{"gpu"}},
+			},
+		}, nil
+	}
+	if uint64(len(deviceIDs)) != gpus {
+		return nil, fmt.Errorf("job requires %d GPUs but was assigned %d: %v", gpus, len(deviceIDs), deviceIDs)
+	}
+	log.Trace().Msgf("Adding GPUs %v to request", deviceIDs)
+	return []container.DeviceRequest{
+		{
+			DeviceIDs:    deviceIDs,
+			Capabilities: [][]string{{"gpu"}},
+		},
+	}, nil
+}
+
 func (e *Executor) cleanupJob(job executor.Job, shardIndex int) {
 	if config.ShouldKeepStack() {
 		return
@@ -376,6 +604,9 @@ func (e *Executor) cleanupJob(job executor.Job, shardIndex int) {
 		log.Error().Msgf("Docker remove container error: %s", err.Error())
 		debug.PrintStack()
 	}
+	if job.Spec.Network.Type == executor.NetworkHTTP {
+		e.cleanupNetwork(job, shardIndex)
+	}
 }
 
 func (e *Executor) cleanupAll() {
@@ -397,6 +628,29 @@ func (e *Executor) cleanupAll() {
 			log.Error().Msgf("Non-critical error cleaning up container: %s", err.Error())
 		}
 	}
+	e.cleanupAllNetworks()
+	os.RemoveAll(e.proxyConfigDir)
+}
+
+func (e *Executor) GetResultsJobIDs(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(e.ResultsDir)
+	if err != nil {
+		return nil, err
+	}
+	jobIDs := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			jobIDs = append(jobIDs, entry.Name())
+		}
+	}
+	return jobIDs, nil
+}
+
+func (e *Executor) CleanupJobResults(ctx context.Context, jobID string) error {
+	if jobID == "" || filepath.Base(jobID) != jobID {
+		return fmt.Errorf("invalid job id: '%s'", jobID)
+	}
+	return os.RemoveAll(filepath.Join(e.ResultsDir, jobID))
 }
 
 func (e *Executor) jobContainerName(job executor.Job, shardIndex int) string {
@@ -428,3 +682,5 @@ func newSpan(ctx context.Context, apiName string) (context.Context, trace.Span)
 
 // Compile-time interface check:
 var _ executor.Executor = (*Executor)(nil)
+var _ executor.ResultsKeeper = (*Executor)(nil)
+var _ executor.ImageKeeper = (*Executor)(nil)
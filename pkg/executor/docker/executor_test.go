@@ -0,0 +1,273 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/filecoin-project/bacalhau/pkg/docker"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// just enough of a docker daemon to run a job - it remembers the config
+// containers were created with and they all exit straight away
+type fakeDockerClient struct {
+	dockerclient.APIClient
+	containers []dockertypes.Container
+	// the last container created
+	config     *container.Config
+	hostConfig *container.HostConfig
+	// every container created by name
+	configs     map[string]*container.Config
+	hostConfigs map[string]*container.HostConfig
+	// network name -> how it was created, and network id -> connected
+	// container ids and their aliases
+	networks           map[string]dockertypes.NetworkCreate
+	connected          map[string]map[string][]string
+	removedNetworks    []string
+	failNetworkConnect bool
+	// images are pulled (with these options) unless we already have them
+	haveImages    bool
+	hangOnPull    bool
+	imageSize     int64
+	pullOptions   dockertypes.ImagePullOptions
+	pulledImages  []string
+	removedImages []string
+}
+
+func (c *fakeDockerClient) ImageInspectWithRaw(ctx context.Context, image string) (dockertypes.ImageInspect, []byte, error) {
+	if !c.haveImages && !contains(c.pulledImages, image) {
+		return dockertypes.ImageInspect{}, nil, errdefs.NotFound(errors.New("no such image: " + image))
+	}
+	return dockertypes.ImageInspect{ID: "sha256:" + image, Size: c.imageSize}, nil, nil
+}
+
+func (c *fakeDockerClient) ImageRemove(
+	ctx context.Context, image string, options dockertypes.ImageRemoveOptions,
+) ([]dockertypes.ImageDeleteResponseItem, error) {
+	c.removedImages = append(c.removedImages, image)
+	return nil, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fakeDockerClient) ImagePull(ctx context.Context, image string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+	c.pullOptions = options
+	c.pulledImages = append(c.pulledImages, image)
+	reader, writer := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(writer)
+		_ = encoder.Encode(jsonmessage.JSONMessage{ID: "layer1", Status: "Pulling fs layer"})
+		if c.hangOnPull {
+			<-ctx.Done()
+			writer.CloseWithError(ctx.Err())
+			return
+		}
+		_ = encoder.Encode(jsonmessage.JSONMessage{ID: "layer1", Status: "Pull complete"})
+		writer.Close()
+	}()
+	return reader, nil
+}
+
+func (c *fakeDockerClient) ContainerCreate(
+	ctx context.Context,
+	config *container.Config,
+	hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig,
+	platform *specs.Platform,
+	containerName string,
+) (container.ContainerCreateCreatedBody, error) {
+	c.config = config
+	c.hostConfig = hostConfig
+	if c.configs == nil {
+		c.configs = map[string]*container.Config{}
+		c.hostConfigs = map[string]*container.HostConfig{}
+	}
+	c.configs[containerName] = config
+	c.hostConfigs[containerName] = hostConfig
+	id := fmt.Sprintf("%016x", len(c.configs))
+	c.containers = append(c.containers, dockertypes.Container{
+		ID:     id,
+		Names:  []string{"/" + containerName},
+		Labels: config.Labels,
+		State:  "running",
+	})
+	return container.ContainerCreateCreatedBody{ID: id}, nil
+}
+
+func (c *fakeDockerClient) ContainerStart(ctx context.Context, id string, options dockertypes.ContainerStartOptions) error {
+	return nil
+}
+
+func (c *fakeDockerClient) ContainerWait(
+	ctx context.Context, id string, condition container.WaitCondition,
+) (<-chan container.ContainerWaitOKBody, <-chan error) {
+	statusCh := make(chan container.ContainerWaitOKBody, 1)
+	statusCh <- container.ContainerWaitOKBody{StatusCode: 0}
+	return statusCh, make(chan error)
+}
+
+func (c *fakeDockerClient) ContainerList(ctx context.Context, options dockertypes.ContainerListOptions) ([]dockertypes.Container, error) {
+	return c.containers, nil
+}
+
+func (c *fakeDockerClient) ContainerLogs(ctx context.Context, id string, options dockertypes.ContainerLogsOptions) (io.ReadCloser, error) {
+	var logs bytes.Buffer
+	_, err := stdcopy.NewStdWriter(&logs, stdcopy.Stdout).Write([]byte("hello\n"))
+	return io.NopCloser(&logs), err
+}
+
+func (c *fakeDockerClient) ContainerStop(ctx context.Context, id string, timeout *time.Duration) error {
+	return nil
+}
+
+func (c *fakeDockerClient) ContainerRemove(ctx context.Context, id string, options dockertypes.ContainerRemoveOptions) error {
+	containers := []dockertypes.Container{}
+	for _, existing := range c.containers {
+		if existing.ID != id {
+			containers = append(containers, existing)
+		}
+	}
+	c.containers = containers
+	return nil
+}
+
+func (c *fakeDockerClient) NetworkCreate(
+	ctx context.Context, name string, options dockertypes.NetworkCreate,
+) (dockertypes.NetworkCreateResponse, error) {
+	if c.networks == nil {
+		c.networks = map[string]dockertypes.NetworkCreate{}
+	}
+	c.networks[name] = options
+	return dockertypes.NetworkCreateResponse{ID: "network-" + name}, nil
+}
+
+func (c *fakeDockerClient) NetworkConnect(ctx context.Context, networkID, containerID string, config *network.EndpointSettings) error {
+	if c.failNetworkConnect {
+		return errors.New("connect failed")
+	}
+	if c.connected == nil {
+		c.connected = map[string]map[string][]string{}
+	}
+	if c.connected[networkID] == nil {
+		c.connected[networkID] = map[string][]string{}
+	}
+	c.connected[networkID][containerID] = config.Aliases
+	return nil
+}
+
+func (c *fakeDockerClient) NetworkRemove(ctx context.Context, networkID string) error {
+	c.removedNetworks = append(c.removedNetworks, networkID)
+	return nil
+}
+
+func (c *fakeDockerClient) NetworkList(ctx context.Context, options dockertypes.NetworkListOptions) ([]dockertypes.NetworkResource, error) {
+	return nil, nil
+}
+
+func newTestExecutor(t *testing.T, config ExecutorConfig, client *fakeDockerClient) *Executor {
+	system.InitConfigForTesting(t)
+	cm := system.NewCleanupManager()
+	t.Cleanup(cm.Cleanup)
+
+	e, err := NewExecutorWithClient(cm, "dockertest", map[storage.StorageSourceType]storage.StorageProvider{}, config, client)
+	require.NoError(t, err)
+	return e
+}
+
+func testJob(env []string) executor.Job {
+	return executor.Job{
+		ID: "job-1",
+		Spec: executor.JobSpec{
+			Engine: executor.EngineDocker,
+			Docker: executor.JobSpecDocker{
+				Image:      "registry.example.com/team/app:v1",
+				Entrypoint: []string{"echo", "hello"},
+				Env:        env,
+			},
+			Outputs: []storage.StorageSpec{{Name: "outputs", Path: "/outputs"}},
+		},
+	}
+}
+
+func TestPullImage(t *testing.T) {
+	client := &fakeDockerClient{}
+	e := newTestExecutor(t, ExecutorConfig{
+		RegistryAuth: docker.RegistryAuth{
+			"registry.example.com": dockertypes.AuthConfig{Username: "user", Password: "pass"},
+		},
+	}, client)
+
+	statuses := []string{}
+	ctx := executor.ContextWithProgress(context.Background(), func(status string) {
+		statuses = append(statuses, status)
+	})
+	_, err := e.RunShard(ctx, testJob(nil), 0)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"registry.example.com/team/app:v1"}, client.pulledImages)
+	expectedAuth, err := e.Config.RegistryAuth.Encode("registry.example.com/team/app:v1")
+	require.NoError(t, err)
+	require.Equal(t, expectedAuth, client.pullOptions.RegistryAuth)
+	require.Equal(t, []string{
+		"Pulling image registry.example.com/team/app:v1",
+		"Pulling image registry.example.com/team/app:v1: 1/1 layers, downloaded 0 B of 0 B",
+	}, statuses)
+}
+
+func TestPullTimeout(t *testing.T) {
+	client := &fakeDockerClient{hangOnPull: true}
+	e := newTestExecutor(t, ExecutorConfig{PullTimeout: 100 * time.Millisecond}, client)
+
+	_, err := e.RunShard(context.Background(), testJob(nil), 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "gave up after 100ms")
+	require.Nil(t, client.config, "the container should not have been created")
+}
+
+func TestArgTemplates(t *testing.T) {
+	client := &fakeDockerClient{haveImages: true}
+	e := newTestExecutor(t, ExecutorConfig{}, client)
+
+	j := testJob(nil)
+	j.Spec.Docker.Entrypoint = nil
+	j.Spec.Docker.Cmd = []string{"--job", "{{.JobID}}", "--shard", "{{.ShardIndex}}/{{.TotalShards}}"}
+	j.ExecutionPlan.TotalShards = 3
+	_, err := e.RunShard(context.Background(), j, 0)
+	require.NoError(t, err)
+	// the job didn't ask for templating
+	require.Equal(t, j.Spec.Docker.Cmd, []string(client.config.Cmd))
+
+	client = &fakeDockerClient{haveImages: true}
+	e = newTestExecutor(t, ExecutorConfig{}, client)
+	j.Spec.Docker.TemplateArgs = true
+	_, err = e.RunShard(context.Background(), j, 0)
+	require.NoError(t, err)
+
+	// the image's own entrypoint is kept
+	require.Empty(t, client.config.Entrypoint)
+	require.Equal(t, []string{"--job", "job-1", "--shard", "0/3"}, []string(client.config.Cmd))
+}
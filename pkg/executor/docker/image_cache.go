@@ -0,0 +1,170 @@
+package docker
+
+import (
+	"sort"
+	"time"
+
+	sync "github.com/lukemarsden/golang-mutex-tracer"
+)
+
+type ImageCacheConfig struct {
+	// pulled when the executor starts so the first jobs that use them don't
+	// have to wait - these are never evicted
+	PrePull []string
+	// once the images jobs have used take up more than this many bytes the
+	// least recently used are removed - no limit if 0
+	DiskBudget uint64
+}
+
+type cachedImage struct {
+	id string
+	// the references jobs have used for the image (e.g. ubuntu and ubuntu:latest)
+	refs map[string]bool
+	size int64
+	// when the image was last used - ticks of the cache's clock rather than
+	// the time so that the order is exact
+	lastUsed uint64
+	// how many times jobs have used the image
+	uses int
+	// how many running shards are using the image right now
+	inUse  int
+	pinned bool
+}
+
+// keeps track of the images jobs have used so we can get rid of the ones
+// we haven't used for a while - images that were on the host before jobs
+// used them don't count towards the budget
+type imageCache struct {
+	budget uint64
+	// image id -> image
+	images map[string]*cachedImage
+	// image reference -> image id
+	refs  map[string]string
+	clock uint64
+	mu    sync.Mutex
+}
+
+func newImageCache(budget uint64) *imageCache {
+	cache := &imageCache{
+		budget: budget,
+		images: map[string]*cachedImage{},
+		refs:   map[string]string{},
+	}
+	cache.mu.EnableTracerWithOpts(sync.Opts{
+		Threshold: 10 * time.Millisecond,
+		Id:        "imageCache.mu",
+	})
+	return cache
+}
+
+// record that a shard is using the image - it won't be evicted until the
+// shard releases it by the returned id
+func (cache *imageCache) acquire(ref, id string, size int64) string {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// the reference might have moved on to a new image (e.g. a newer :latest)
+	if oldID, ok := cache.refs[ref]; ok && oldID != id {
+		delete(cache.images[oldID].refs, ref)
+	}
+
+	image, ok := cache.images[id]
+	if !ok {
+		image = &cachedImage{
+			id:   id,
+			refs: map[string]bool{},
+		}
+		cache.images[id] = image
+	}
+	cache.clock++
+	image.refs[ref] = true
+	image.size = size
+	image.lastUsed = cache.clock
+	image.uses++
+	image.inUse++
+	cache.refs[ref] = id
+	return id
+}
+
+// by id rather than reference - the reference might point at another image
+// by the time the shard has finished
+func (cache *imageCache) release(id string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	image, ok := cache.images[id]
+	if ok && image.inUse > 0 {
+		image.inUse--
+	}
+}
+
+// whether the image is one jobs made us pull
+func (cache *imageCache) has(id string) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	_, ok := cache.images[id]
+	return ok
+}
+
+// never evict the image
+func (cache *imageCache) pin(ref string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	image, ok := cache.images[cache.refs[ref]]
+	if ok {
+		image.pinned = true
+	}
+}
+
+// forget the least recently used images until we're back under budget and
+// return them (least recently used first) so they can be removed
+func (cache *imageCache) evict() []cachedImage {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.budget == 0 {
+		return nil
+	}
+
+	images := cache.byLastUsed()
+	total := uint64(0)
+	for _, image := range images {
+		total += uint64(image.size)
+	}
+
+	evicted := []cachedImage{}
+	for i := len(images) - 1; i >= 0 && total > cache.budget; i-- {
+		image := images[i]
+		if image.pinned || image.inUse > 0 {
+			continue
+		}
+		total -= uint64(image.size)
+		for ref := range image.refs {
+			delete(cache.refs, ref)
+		}
+		delete(cache.images, image.id)
+		evicted = append(evicted, *image)
+	}
+	return evicted
+}
+
+// the number of images and the bytes they take up
+func (cache *imageCache) usage() (count int, size uint64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, image := range cache.images {
+		size += uint64(image.size)
+	}
+	return len(cache.images), size
+}
+
+// most recently used first
+func (cache *imageCache) byLastUsed() []*cachedImage {
+	images := make([]*cachedImage, 0, len(cache.images))
+	for _, image := range cache.images {
+		images = append(images, image)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].lastUsed > images[j].lastUsed
+	})
+	return images
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/docker"
+	"github.com/stretchr/testify/require"
+)
+
+func evictedIDs(cache *imageCache) []string {
+	ids := []string{}
+	for _, image := range cache.evict() {
+		ids = append(ids, image.id)
+	}
+	return ids
+}
+
+func TestImageCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newImageCache(250)
+	cache.acquire("a", "id-a", 100)
+	cache.acquire("b", "id-b", 100)
+	cache.acquire("c", "id-c", 100)
+	for _, id := range []string{"id-a", "id-b", "id-c"} {
+		cache.release(id)
+	}
+	// a is used again so b is the oldest
+	cache.acquire("a", "id-a", 100)
+	cache.release("id-a")
+
+	require.Equal(t, []string{"id-b"}, evictedIDs(cache))
+	count, size := cache.usage()
+	require.Equal(t, 2, count)
+	require.Equal(t, uint64(200), size)
+	require.Empty(t, evictedIDs(cache))
+}
+
+func TestImageCacheKeepsImagesInUseAndPinned(t *testing.T) {
+	cache := newImageCache(100)
+	cache.acquire("pinned", "id-pinned", 100)
+	cache.pin("pinned")
+	cache.release("id-pinned")
+	cache.acquire("running", "id-running", 100)
+	cache.acquire("done", "id-done", 100)
+	cache.release("id-done")
+
+	require.Equal(t, []string{"id-done"}, evictedIDs(cache))
+
+	// still over budget but nothing else can go until the shard finishes
+	require.Empty(t, evictedIDs(cache))
+	cache.release("id-running")
+	require.Equal(t, []string{"id-running"}, evictedIDs(cache))
+}
+
+func TestImageCacheSharedImages(t *testing.T) {
+	cache := newImageCache(50)
+	id := cache.acquire("ubuntu", "id-1", 100)
+	cache.acquire("ubuntu:latest", "id-1", 100)
+	cache.release(id)
+	cache.release(id)
+
+	evicted := cache.evict()
+	require.Len(t, evicted, 1)
+	require.Equal(t, map[string]bool{"ubuntu": true, "ubuntu:latest": true}, evicted[0].refs)
+	require.Equal(t, 2, evicted[0].uses)
+}
+
+func TestImageCacheNoBudget(t *testing.T) {
+	cache := newImageCache(0)
+	cache.acquire("a", "id-a", 1000)
+	cache.release("id-a")
+	require.Empty(t, evictedIDs(cache))
+}
+
+func TestPrePullAndEvictImages(t *testing.T) {
+	client := &fakeDockerClient{imageSize: 10}
+	e := newTestExecutor(t, ExecutorConfig{
+		ImagePolicy: docker.ImagePolicy{Deny: []string{"denied"}},
+		ImageCache:  ImageCacheConfig{DiskBudget: 15},
+	}, client)
+
+	// normally this happens in the background when the executor starts
+	e.Config.ImageCache.PrePull = []string{"ubuntu", "denied"}
+	e.prePullImages(context.Background())
+	require.Equal(t, []string{"ubuntu"}, client.pulledImages)
+
+	hasImage, err := e.HasImageLocally(context.Background(), "ubuntu")
+	require.NoError(t, err)
+	require.True(t, hasImage)
+	hasImage, err = e.HasImageLocally(context.Background(), "registry.example.com/team/app:v1")
+	require.NoError(t, err)
+	require.False(t, hasImage)
+
+	// the job's image takes us over budget once it has finished with it and
+	// the pre-pulled image has to stay
+	_, err = e.RunShard(context.Background(), testJob(nil), 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ubuntu", "registry.example.com/team/app:v1"}, client.pulledImages)
+	require.Equal(t, []string{"registry.example.com/team/app:v1"}, client.removedImages)
+
+	count, size := e.images.usage()
+	require.Equal(t, 1, count)
+	require.Equal(t, uint64(10), size)
+}
+
+func TestImageCacheReleasesByID(t *testing.T) {
+	cache := newImageCache(50)
+	id := cache.acquire("ubuntu:latest", "id-1", 100)
+	// a newer :latest is pulled while the first shard is still running
+	cache.acquire("ubuntu:latest", "id-2", 100)
+	cache.release(id)
+	require.Equal(t, []string{"id-1"}, evictedIDs(cache))
+}
+
+func TestPreExistingImagesAreNeverEvicted(t *testing.T) {
+	client := &fakeDockerClient{haveImages: true, imageSize: 10}
+	e := newTestExecutor(t, ExecutorConfig{
+		ImageCache: ImageCacheConfig{DiskBudget: 5},
+	}, client)
+
+	_, err := e.RunShard(context.Background(), testJob(nil), 0)
+	require.NoError(t, err)
+	require.Empty(t, client.pulledImages)
+	require.Empty(t, client.removedImages)
+
+	count, size := e.images.usage()
+	require.Equal(t, 0, count)
+	require.Equal(t, uint64(0), size)
+}
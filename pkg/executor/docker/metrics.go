@@ -0,0 +1,33 @@
+package docker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for the images jobs have used:
+var (
+	cachedImages = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "docker_cached_images",
+			Help: "Number of images the docker executor is keeping for jobs.",
+		},
+		[]string{"executor_id"},
+	)
+
+	cachedImageBytes = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "docker_cached_image_bytes",
+			Help: "Disk space taken up by the images the docker executor is keeping for jobs.",
+		},
+		[]string{"executor_id"},
+	)
+
+	imagePulls = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "docker_image_pulls",
+			Help: "Number of images the docker executor had to pull before it could run a job.",
+		},
+		[]string{"executor_id"},
+	)
+)
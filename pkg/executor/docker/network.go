@@ -0,0 +1,244 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/filecoin-project/bacalhau/pkg/docker"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	jobutils "github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/storage/util"
+	"github.com/rs/zerolog/log"
+)
+
+// http jobs are put on an internal network that has nothing else on it but a
+// squid proxy (which is also on the default bridge so it can get out) that
+// only lets requests for the job's domains through
+const DefaultProxyImage = "ubuntu/squid:5.2-22.04_beta"
+
+const proxyAlias = "bacalhau-proxy"
+const proxyPort = 3128
+
+// how the job container is connected to the network
+type jobNetwork struct {
+	disabled bool
+	mode     container.NetworkMode
+	env      []string
+}
+
+func (e *Executor) setupNetwork(ctx context.Context, j executor.Job, shardIndex int) (jobNetwork, error) {
+	switch j.Spec.Network.Type {
+	case executor.NetworkNone:
+		return jobNetwork{disabled: true}, nil
+	case executor.NetworkFull:
+		return jobNetwork{}, nil
+	case executor.NetworkHTTP:
+		return e.setupHTTPNetwork(ctx, j, shardIndex)
+	default:
+		return jobNetwork{}, fmt.Errorf("unsupported network type: %s", j.Spec.Network.Type)
+	}
+}
+
+//nolint:funlen // reads best top to bottom
+func (e *Executor) setupHTTPNetwork(ctx context.Context, j executor.Job, shardIndex int) (_ jobNetwork, err error) {
+	// the domains go straight into the proxy's config
+	err = jobutils.ValidateNetwork(j.Spec.Network)
+	if err != nil {
+		return jobNetwork{}, err
+	}
+
+	proxyImage := e.Config.ProxyImage
+	if proxyImage == "" {
+		proxyImage = DefaultProxyImage
+	}
+	releaseImage, err := e.ensureImage(ctx, proxyImage)
+	if err != nil {
+		return jobNetwork{}, err
+	}
+	// docker won't remove the image once the proxy container has been created
+	defer releaseImage()
+
+	networkName := e.jobContainerName(j, shardIndex)
+	jobNet, err := e.Client.NetworkCreate(ctx, networkName, dockertypes.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+		// no route out except through the proxy
+		Internal: true,
+		Labels:   e.jobContainerLabels(j),
+	})
+	if err != nil {
+		return jobNetwork{}, fmt.Errorf("failed to create network: %w", err)
+	}
+	// don't leave the network, the proxy or its config behind if we can't
+	// finish setting them up
+	defer func() {
+		if err != nil {
+			e.cleanupNetwork(j, shardIndex)
+		}
+	}()
+
+	configPath := e.proxyConfigPath(j, shardIndex)
+	err = os.WriteFile(configPath, []byte(proxyConfig(j.Spec.Network.Domains)), util.OS_ALL_R|util.OS_USER_RW)
+	if err != nil {
+		return jobNetwork{}, err
+	}
+
+	proxyContainerConfig := &container.Config{
+		Image:  proxyImage,
+		Labels: e.jobContainerLabels(j),
+	}
+	proxyHostConfig := &container.HostConfig{
+		Mounts: []mount.Mount{
+			{
+				Type:     "bind",
+				ReadOnly: true,
+				Source:   configPath,
+				Target:   "/etc/squid/squid.conf",
+			},
+		},
+	}
+	e.Config.Security.applyToProxy(proxyContainerConfig, proxyHostConfig)
+
+	proxy, err := e.Client.ContainerCreate(
+		ctx,
+		proxyContainerConfig,
+		proxyHostConfig,
+		&network.NetworkingConfig{},
+		nil,
+		e.proxyContainerName(j, shardIndex),
+	)
+	if err != nil {
+		return jobNetwork{}, fmt.Errorf("failed to create proxy container: %w", err)
+	}
+
+	err = e.Client.NetworkConnect(ctx, jobNet.ID, proxy.ID, &network.EndpointSettings{
+		Aliases: []string{proxyAlias},
+	})
+	if err != nil {
+		return jobNetwork{}, fmt.Errorf("failed to connect proxy to the job network: %w", err)
+	}
+
+	err = e.Client.ContainerStart(ctx, proxy.ID, dockertypes.ContainerStartOptions{})
+	if err != nil {
+		return jobNetwork{}, fmt.Errorf("failed to start proxy container: %w", err)
+	}
+	err = docker.WaitForContainer(e.Client, proxy.ID, 50, time.Millisecond*100)
+	if err != nil {
+		return jobNetwork{}, err
+	}
+
+	proxyURL := fmt.Sprintf("http://%s:%d", proxyAlias, proxyPort)
+	return jobNetwork{
+		mode: container.NetworkMode(networkName),
+		env: []string{
+			"HTTP_PROXY=" + proxyURL,
+			"HTTPS_PROXY=" + proxyURL,
+			"http_proxy=" + proxyURL,
+			"https_proxy=" + proxyURL,
+		},
+	}, nil
+}
+
+// squid only lets http and https (CONNECT) through to the job's domains
+// and never to private addresses one of them might resolve to -
+// *.example.com is .example.com to squid
+func proxyConfig(domains []string) string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if strings.HasPrefix(domain, "*.") {
+			domain = strings.TrimPrefix(domain, "*")
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			normalized = append(normalized, domain)
+		}
+	}
+
+	// squid won't start if a domain is covered by a wildcard as well, e.g.
+	// example.com or .api.example.com alongside .example.com
+	dstdomains := []string{}
+	for _, domain := range normalized {
+		if !coveredByWildcard(domain, normalized) {
+			dstdomains = append(dstdomains, domain)
+		}
+	}
+
+	return strings.Join([]string{
+		fmt.Sprintf("http_port %d", proxyPort),
+		"acl job_domains dstdomain " + strings.Join(dstdomains, " "),
+		"acl to_private dst 10.0.0.0/8 172.16.0.0/12 192.168.0.0/16 169.254.0.0/16 127.0.0.0/8",
+		"acl safe_ports port 80 443",
+		"acl ssl_ports port 443",
+		"acl CONNECT method CONNECT",
+		"http_access deny !safe_ports",
+		"http_access deny CONNECT !ssl_ports",
+		"http_access deny to_private",
+		"http_access allow job_domains",
+		"http_access deny all",
+		"cache deny all",
+		"",
+	}, "\n")
+}
+
+func coveredByWildcard(domain string, domains []string) bool {
+	for _, wildcard := range domains {
+		if wildcard == domain || !strings.HasPrefix(wildcard, ".") {
+			continue
+		}
+		if domain == strings.TrimPrefix(wildcard, ".") || strings.HasSuffix(domain, wildcard) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Executor) cleanupNetwork(job executor.Job, shardIndex int) {
+	err := docker.RemoveContainer(e.Client, e.proxyContainerName(job, shardIndex))
+	if err != nil {
+		log.Error().Msgf("Docker remove proxy container error: %s", err.Error())
+	}
+	err = e.Client.NetworkRemove(context.Background(), e.jobContainerName(job, shardIndex))
+	if err != nil && !dockerclient.IsErrNotFound(err) {
+		log.Error().Msgf("Docker remove network error: %s", err.Error())
+	}
+	err = os.Remove(e.proxyConfigPath(job, shardIndex))
+	if err != nil && !os.IsNotExist(err) {
+		log.Error().Msgf("Error removing proxy config: %s", err.Error())
+	}
+}
+
+func (e *Executor) cleanupAllNetworks() {
+	networks, err := e.Client.NetworkList(context.Background(), dockertypes.NetworkListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "bacalhau-executor="+e.ID)),
+	})
+	if err != nil {
+		log.Error().Msgf("Docker executor network list error: %s", err.Error())
+		return
+	}
+	for _, jobNet := range networks {
+		err = e.Client.NetworkRemove(context.Background(), jobNet.ID)
+		if err != nil {
+			log.Error().Msgf("Non-critical error cleaning up network: %s", err.Error())
+		}
+	}
+}
+
+func (e *Executor) proxyContainerName(job executor.Job, shardIndex int) string {
+	return e.jobContainerName(job, shardIndex) + "-proxy"
+}
+
+func (e *Executor) proxyConfigPath(job executor.Job, shardIndex int) string {
+	return filepath.Join(e.proxyConfigDir, e.jobContainerName(job, shardIndex)+".conf")
+}
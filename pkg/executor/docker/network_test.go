@@ -0,0 +1,104 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyConfig(t *testing.T) {
+	config := proxyConfig([]string{"example.com", "*.Example.org", "*.example.org"})
+	require.Contains(t, strings.Split(config, "\n"), "acl job_domains dstdomain example.com .example.org")
+	require.Contains(t, config, "http_port 3128\n")
+	// everything that isn't for the job's domains is denied
+	require.True(t, strings.HasSuffix(config, "http_access allow job_domains\nhttp_access deny all\ncache deny all\n"))
+	// even if one of them resolves to a private address
+	require.Contains(t, config, "http_access deny to_private\nhttp_access allow job_domains\n")
+}
+
+func TestProxyConfigWildcards(t *testing.T) {
+	config := proxyConfig([]string{"example.com", "*.api.example.com", "*.example.com", "www.example.org"})
+	require.Contains(t, strings.Split(config, "\n"), "acl job_domains dstdomain .example.com www.example.org")
+}
+
+func httpJob() executor.Job {
+	j := testJob(nil)
+	j.Spec.Network = executor.JobSpecNetwork{
+		Type:    executor.NetworkHTTP,
+		Domains: []string{"example.com"},
+	}
+	return j
+}
+
+func TestHTTPNetwork(t *testing.T) {
+	client := &fakeDockerClient{haveImages: true}
+	e := newTestExecutor(t, ExecutorConfig{Security: DefaultSecurityProfile()}, client)
+
+	j := httpJob()
+	_, err := e.RunShard(context.Background(), j, 0)
+	require.NoError(t, err)
+
+	networkName := e.jobContainerName(j, 0)
+	require.True(t, client.networks[networkName].Internal, "the job network should have no route out")
+
+	// the job is on the internal network and goes through the proxy
+	jobName := e.jobContainerName(j, 0)
+	require.Equal(t, container.NetworkMode(networkName), client.hostConfigs[jobName].NetworkMode)
+	require.Contains(t, client.configs[jobName].Env, "HTTP_PROXY=http://bacalhau-proxy:3128")
+
+	// the proxy is on the job network too and is locked down like the job
+	proxyName := e.proxyContainerName(j, 0)
+	proxyID := ""
+	for id, aliases := range client.connected["network-"+networkName] {
+		require.Equal(t, []string{proxyAlias}, aliases)
+		proxyID = id
+	}
+	require.Equal(t, "0000000000000001", proxyID, "the proxy is created before the job")
+	proxyHostConfig := client.hostConfigs[proxyName]
+	require.Equal(t, "/etc/squid/squid.conf", proxyHostConfig.Mounts[0].Target)
+	require.Equal(t, strslice.StrSlice{"ALL"}, proxyHostConfig.CapDrop)
+	require.Equal(t, []string{"no-new-privileges"}, proxyHostConfig.SecurityOpt)
+	require.True(t, proxyHostConfig.ReadonlyRootfs)
+	require.Empty(t, client.configs[proxyName].User, "squid switches to its own user")
+
+	// and it's all gone once the job has finished
+	require.Equal(t, []string{networkName}, client.removedNetworks)
+	require.Empty(t, client.containers)
+	_, err = os.Stat(e.proxyConfigPath(j, 0))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestHTTPNetworkCleanupOnError(t *testing.T) {
+	client := &fakeDockerClient{haveImages: true, failNetworkConnect: true}
+	e := newTestExecutor(t, ExecutorConfig{}, client)
+
+	j := httpJob()
+	_, err := e.setupHTTPNetwork(context.Background(), j, 0)
+	require.Error(t, err)
+
+	require.Equal(t, []string{e.jobContainerName(j, 0)}, client.removedNetworks)
+	require.Empty(t, client.containers, "the proxy container should have been removed")
+	_, err = os.Stat(e.proxyConfigPath(j, 0))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestHTTPNetworkRejectsInvalidDomains(t *testing.T) {
+	client := &fakeDockerClient{haveImages: true}
+	e := newTestExecutor(t, ExecutorConfig{}, client)
+
+	// a requester that doesn't check the domains can't write our proxy config
+	j := httpJob()
+	j.Spec.Network.Domains = []string{"example.com\nhttp_access allow all"}
+	_, err := e.setupHTTPNetwork(context.Background(), j, 0)
+	require.Error(t, err)
+
+	require.Empty(t, client.networks)
+	_, err = os.Stat(e.proxyConfigPath(j, 0))
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// what job containers are allowed to do - the zero value leaves containers
+// with docker's defaults, DefaultSecurityProfile locks them down
+type SecurityProfile struct {
+	// drop every capability (docker leaves containers with a handful, e.g. chown and net_raw)
+	DropCapabilities bool
+	// stop processes gaining privileges (e.g. through setuid binaries)
+	NoNewPrivileges bool
+	// the root filesystem is read only - jobs can only write to their
+	// outputs and a tmpfs on /tmp
+	ReadOnlyRootFS bool
+	// the size of the /tmp tmpfs (e.g. 512m) - no limit if empty
+	ScratchSize string
+	// the most processes a job can run at once - no limit if 0
+	PidsLimit int64
+	// the user (uid:gid) jobs run as rather than the image's user (which is
+	// usually root)
+	User string
+	// a seccomp profile (the JSON, not a path) - docker's default profile if empty
+	SeccompProfile string
+}
+
+// nobody:nogroup on most distros
+const DefaultJobUser = "65534:65534"
+
+func DefaultSecurityProfile() SecurityProfile {
+	return SecurityProfile{
+		DropCapabilities: true,
+		NoNewPrivileges:  true,
+		ReadOnlyRootFS:   true,
+		ScratchSize:      "1g",
+		PidsLimit:        1024, //nolint:gomnd
+		User:             DefaultJobUser,
+	}
+}
+
+// lock the container down as far as the profile says
+func (profile SecurityProfile) apply(containerConfig *container.Config, hostConfig *container.HostConfig) {
+	if profile.DropCapabilities {
+		hostConfig.CapDrop = []string{"ALL"}
+	}
+	if profile.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges")
+	}
+	if profile.SeccompProfile != "" {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+profile.SeccompProfile)
+	}
+	if profile.ReadOnlyRootFS {
+		hostConfig.ReadonlyRootfs = true
+		options := "rw,nosuid,nodev"
+		if profile.ScratchSize != "" {
+			options += ",size=" + profile.ScratchSize
+		}
+		hostConfig.Tmpfs = map[string]string{"/tmp": options}
+	}
+	if profile.PidsLimit > 0 {
+		pidsLimit := profile.PidsLimit
+		hostConfig.Resources.PidsLimit = &pidsLimit
+	}
+	if profile.User != "" {
+		containerConfig.User = profile.User
+		// the user probably doesn't have a home directory (and if it does
+		// it won't be writable) so tools that want one get the scratch space
+		if !hasEnv(containerConfig.Env, "HOME") {
+			containerConfig.Env = append(containerConfig.Env, "HOME=/tmp")
+		}
+	}
+}
+
+// the proxy gets the same profile as jobs apart from the user - squid starts
+// as root and switches to its own user, so it needs to be able to do that and
+// to write its pid file, logs and cache when the root filesystem is read only
+func (profile SecurityProfile) applyToProxy(containerConfig *container.Config, hostConfig *container.HostConfig) {
+	profile.User = ""
+	profile.apply(containerConfig, hostConfig)
+	if profile.DropCapabilities {
+		hostConfig.CapAdd = []string{"CHOWN", "SETGID", "SETUID"}
+	}
+	if profile.ReadOnlyRootFS {
+		for _, dir := range []string{"/run", "/var/log/squid", "/var/spool/squid"} {
+			hostConfig.Tmpfs[dir] = "rw,nosuid,nodev,mode=1777"
+		}
+	}
+}
+
+func hasEnv(env []string, name string) bool {
+	for _, value := range env {
+		if strings.HasPrefix(value, name+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/stretchr/testify/require"
+)
+
+// runs a job with the profile and returns what the container was created with
+func runWithProfile(t *testing.T, profile SecurityProfile, env []string) (*fakeDockerClient, string) {
+	client := &fakeDockerClient{haveImages: true}
+	e := newTestExecutor(t, ExecutorConfig{Security: profile}, client)
+
+	resultsDir, err := e.RunShard(context.Background(), testJob(env), 0)
+	require.NoError(t, err)
+
+	stdout, err := os.ReadFile(filepath.Join(resultsDir, "stdout"))
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", string(stdout))
+	return client, resultsDir
+}
+
+func TestDefaultSecurityProfile(t *testing.T) {
+	client, resultsDir := runWithProfile(t, DefaultSecurityProfile(), nil)
+
+	require.Equal(t, strslice.StrSlice{"ALL"}, client.hostConfig.CapDrop)
+	require.Equal(t, []string{"no-new-privileges"}, client.hostConfig.SecurityOpt)
+	require.True(t, client.hostConfig.ReadonlyRootfs)
+	require.Equal(t, map[string]string{"/tmp": "rw,nosuid,nodev,size=1g"}, client.hostConfig.Tmpfs)
+	require.NotNil(t, client.hostConfig.PidsLimit)
+	require.Equal(t, int64(1024), *client.hostConfig.PidsLimit)
+	require.Equal(t, DefaultJobUser, client.config.User)
+	require.Contains(t, client.config.Env, "HOME=/tmp")
+
+	// the job's user has to be able to write its outputs
+	info, err := os.Stat(filepath.Join(resultsDir, "outputs"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0777), info.Mode().Perm())
+}
+
+func TestSeccompProfile(t *testing.T) {
+	profile := DefaultSecurityProfile()
+	profile.SeccompProfile = `{"defaultAction":"SCMP_ACT_ERRNO"}`
+	profile.User = "1000:1000"
+	client, _ := runWithProfile(t, profile, []string{"HOME=/home/job"})
+
+	require.Equal(t, []string{
+		"no-new-privileges",
+		`seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`,
+	}, client.hostConfig.SecurityOpt)
+	require.Equal(t, "1000:1000", client.config.User)
+	require.Contains(t, client.config.Env, "HOME=/home/job")
+	require.NotContains(t, client.config.Env, "HOME=/tmp")
+}
+
+func TestRelaxedSecurityProfile(t *testing.T) {
+	client, _ := runWithProfile(t, SecurityProfile{}, nil)
+
+	require.Empty(t, client.hostConfig.CapDrop)
+	require.Empty(t, client.hostConfig.SecurityOpt)
+	require.False(t, client.hostConfig.ReadonlyRootfs)
+	require.Empty(t, client.hostConfig.Tmpfs)
+	require.Nil(t, client.hostConfig.PidsLimit)
+	require.Empty(t, client.config.User)
+	require.NotContains(t, client.config.Env, "HOME=/tmp")
+}
@@ -2,12 +2,14 @@ package language
 
 /*
 The language executor wraps either the python_wasm executor or the generic
-docker executor, depending on whether determinism is required.
+docker executor, depending on whether determinism is required. Languages that
+run in docker are set up by the runtimes in runtimes.go.
 */
 
 import (
 	"context"
 	"fmt"
+	"path"
 
 	"github.com/rs/zerolog/log"
 
@@ -16,6 +18,9 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/system"
 )
 
+// the requestor mounts the context here
+const contextPath = "/job"
+
 type Executor struct {
 	Jobs []*executor.Job
 
@@ -45,21 +50,107 @@ func (e *Executor) GetVolumeSize(ctx context.Context, volumes storage.StorageSpe
 }
 
 func (e *Executor) RunShard(ctx context.Context, job executor.Job, shardIndex int) (string, error) {
-	if job.Spec.Language.Language != "python" && job.Spec.Language.LanguageVersion != "3.10" {
-		return "", fmt.Errorf("only python 3.10 is supported")
+	language := job.Spec.Language
+	if language.Deterministic {
+		if language.Language != "python" || language.LanguageVersion != "3.10" {
+			return "", fmt.Errorf("only python 3.10 can be run deterministically")
+		}
+		log.Debug().Msgf("running deterministic python 3.10")
+		return e.executors[executor.EnginePythonWasm].RunShard(ctx, job, shardIndex)
 	}
 
+	log.Debug().Msgf("running arbitrary %s %s", language.Language, language.LanguageVersion)
+	dockerJob, err := getDockerJob(job)
+	if err != nil {
+		return "", err
+	}
+	return e.executors[executor.EngineDocker].RunShard(ctx, dockerJob, shardIndex)
+}
+
+func (e *Executor) GetJobImage(ctx context.Context, job executor.Job) (string, error) {
 	if job.Spec.Language.Deterministic {
-		log.Debug().Msgf("running deterministic python 3.10")
-		// Instantiate a python_wasm
-		// TODO: mutate job as needed?
-		return e.executors[executor.EnginePythonWasm].RunShard(ctx, job, shardIndex)
+		resolver, ok := e.executors[executor.EnginePythonWasm].(executor.ImageResolver)
+		if !ok {
+			return "", nil
+		}
+		return resolver.GetJobImage(ctx, job)
+	}
+	dockerJob, err := getDockerJob(job)
+	if err != nil {
+		return "", err
+	}
+	return dockerJob.Spec.Docker.Image, nil
+}
+
+// only deterministic python can run in more than one runtime
+func (e *Executor) GetJobRuntime(ctx context.Context, job executor.Job) (string, error) {
+	if !job.Spec.Language.Deterministic {
+		return "", nil
+	}
+	namer, ok := e.executors[executor.EnginePythonWasm].(executor.RuntimeNamer)
+	if !ok {
+		return "", nil
+	}
+	return namer.GetJobRuntime(ctx, job)
+}
+
+// translate a language job into a docker job on the language's official
+// image - containers have no network so dependencies are installed from the
+// packages in the context
+func getDockerJob(job executor.Job) (executor.Job, error) {
+	language := job.Spec.Language
+	runtime, ok := runtimes[language.Language]
+	if !ok {
+		return job, fmt.Errorf("unsupported language: %s", language.Language)
+	}
+	if !versionRegex.MatchString(language.LanguageVersion) {
+		return job, fmt.Errorf("invalid %s version: %s", language.Language, language.LanguageVersion)
+	}
+
+	var run []string
+	if language.Command != "" {
+		run = runtime.command(language.Command)
+	} else if language.ProgramPath != "" {
+		run = runtime.program(path.Join(contextPath, language.ProgramPath))
 	} else {
-		log.Debug().Msgf("running arbitrary python 3.10")
-		// TODO: Instantiate a docker with python:3.10 image
-		return "", fmt.Errorf("arbitrary python not supported yet")
+		return job, fmt.Errorf("%s jobs need a command or a program path", language.Language)
+	}
+
+	hasContext := false
+	for _, volume := range job.Spec.Contexts {
+		if volume.Path == contextPath {
+			hasContext = true
+		}
+	}
+
+	entrypoint := run
+	if language.RequirementsPath != "" {
+		if runtime.install == "" {
+			return job, fmt.Errorf("%s jobs can't have requirements", language.Language)
+		}
+		if !hasContext {
+			return job, fmt.Errorf("requirements given but the job has no context")
+		}
+		// sh -c gets the requirements as $0 and the program as $@ so nothing
+		// needs quoting - the installer logs to stderr to keep stdout for the job
+		entrypoint = append([]string{
+			"/bin/sh", "-c",
+			runtime.install + ` 1>&2 && exec "$@"`,
+			path.Join(contextPath, language.RequirementsPath),
+		}, run...)
+		job.Spec.Docker.Env = append(job.Spec.Docker.Env, runtime.env...)
+	}
+
+	job.Spec.Engine = executor.EngineDocker
+	job.Spec.Docker.Image = fmt.Sprintf("%s:%s", runtime.image, language.LanguageVersion)
+	job.Spec.Docker.Entrypoint = entrypoint
+	if hasContext {
+		job.Spec.Docker.WorkingDir = contextPath
 	}
+	return job, nil
 }
 
 // Compile-time check that Executor implements the Executor interface.
 var _ executor.Executor = (*Executor)(nil)
+var _ executor.ImageResolver = (*Executor)(nil)
+var _ executor.RuntimeNamer = (*Executor)(nil)
@@ -0,0 +1,223 @@
+package language
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	noop_executor "github.com/filecoin-project/bacalhau/pkg/executor/noop"
+	pythonwasm "github.com/filecoin-project/bacalhau/pkg/executor/python_wasm"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/stretchr/testify/require"
+)
+
+// runs the job on a language executor that wraps noop executors and
+// returns the job the wrapped executor was given
+func runJob(t *testing.T, j executor.Job) (executor.EngineType, executor.Job, error) {
+	cm := system.NewCleanupManager()
+	t.Cleanup(cm.Cleanup)
+
+	var ranOn executor.EngineType
+	var ran executor.Job
+	executors := map[executor.EngineType]executor.Executor{}
+	for _, engine := range []executor.EngineType{executor.EngineDocker, executor.EnginePythonWasm} {
+		engine := engine
+		noopExecutor, err := noop_executor.NewExecutorWithConfig(noop_executor.ExecutorConfig{
+			ExternalHooks: noop_executor.ExecutorConfigExternalHooks{
+				JobHandler: func(ctx context.Context, job executor.Job, shardIndex int) (string, error) {
+					ranOn = engine
+					ran = job
+					return "", nil
+				},
+			},
+		})
+		require.NoError(t, err)
+		executors[engine] = noopExecutor
+	}
+
+	e, err := NewExecutor(cm, executors)
+	require.NoError(t, err)
+	_, err = e.RunShard(context.Background(), j, 0)
+	return ranOn, ran, err
+}
+
+func pythonJob(language executor.JobSpecLanguage) executor.Job {
+	return languageJob("python", "3.10", language)
+}
+
+func languageJob(name, version string, language executor.JobSpecLanguage) executor.Job {
+	language.Language = name
+	language.LanguageVersion = version
+	return executor.Job{
+		ID: "job-1",
+		Spec: executor.JobSpec{
+			Engine:   executor.EngineLanguage,
+			Language: language,
+			Contexts: []storage.StorageSpec{
+				{Engine: storage.StorageSourceIPFS, Cid: "context", Path: "/job"},
+			},
+		},
+	}
+}
+
+func TestDeterministicPython(t *testing.T) {
+	engine, _, err := runJob(t, pythonJob(executor.JobSpecLanguage{
+		Deterministic: true,
+		Command:       "print(1)",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, executor.EnginePythonWasm, engine)
+}
+
+func TestPythonCommand(t *testing.T) {
+	engine, j, err := runJob(t, pythonJob(executor.JobSpecLanguage{
+		Command: "print(1)",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, executor.EngineDocker, engine)
+	require.Equal(t, executor.EngineDocker, j.Spec.Engine)
+	require.Equal(t, "python:3.10", j.Spec.Docker.Image)
+	require.Equal(t, []string{"python", "-c", "print(1)"}, j.Spec.Docker.Entrypoint)
+	require.Equal(t, "/job", j.Spec.Docker.WorkingDir)
+}
+
+func TestPythonProgramWithRequirements(t *testing.T) {
+	_, j, err := runJob(t, pythonJob(executor.JobSpecLanguage{
+		ProgramPath:      "main.py",
+		RequirementsPath: "requirements.txt",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"/bin/sh", "-c",
+		`pip install --quiet --no-index --find-links /job --target /tmp/deps -r "$0" 1>&2 && exec "$@"`,
+		"/job/requirements.txt",
+		"python", "/job/main.py",
+	}, j.Spec.Docker.Entrypoint)
+	require.Equal(t, []string{"PYTHONPATH=/tmp/deps"}, j.Spec.Docker.Env)
+}
+
+func TestInvalidJobs(t *testing.T) {
+	noProgram := pythonJob(executor.JobSpecLanguage{})
+
+	badVersion := pythonJob(executor.JobSpecLanguage{Command: "print(1)"})
+	badVersion.Spec.Language.LanguageVersion = "3.10; rm -rf /"
+
+	noContext := pythonJob(executor.JobSpecLanguage{
+		Command:          "print(1)",
+		RequirementsPath: "requirements.txt",
+	})
+	noContext.Spec.Contexts = nil
+
+	deterministicNode := languageJob("node", "18", executor.JobSpecLanguage{
+		Command:       "console.log(1)",
+		Deterministic: true,
+	})
+	deterministicPython := pythonJob(executor.JobSpecLanguage{
+		Command:       "print(1)",
+		Deterministic: true,
+	})
+	deterministicPython.Spec.Language.LanguageVersion = "3.11"
+
+	cobol := languageJob("cobol", "85", executor.JobSpecLanguage{Command: "DISPLAY 1"})
+
+	bashRequirements := languageJob("bash", "5.2", executor.JobSpecLanguage{
+		Command:          "echo 1",
+		RequirementsPath: "requirements.txt",
+	})
+
+	for _, j := range []executor.Job{
+		noProgram, badVersion, noContext, deterministicNode, deterministicPython, cobol, bashRequirements,
+	} {
+		engine, _, err := runJob(t, j)
+		require.Error(t, err)
+		require.Empty(t, engine)
+	}
+}
+
+func TestOtherLanguages(t *testing.T) {
+	for _, test := range []struct {
+		language   string
+		version    string
+		image      string
+		entrypoint []string
+	}{
+		{"node", "18", "node:18", []string{"node", "-e", "1"}},
+		{"r", "4.2.2", "r-base:4.2.2", []string{"Rscript", "-e", "1"}},
+		{"bash", "5.2", "bash:5.2", []string{"bash", "-c", "1"}},
+	} {
+		engine, j, err := runJob(t, languageJob(test.language, test.version, executor.JobSpecLanguage{
+			Command: "1",
+		}))
+		require.NoError(t, err, test.language)
+		require.Equal(t, executor.EngineDocker, engine, test.language)
+		require.Equal(t, test.image, j.Spec.Docker.Image, test.language)
+		require.Equal(t, test.entrypoint, j.Spec.Docker.Entrypoint, test.language)
+	}
+}
+
+func TestNodeProgramWithRequirements(t *testing.T) {
+	_, j, err := runJob(t, languageJob("node", "18", executor.JobSpecLanguage{
+		ProgramPath:      "index.js",
+		RequirementsPath: "packages.txt",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"/bin/sh", "-c",
+		`xargs npm install --prefix /tmp/deps --offline --no-audit --no-fund < "$0" 1>&2 && exec "$@"`,
+		"/job/packages.txt",
+		"node", "/job/index.js",
+	}, j.Spec.Docker.Entrypoint)
+	require.Equal(t, []string{"NODE_PATH=/tmp/deps/node_modules"}, j.Spec.Docker.Env)
+}
+
+// the compute node checks these against its image policy before bidding
+func TestGetJobImage(t *testing.T) {
+	cm := system.NewCleanupManager()
+	t.Cleanup(cm.Cleanup)
+
+	pyodide, err := pythonwasm.NewExecutor(cm, map[executor.EngineType]executor.Executor{}, pythonwasm.ExecutorConfig{})
+	require.NoError(t, err)
+	e, err := NewExecutor(cm, map[executor.EngineType]executor.Executor{
+		executor.EnginePythonWasm: pyodide,
+	})
+	require.NoError(t, err)
+
+	image, err := e.GetJobImage(context.Background(), languageJob("r", "4.2", executor.JobSpecLanguage{Command: "1"}))
+	require.NoError(t, err)
+	require.Equal(t, "r-base:4.2", image)
+
+	image, err = e.GetJobImage(context.Background(), pythonJob(executor.JobSpecLanguage{
+		Deterministic: true,
+		Command:       "print(1)",
+	}))
+	require.NoError(t, err)
+	require.Contains(t, image, "pyodide")
+
+	_, err = e.GetJobImage(context.Background(), languageJob("cobol", "1", executor.JobSpecLanguage{Command: "1"}))
+	require.Error(t, err)
+}
+
+func TestGetJobRuntime(t *testing.T) {
+	cm := system.NewCleanupManager()
+	t.Cleanup(cm.Cleanup)
+
+	pyodide, err := pythonwasm.NewExecutor(cm, map[executor.EngineType]executor.Executor{}, pythonwasm.ExecutorConfig{})
+	require.NoError(t, err)
+	e, err := NewExecutor(cm, map[executor.EngineType]executor.Executor{
+		executor.EnginePythonWasm: pyodide,
+	})
+	require.NoError(t, err)
+
+	runtime, err := e.GetJobRuntime(context.Background(), pythonJob(executor.JobSpecLanguage{
+		Deterministic: true,
+		Command:       "print(1)",
+	}))
+	require.NoError(t, err)
+	require.Contains(t, runtime, "pyodide")
+
+	// there is only one way to run everything else
+	runtime, err = e.GetJobRuntime(context.Background(), pythonJob(executor.JobSpecLanguage{Command: "print(1)"}))
+	require.NoError(t, err)
+	require.Equal(t, "", runtime)
+}
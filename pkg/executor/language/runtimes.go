@@ -0,0 +1,61 @@
+package language
+
+import (
+	"regexp"
+)
+
+// where installers put dependencies that can't go in the (read only) context
+const depsPath = "/tmp/deps"
+
+// how to run a language on its official docker image
+type runtime struct {
+	// the image repository, tagged with the language version
+	image string
+	// the command line that runs an inline program
+	command func(command string) []string
+	// the command line that runs a program file
+	program func(programPath string) []string
+	// a shell snippet that installs the dependencies listed in the file at $0
+	// from the packages in the context - empty if the language has no
+	// dependencies to install
+	install string
+	// so the program can find what was installed
+	env []string
+}
+
+// official image tags look like 3, 3.10 or 3.10.8
+var versionRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,2}$`)
+
+var runtimes = map[string]runtime{
+	// requirements.txt naming wheels or sdists in the context - installed
+	// out of the way of site-packages because jobs might not be allowed to
+	// write there (see the docker executor's SecurityProfile)
+	"python": {
+		image:   "python",
+		command: func(command string) []string { return []string{"python", "-c", command} },
+		program: func(programPath string) []string { return []string{"python", programPath} },
+		install: `pip install --quiet --no-index --find-links ` + contextPath + ` --target ` + depsPath + ` -r "$0"`,
+		env:     []string{"PYTHONPATH=" + depsPath},
+	},
+	// one package tarball (from npm pack) in the context per line
+	"node": {
+		image:   "node",
+		command: func(command string) []string { return []string{"node", "-e", command} },
+		program: func(programPath string) []string { return []string{"node", programPath} },
+		install: `xargs npm install --prefix ` + depsPath + ` --offline --no-audit --no-fund < "$0"`,
+		env:     []string{"NODE_PATH=" + depsPath + "/node_modules"},
+	},
+	// one source package tarball in the context per line
+	"r": {
+		image:   "r-base",
+		command: func(command string) []string { return []string{"Rscript", "-e", command} },
+		program: func(programPath string) []string { return []string{"Rscript", programPath} },
+		install: `mkdir -p ` + depsPath + ` && xargs R CMD INSTALL --library=` + depsPath + ` < "$0"`,
+		env:     []string{"R_LIBS=" + depsPath},
+	},
+	"bash": {
+		image:   "bash",
+		command: func(command string) []string { return []string{"bash", "-c", command} },
+		program: func(programPath string) []string { return []string{"bash", programPath} },
+	},
+}
@@ -0,0 +1,98 @@
+package podman
+
+/*
+The podman executor runs docker jobs with podman. Podman serves a docker
+compatible API so this is the docker executor pointed at the podman socket -
+which, when podman is run by an unprivileged user, means compute nodes don't
+need root or a docker daemon.
+*/
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/executor/docker"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+)
+
+type ExecutorConfig struct {
+	// run docker jobs with podman instead of docker
+	Enabled bool
+	// the podman API socket - defaults to the rootless socket of the user
+	// running bacalhau (see `podman system service`)
+	Socket string
+}
+
+type Executor struct {
+	*docker.Executor
+}
+
+func NewExecutor(
+	cm *system.CleanupManager,
+	id string,
+	storageProviders map[storage.StorageSourceType]storage.StorageProvider,
+	dockerConfig docker.ExecutorConfig,
+	config ExecutorConfig,
+) (*Executor, error) {
+	socket := config.Socket
+	if socket == "" {
+		socket = DefaultSocket()
+	}
+	dockerConfig.Host = "unix://" + socket
+
+	dockerExecutor, err := docker.NewExecutor(cm, id, storageProviders, dockerConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{
+		Executor: dockerExecutor,
+	}, nil
+}
+
+// where podman puts its socket when it's run as a service by the current user
+func DefaultSocket() string {
+	if os.Getuid() == 0 {
+		return "/run/podman/podman.sock"
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	return filepath.Join(runtimeDir, "podman", "podman.sock")
+}
+
+// IsInstalled checks podman is running - it'll work as root but that
+// misses the point so we complain about it
+func (e *Executor) IsInstalled(ctx context.Context) (bool, error) {
+	info, err := e.Client.Info(ctx)
+	if err != nil {
+		log.Debug().Msgf("podman is not available: %s", err)
+		return false, nil
+	}
+	if !isRootless(info) {
+		log.Warn().Msgf("podman is running as root - run it as an unprivileged user to run jobs without root")
+	}
+	return true, nil
+}
+
+// podman says it's rootless in the security options docker has
+func isRootless(info dockertypes.Info) bool {
+	for _, option := range info.SecurityOptions {
+		if option == "name=rootless" {
+			return true
+		}
+	}
+	return false
+}
+
+// Compile-time interface check:
+var _ executor.Executor = (*Executor)(nil)
+var _ executor.ResultsKeeper = (*Executor)(nil)
+var _ executor.ImageKeeper = (*Executor)(nil)
@@ -0,0 +1,79 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/filecoin-project/bacalhau/pkg/executor/docker"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/stretchr/testify/require"
+)
+
+// serves enough of the podman API on a unix socket to tell if it's there
+func fakePodman(t *testing.T, info dockertypes.Info) string {
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/_ping":
+			w.Header().Set("API-Version", "1.41")
+			_, _ = w.Write([]byte("OK"))
+		case strings.HasSuffix(r.URL.Path, "/info"):
+			_ = json.NewEncoder(w).Encode(info)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return socket
+}
+
+func newExecutor(t *testing.T, socket string) *Executor {
+	system.InitConfigForTesting(t)
+	cm := system.NewCleanupManager()
+	t.Cleanup(cm.Cleanup)
+	e, err := NewExecutor(cm, "podmantest", map[storage.StorageSourceType]storage.StorageProvider{},
+		docker.ExecutorConfig{}, ExecutorConfig{Enabled: true, Socket: socket})
+	require.NoError(t, err)
+	return e
+}
+
+func TestIsInstalled(t *testing.T) {
+	socket := fakePodman(t, dockertypes.Info{
+		SecurityOptions: []string{"name=seccomp,profile=default", "name=rootless"},
+	})
+	e := newExecutor(t, socket)
+	installed, err := e.IsInstalled(context.Background())
+	require.NoError(t, err)
+	require.True(t, installed)
+}
+
+func TestNotInstalled(t *testing.T) {
+	e := newExecutor(t, filepath.Join(t.TempDir(), "nothing-here.sock"))
+	installed, err := e.IsInstalled(context.Background())
+	require.NoError(t, err)
+	require.False(t, installed)
+}
+
+func TestIsRootless(t *testing.T) {
+	require.True(t, isRootless(dockertypes.Info{SecurityOptions: []string{"name=rootless"}}))
+	require.False(t, isRootless(dockertypes.Info{SecurityOptions: []string{"name=seccomp,profile=default"}}))
+}
+
+func TestDefaultSocket(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1234")
+	socket := DefaultSocket()
+	require.True(t, socket == "/run/podman/podman.sock" || socket == "/run/user/1234/podman/podman.sock", socket)
+}
@@ -1,34 +1,83 @@
 package pythonwasm
 
 /*
-The python_wasm executor wraps the docker executor. The requestor will have
-automatically uploaded the execution context (python files, requirements.txt) to
-ipfs so that it can be mounted into the wasm runtime container.
+The python_wasm executor runs python with a CPython build for WASI on the
+wasm executor if the node has one, and otherwise wraps the docker executor
+to run pyodide. The requestor will have automatically uploaded the execution
+context (python files, requirements.txt) to ipfs so that it can be mounted
+into the wasm runtime.
 */
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/executor/wasm"
 	"github.com/filecoin-project/bacalhau/pkg/storage"
 	"github.com/filecoin-project/bacalhau/pkg/system"
 )
 
+// where things are from the point of view of the python runtime
+const (
+	// the requestor mounts the context here
+	contextPath = "/job"
+	// the runtime looks for the standard library under PYTHONHOME/lib
+	pythonHome        = "/usr/local"
+	sitePackagesPath  = "/site-packages"
+	pythonRuntimeName = "python.wasm"
+)
+
+// what we run python in when the node has no WASI runtime
+const pyodideImage = "quay.io/bacalhau/pyodide:e4b0eb7c1d81f320f5b43fc838b0f2a5b9003c9a"
+
+type ExecutorConfig struct {
+	// a CPython build for WASI (e.g. python.wasm) - if this isn't given we
+	// run pyodide with the docker executor
+	Runtime string
+	// the python standard library the runtime was built with - the
+	// directory is mounted at /usr/local/lib so it should have a
+	// pythonX.Y directory in it
+	Lib string
+}
+
 type Executor struct {
 	Jobs []*executor.Job
 
 	executors map[executor.EngineType]executor.Executor
+
+	config ExecutorConfig
+
+	// what we tell requesters we run python in
+	runtimeName string
 }
 
 func NewExecutor(
 	cm *system.CleanupManager,
 	executors map[executor.EngineType]executor.Executor,
+	config ExecutorConfig,
 ) (*Executor, error) {
+	// different builds of CPython can give different results so the
+	// runtime is named after the build
+	runtimeName := "pyodide@" + pyodideImage
+	if config.Runtime != "" {
+		module, err := os.ReadFile(config.Runtime)
+		if err != nil {
+			return nil, fmt.Errorf("python wasm runtime not found: %w", err)
+		}
+		runtimeName = fmt.Sprintf("cpython-wasi@sha256:%x", sha256.Sum256(module))
+	}
 	e := &Executor{
-		executors: executors,
+		executors:   executors,
+		config:      config,
+		runtimeName: runtimeName,
 	}
 	return e, nil
 }
@@ -46,9 +95,101 @@ func (e *Executor) GetVolumeSize(ctx context.Context, volumes storage.StorageSpe
 }
 
 func (e *Executor) RunShard(ctx context.Context, job executor.Job, shardIndex int) (string, error) {
+	if e.config.Runtime != "" {
+		return e.runShardInProcess(ctx, job, shardIndex)
+	}
+	return e.runShardInDocker(ctx, job, shardIndex)
+}
+
+// only pyodide runs in docker
+func (e *Executor) GetJobImage(ctx context.Context, job executor.Job) (string, error) {
+	if e.config.Runtime != "" {
+		return "", nil
+	}
+	return pyodideImage, nil
+}
+
+func (e *Executor) GetJobRuntime(ctx context.Context, job executor.Job) (string, error) {
+	return e.runtimeName, nil
+}
+
+func (e *Executor) runShardInProcess(ctx context.Context, job executor.Job, shardIndex int) (string, error) {
+	log.Debug().Msgf("running python in-process with %s", e.config.Runtime)
+	wasmExecutor, ok := e.executors[executor.EngineWasm].(*wasm.Executor)
+	if !ok {
+		return "", fmt.Errorf("python needs the wasm executor to run in-process")
+	}
+
+	job.Spec.Engine = executor.EngineWasm
+	job.Spec.Wasm = getWasmSpec(job.Spec.Language)
+
+	// wheels are unpacked on the host and mounted read only
+	var sitePackages string
+	defer func() {
+		if sitePackages != "" {
+			os.RemoveAll(sitePackages)
+		}
+	}()
+
+	return wasmExecutor.RunShardWithRuntime(ctx, job, shardIndex, func(ctx context.Context, inputs map[string]string) (wasm.Runtime, error) {
+		module, err := os.ReadFile(e.config.Runtime)
+		if err != nil {
+			return wasm.Runtime{}, err
+		}
+		runtime := wasm.Runtime{
+			Module: module,
+			Mounts: map[string]string{},
+		}
+		if e.config.Lib != "" {
+			runtime.Mounts[path.Join(pythonHome, "lib")] = e.config.Lib
+		}
+
+		if job.Spec.Language.RequirementsPath != "" {
+			hostContext, ok := inputs[contextPath]
+			if !ok {
+				return wasm.Runtime{}, fmt.Errorf("requirements given but the job has no context")
+			}
+			sitePackages, err = ioutil.TempDir("", "bacalhau-python-wasm-site-packages")
+			if err != nil {
+				return wasm.Runtime{}, err
+			}
+			requirements := filepath.Join(hostContext, filepath.Clean("/"+job.Spec.Language.RequirementsPath))
+			err = installRequirements(requirements, hostContext, sitePackages)
+			if err != nil {
+				return wasm.Runtime{}, err
+			}
+			runtime.Mounts[sitePackagesPath] = sitePackages
+		}
+		return runtime, nil
+	})
+}
+
+// how to run the language job with the python runtime - everything that
+// could make python behave differently from one run to the next is pinned
+func getWasmSpec(language executor.JobSpecLanguage) executor.JobSpecWasm {
+	spec := executor.JobSpecWasm{
+		EntryModule: pythonRuntimeName,
+		Env: []string{
+			"PYTHONHOME=" + pythonHome,
+			"PYTHONHASHSEED=0",
+			"PYTHONDONTWRITEBYTECODE=1",
+		},
+	}
+	if language.RequirementsPath != "" {
+		spec.Env = append(spec.Env, "PYTHONPATH="+sitePackagesPath)
+	}
+	if language.Command != "" {
+		spec.Parameters = []string{"-c", language.Command}
+	} else if language.ProgramPath != "" {
+		spec.Parameters = []string{path.Join(contextPath, language.ProgramPath)}
+	}
+	return spec
+}
+
+func (e *Executor) runShardInDocker(ctx context.Context, job executor.Job, shardIndex int) (string, error) {
 	log.Debug().Msgf("in python_wasm executor!")
 	// translate language jobspec into a docker run command
-	job.Spec.Docker.Image = "quay.io/bacalhau/pyodide:e4b0eb7c1d81f320f5b43fc838b0f2a5b9003c9a"
+	job.Spec.Docker.Image = pyodideImage
 	if job.Spec.Language.Command != "" {
 		// pass command through to node wasm wrapper
 		job.Spec.Docker.Entrypoint = []string{"node", "n.js", "-c", job.Spec.Language.Command}
@@ -78,3 +219,5 @@ func (e *Executor) RunShard(ctx context.Context, job executor.Job, shardIndex in
 
 // Compile-time check that Executor implements the Executor interface.
 var _ executor.Executor = (*Executor)(nil)
+var _ executor.ImageResolver = (*Executor)(nil)
+var _ executor.RuntimeNamer = (*Executor)(nil)
@@ -0,0 +1,117 @@
+package pythonwasm
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWasmSpec(t *testing.T) {
+	spec := getWasmSpec(executor.JobSpecLanguage{
+		Command: "print(1)",
+	})
+	require.Equal(t, "python.wasm", spec.EntryModule)
+	require.Equal(t, []string{"-c", "print(1)"}, spec.Parameters)
+	require.Contains(t, spec.Env, "PYTHONHASHSEED=0")
+	require.NotContains(t, spec.Env, "PYTHONPATH=/site-packages")
+
+	spec = getWasmSpec(executor.JobSpecLanguage{
+		ProgramPath:      "main.py",
+		RequirementsPath: "requirements.txt",
+	})
+	require.Equal(t, []string{"/job/main.py"}, spec.Parameters)
+	require.Contains(t, spec.Env, "PYTHONPATH=/site-packages")
+}
+
+func TestGetJobRuntime(t *testing.T) {
+	cm := system.NewCleanupManager()
+	t.Cleanup(cm.Cleanup)
+
+	getRuntime := func(config ExecutorConfig) string {
+		e, err := NewExecutor(cm, map[executor.EngineType]executor.Executor{}, config)
+		require.NoError(t, err)
+		runtime, err := e.GetJobRuntime(context.Background(), executor.Job{})
+		require.NoError(t, err)
+		return runtime
+	}
+
+	require.Equal(t, "pyodide@"+pyodideImage, getRuntime(ExecutorConfig{}))
+
+	// different builds of CPython are different runtimes
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.wasm"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.wasm"), []byte("b"), 0644))
+	runtimeA := getRuntime(ExecutorConfig{Runtime: filepath.Join(dir, "a.wasm")})
+	runtimeB := getRuntime(ExecutorConfig{Runtime: filepath.Join(dir, "b.wasm")})
+	require.True(t, strings.HasPrefix(runtimeA, "cpython-wasi@sha256:"), runtimeA)
+	require.NotEqual(t, runtimeA, runtimeB)
+}
+
+// writes a wheel with one file in it
+func writeWheel(t *testing.T, path, name, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	fw, err := w.Create(name)
+	require.NoError(t, err)
+	_, err = fw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func TestInstallRequirements(t *testing.T) {
+	contextDir := t.TempDir()
+	writeWheel(t, filepath.Join(contextDir, "wheels", "six-1.16.0-py2.py3-none-any.whl"), "six.py", "six")
+	writeWheel(t, filepath.Join(contextDir, "wheels", "my_lib-0.1-py3-none-any.whl"), "my_lib/__init__.py", "mine")
+	writeWheel(t, filepath.Join(contextDir, "wheels", "my_lib-0.2-py3-none-any.whl"), "my_lib/__init__.py", "mine again")
+	writeWheel(t, filepath.Join(contextDir, "numpy-1.23.4-cp310-cp310-manylinux_2_17_x86_64.whl"), "numpy/__init__.py", "")
+	writeWheel(t, filepath.Join(contextDir, "evil-1.0-py3-none-any.whl"), "../../escaped.py", "evil")
+
+	install := func(requirements string) (string, error) {
+		requirementsPath := filepath.Join(contextDir, "requirements.txt")
+		require.NoError(t, os.WriteFile(requirementsPath, []byte(requirements), 0644))
+		sitePackages := t.TempDir()
+		return sitePackages, installRequirements(requirementsPath, contextDir, sitePackages)
+	}
+
+	sitePackages, err := install("# pinned\nsix==1.16.0\nMy-Lib == 0.2\n")
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(sitePackages, "six.py"))
+	require.NoError(t, err)
+	require.Equal(t, "six", string(content))
+	content, err = os.ReadFile(filepath.Join(sitePackages, "my_lib", "__init__.py"))
+	require.NoError(t, err)
+	require.Equal(t, "mine again", string(content))
+
+	sitePackages, err = install("wheels/my_lib-0.1-py3-none-any.whl\n")
+	require.NoError(t, err)
+	content, err = os.ReadFile(filepath.Join(sitePackages, "my_lib", "__init__.py"))
+	require.NoError(t, err)
+	require.Equal(t, "mine", string(content))
+
+	// files can't be written outside of site-packages
+	sitePackages, err = install("evil\n")
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(sitePackages, "escaped.py"))
+	require.NoError(t, err)
+
+	for _, requirements := range []string{
+		"my_lib",
+		"numpy",
+		"requests",
+		"six>=1.0",
+		"-r other.txt",
+	} {
+		_, err = install(requirements)
+		require.Error(t, err, requirements)
+	}
+}
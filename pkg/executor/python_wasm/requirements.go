@@ -0,0 +1,180 @@
+package pythonwasm
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// there is no network (or pip) inside the wasm runtime so requirements
+// are installed from wheels the client puts in the context - each line of
+// the requirements file is either the path to a wheel in the context or a
+// name (optionally pinned with ==) that matches one. only pure python wheels
+// work and dependencies are not resolved so every one has to be listed.
+func installRequirements(requirementsPath, contextDir, sitePackages string) error {
+	requirementsFile, err := os.Open(requirementsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open requirements: %w", err)
+	}
+	defer requirementsFile.Close()
+
+	var wheels []wheel
+	scanner := bufio.NewScanner(requirementsFile)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			return fmt.Errorf("pip options are not supported in requirements: %s", line)
+		}
+
+		var w wheel
+		if strings.HasSuffix(line, ".whl") {
+			w, err = parseWheel(filepath.Join(contextDir, filepath.Clean("/"+line)))
+		} else {
+			if wheels == nil {
+				wheels, err = findWheels(contextDir)
+				if err != nil {
+					return err
+				}
+			}
+			w, err = matchRequirement(line, wheels)
+		}
+		if err != nil {
+			return err
+		}
+
+		if w.abi != "none" || w.platform != "any" {
+			return fmt.Errorf("only pure python wheels can be installed: %s", filepath.Base(w.path))
+		}
+		err = unpackWheel(w.path, sitePackages)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type wheel struct {
+	path     string
+	name     string
+	version  string
+	abi      string
+	platform string
+}
+
+// {distribution}-{version}(-{build})?-{python}-{abi}-{platform}.whl
+func parseWheel(path string) (wheel, error) {
+	parts := strings.Split(strings.TrimSuffix(filepath.Base(path), ".whl"), "-")
+	if len(parts) != 5 && len(parts) != 6 {
+		return wheel{}, fmt.Errorf("not a wheel: %s", filepath.Base(path))
+	}
+	return wheel{
+		path:     path,
+		name:     normalizeName(parts[0]),
+		version:  parts[1],
+		abi:      parts[len(parts)-2],
+		platform: parts[len(parts)-1],
+	}, nil
+}
+
+func findWheels(contextDir string) ([]wheel, error) {
+	wheels := []wheel{}
+	err := filepath.Walk(contextDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".whl") {
+			return nil
+		}
+		w, parseErr := parseWheel(path)
+		if parseErr != nil {
+			// not ours to worry about unless it's asked for
+			return nil
+		}
+		wheels = append(wheels, w)
+		return nil
+	})
+	return wheels, err
+}
+
+var requirementRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:==\s*([A-Za-z0-9.+!_-]+))?$`)
+var nameSeparatorRegex = regexp.MustCompile(`[-_.]+`)
+
+func matchRequirement(requirement string, wheels []wheel) (wheel, error) {
+	match := requirementRegex.FindStringSubmatch(requirement)
+	if match == nil {
+		return wheel{}, fmt.Errorf("only name or name==version requirements are supported: %s", requirement)
+	}
+	name, version := normalizeName(match[1]), match[2]
+
+	matches := []wheel{}
+	for _, w := range wheels {
+		if w.name == name && (version == "" || w.version == version) {
+			matches = append(matches, w)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return wheel{}, fmt.Errorf("no wheel for %s in the context", requirement)
+	case 1:
+		return matches[0], nil
+	default:
+		return wheel{}, fmt.Errorf("more than one wheel for %s in the context - pin the version or give the path", requirement)
+	}
+}
+
+// wheel filenames use _ where the project name might have - or .
+func normalizeName(name string) string {
+	return strings.ToLower(nameSeparatorRegex.ReplaceAllString(name, "_"))
+}
+
+func unpackWheel(path, sitePackages string) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open wheel %s: %w", filepath.Base(path), err)
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		dst := filepath.Join(sitePackages, filepath.Clean("/"+file.Name))
+		if file.FileInfo().IsDir() {
+			err = os.MkdirAll(dst, os.ModePerm)
+		} else {
+			err = unpackFile(file, dst)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unpack wheel %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+func unpackFile(file *zip.File, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, src) //nolint:gosec // wheels come from the job's own context
+	return err
+}
@@ -31,6 +31,39 @@ type Executor interface {
 	RunShard(ctx context.Context, job Job, shardIndex int) (string, error)
 }
 
+// ResultsKeeper is implemented by executors that leave shard results on
+// the local filesystem so the compute node can clean up after jobs that
+// have been forgotten about.
+type ResultsKeeper interface {
+	// the ids of the jobs we have results for on disk
+	GetResultsJobIDs(ctx context.Context) ([]string, error)
+	// remove the results of every shard of the job
+	CleanupJobResults(ctx context.Context, jobID string) error
+}
+
+// ImageKeeper is implemented by executors that keep the images jobs run in
+// on the local machine so the compute node can tell requesters when it can
+// start a job without pulling anything first.
+type ImageKeeper interface {
+	HasImageLocally(ctx context.Context, image string) (bool, error)
+}
+
+// ImageResolver is implemented by executors that run jobs in a docker image
+// of their own choosing (e.g. a language's official image) so the compute
+// node can check the image against its policy before it bids.
+type ImageResolver interface {
+	// the image the job will run in - empty if it won't run in docker
+	GetJobImage(ctx context.Context, job Job) (string, error)
+}
+
+// RuntimeNamer is implemented by executors that can run the same job in
+// more than one runtime (e.g. CPython for WASI or pyodide) so requesters
+// can make sure every execution of a shard uses the same one.
+type RuntimeNamer interface {
+	// the runtime the job will run in - empty if there is only one
+	GetJobRuntime(ctx context.Context, job Job) (string, error)
+}
+
 // Job contains data about a job in the bacalhau network.
 type Job struct {
 	// The unique global ID of this job in the bacalhau network.
@@ -111,6 +144,14 @@ type JobShardState struct {
 	// the ID of the results for this shard
 	// this will be resolved by the verifier somehow
 	ResultsID string `json:"results_id"`
+	// a hash of the results that ignores files that differ between
+	// runs - only set by verifiers that compute one
+	ResultsHash string `json:"results_hash,omitempty"`
+	// once the shard is finalized - did the requester node
+	// accept the results after verifying them
+	ResultsAccepted bool `json:"results_accepted"`
+	// when this node heard the shard reached a terminal state
+	FinishedAt time.Time `json:"finished_at"`
 }
 
 // The deal the client has made with the bacalhau network.
@@ -140,10 +181,14 @@ type JobSpec struct {
 	// executor specific data
 	Docker   JobSpecDocker   `json:"job_spec_docker,omitempty" yaml:"job_spec_docker,omitempty"`
 	Language JobSpecLanguage `json:"job_spec_language,omitempty" yaml:"job_spec_language,omitempty"`
+	Wasm     JobSpecWasm     `json:"job_spec_wasm,omitempty" yaml:"job_spec_wasm,omitempty"`
 
 	// the compute (cpy, ram) resources this job requires
 	Resources capacitymanager.ResourceUsageConfig `json:"resources" yaml:"resources"`
 
+	// the network the job can use - none by default
+	Network JobSpecNetwork `json:"network,omitempty" yaml:"network,omitempty"`
+
 	// the data volumes we will read in the job
 	// for example "read this ipfs cid"
 	Inputs []storage.StorageSpec `json:"inputs" yaml:"inputs"`
@@ -164,6 +209,10 @@ type JobSpec struct {
 	// describes how the job might be split up into parallel shards
 	Sharding JobShardingConfig `json:"sharding" yaml:"sharding"`
 
+	// only compute nodes with labels matching this selector will bid on the job
+	// e.g. "region=us-east-1,gpu in (a100, h100),!spot"
+	NodeSelector string `json:"node_selector,omitempty" yaml:"node_selector,omitempty"`
+
 	// Do not track specified by the client
 	DoNotTrack bool `json:"donottrack" yaml:"donottrack"`
 }
@@ -174,12 +223,25 @@ type JobSpecDocker struct {
 	Image string `json:"image" yaml:"image"`
 	// optionally override the default entrypoint
 	Entrypoint []string `json:"entrypoint" yaml:"entrypoint"`
+	// optionally override the default cmd - these are the arguments to the
+	// entrypoint (the image's own if Entrypoint is empty)
+	Cmd []string `json:"cmd,omitempty" yaml:"cmd,omitempty"`
+	// fill in the values in job.ShardTemplateData (e.g. {{.ShardIndex}})
+	// in Entrypoint and Cmd when each shard runs
+	TemplateArgs bool `json:"templateargs,omitempty" yaml:"templateargs,omitempty"`
 	// a map of env to run the container with
 	Env []string `json:"env" yaml:"env"`
 	// working directory inside the container
 	WorkingDir string `json:"workdir" yaml:"workdir"`
 }
 
+// what a job can reach over the network
+type JobSpecNetwork struct {
+	Type Network `json:"type" yaml:"type"`
+	// the domains http jobs can reach (e.g. example.com or *.example.com)
+	Domains []string `json:"domains,omitempty" yaml:"domains,omitempty"`
+}
+
 // for language style executors (can target docker or wasm)
 type JobSpecLanguage struct {
 	Language        string `json:"language" yaml:"language"`                 // e.g. python
@@ -196,6 +258,22 @@ type JobSpecLanguage struct {
 	RequirementsPath string `json:"requirements_path" yaml:"requirements_path"`
 }
 
+// for executors that run wasm modules directly
+type JobSpecWasm struct {
+	// the wasm module to run - a path inside one of the job's input
+	// or context volumes (e.g. /job/main.wasm)
+	EntryModule string `json:"entry_module" yaml:"entry_module"`
+	// the exported function to call - defaults to the WASI _start function
+	EntryPoint string `json:"entry_point" yaml:"entry_point"`
+	// the arguments passed to the module (argv[0] is the module name)
+	Parameters []string `json:"parameters" yaml:"parameters"`
+	// a list of KEY=value environment variables
+	Env []string `json:"env" yaml:"env"`
+	// how many function calls and loop iterations the module can run before
+	// it is stopped - zero means there is no limit beyond the executor's timeout
+	Fuel uint64 `json:"fuel" yaml:"fuel"`
+}
+
 // gives us a way to keep local data against a job
 // so our compute node and requester node control loops
 // can keep state against a job without broadcasting it
@@ -205,6 +283,36 @@ type JobLocalEvent struct {
 	JobID        string            `json:"job_id"`
 	ShardIndex   int               `json:"shard_index"`
 	TargetNodeID string            `json:"target_node_id"`
+	// the terms we will bid with - only defined in "selected" events
+	// and the accepted bid in "bid accepted" events
+	JobBid JobBid `json:"job_bid"`
+	// the root of the job's combined results - only defined in
+	// "results combined" events
+	ResultsID string `json:"results_id,omitempty"`
+}
+
+// the terms a compute node offers when it bids on a job shard
+// these come from the job selection probe if one is configured
+type JobBid struct {
+	// what the compute node is asking to run the shard - the requester
+	// prefers lower prices and zero means the node did not name a price
+	// (those bids come after every priced bid)
+	Price float64 `json:"price,omitempty"`
+	// how many seconds until the compute node expects to start the shard
+	ETASeconds int64 `json:"eta_seconds,omitempty"`
+	// how many of the job's inputs the compute node already has locally
+	LocalInputs int `json:"local_inputs,omitempty"`
+	// the compute node already has the job's docker image
+	HasImage bool `json:"has_image,omitempty"`
+	// the fraction (0 - 1) of the compute node's capacity that other
+	// jobs were using when it bid
+	Load float64 `json:"load,omitempty"`
+	// the runtime the compute node will run the shard in for engines
+	// that have more than one - executions of a shard that ran in
+	// different runtimes can't be compared
+	Runtime string `json:"runtime,omitempty"`
+	// a human readable explanation of the bid
+	Reason string `json:"reason,omitempty"`
 }
 
 // we emit these to other nodes so they update their
@@ -226,10 +334,14 @@ type JobEvent struct {
 	// this is only defined in "create" events
 	JobExecutionPlan JobExecutionPlan `json:"job_execution_plan"`
 	// this is only defined in "update_deal" events
-	JobDeal   JobDeal   `json:"job_deal"`
-	Status    string    `json:"status"`
-	ResultsID string    `json:"results_id"`
-	EventTime time.Time `json:"event_time"`
+	JobDeal JobDeal `json:"job_deal"`
+	// this is only defined in "bid" events
+	JobBid    JobBid `json:"job_bid"`
+	Status    string `json:"status"`
+	ResultsID string `json:"results_id"`
+	// this is only defined in "completed" events
+	ResultsHash string    `json:"results_hash,omitempty"`
+	EventTime   time.Time `json:"event_time"`
 }
 
 type JobCreatePayload struct {
@@ -5,7 +5,9 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/executor/docker"
 	"github.com/filecoin-project/bacalhau/pkg/executor/language"
 	noop_executor "github.com/filecoin-project/bacalhau/pkg/executor/noop"
+	"github.com/filecoin-project/bacalhau/pkg/executor/podman"
 	pythonwasm "github.com/filecoin-project/bacalhau/pkg/executor/python_wasm"
+	"github.com/filecoin-project/bacalhau/pkg/executor/wasm"
 	"github.com/filecoin-project/bacalhau/pkg/storage"
 	apicopy "github.com/filecoin-project/bacalhau/pkg/storage/ipfs_apicopy"
 	noop_storage "github.com/filecoin-project/bacalhau/pkg/storage/noop"
@@ -55,20 +57,34 @@ func NewStandardExecutors(
 	cm *system.CleanupManager,
 	ipfsMultiAddress,
 	dockerID string,
+	dockerConfig docker.ExecutorConfig,
+	podmanConfig podman.ExecutorConfig,
+	pythonWasmConfig pythonwasm.ExecutorConfig,
 ) (map[executor.EngineType]executor.Executor, error) {
 	storageProviders, err := NewStandardStorageProviders(cm, ipfsMultiAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	dockerExecutor, err := docker.NewExecutor(cm, dockerID, storageProviders)
+	// docker jobs are run by podman if the node wants them to be
+	var dockerExecutor executor.Executor
+	if podmanConfig.Enabled {
+		dockerExecutor, err = podman.NewExecutor(cm, dockerID, storageProviders, dockerConfig, podmanConfig)
+	} else {
+		dockerExecutor, err = docker.NewExecutor(cm, dockerID, storageProviders, dockerConfig)
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	wasmExecutor, err := wasm.NewExecutor(cm, storageProviders, wasm.ExecutorConfig{})
 	if err != nil {
 		return nil, err
 	}
 
 	executors := map[executor.EngineType]executor.Executor{
 		executor.EngineDocker: dockerExecutor,
+		executor.EngineWasm:   wasmExecutor,
 	}
 
 	// language executors wrap other executors, so pass them a reference to all
@@ -78,7 +94,7 @@ func NewStandardExecutors(
 	if err != nil {
 		return nil, err
 	}
-	exPythonWasm, err := pythonwasm.NewExecutor(cm, executors)
+	exPythonWasm, err := pythonwasm.NewExecutor(cm, executors, pythonWasmConfig)
 	executors[executor.EnginePythonWasm] = exPythonWasm
 	if err != nil {
 		return nil, err
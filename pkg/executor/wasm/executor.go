@@ -0,0 +1,485 @@
+package wasm
+
+/*
+The wasm executor runs WASI modules in-process with wazero so compute nodes
+don't need docker to run them. The module gets no clock, randomness or
+network from the host - wazero's defaults are fake clocks and a fixed
+random source - so the same module with the same inputs does the same thing
+on every node.
+*/
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/capacitymanager"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	jobutils "github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/storage/util"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/rs/zerolog/log"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DefaultEntryPoint = "_start"
+const DefaultTimeout = 10 * time.Minute
+
+// wasm memory is allocated in 64KiB pages
+const wasmPageSize = 65536
+
+// the exit codes we record when we stop a module ourselves - the same
+// ones a shell would use for SIGKILL and SIGXCPU
+const ExitCodeTimeout = 137
+const ExitCodeOutOfFuel = 152
+
+type ExecutorConfig struct {
+	// the longest a module can run for - this is a backstop for jobs
+	// that don't set any fuel and for time spent in host functions
+	Timeout time.Duration
+}
+
+// lets other executors (e.g. language runtimes) run their own module
+// against a job's volumes
+type Runtime struct {
+	// the module to run instead of the job's entry module
+	Module []byte
+	// host directories to mount read only - guest path -> host path
+	Mounts map[string]string
+}
+
+// called once the job's input volumes are ready with where they are on
+// the host - guest path -> host path
+type RuntimeLoader func(ctx context.Context, inputs map[string]string) (Runtime, error)
+
+type Executor struct {
+	// where do we copy the results from jobs temporarily?
+	ResultsDir string
+
+	// the storage providers we can implement for a job
+	StorageProviders map[storage.StorageSourceType]storage.StorageProvider
+
+	Config ExecutorConfig
+
+	// modules like language runtimes are big so we only compile them once
+	cache wazero.CompilationCache
+}
+
+func NewExecutor(
+	cm *system.CleanupManager,
+	storageProviders map[storage.StorageSourceType]storage.StorageProvider,
+	config ExecutorConfig,
+) (*Executor, error) {
+	dir, err := ioutil.TempDir("", "bacalhau-wasm-executor")
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	e := &Executor{
+		ResultsDir:       dir,
+		StorageProviders: storageProviders,
+		Config:           config,
+		cache:            wazero.NewCompilationCache(),
+	}
+
+	cm.RegisterCallback(func() error {
+		err := e.cache.Close(context.Background())
+		if err != nil {
+			return err
+		}
+		return os.RemoveAll(dir)
+	})
+
+	return e, nil
+}
+
+func (e *Executor) getStorageProvider(ctx context.Context, engine storage.StorageSourceType) (storage.StorageProvider, error) {
+	return util.GetStorageProvider(ctx, engine, e.StorageProviders)
+}
+
+// the runtime is compiled into bacalhau so it's always there
+func (e *Executor) IsInstalled(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (e *Executor) HasStorageLocally(ctx context.Context, volume storage.StorageSpec) (bool, error) {
+	ctx, span := newSpan(ctx, "HasStorageLocally")
+	defer span.End()
+
+	s, err := e.getStorageProvider(ctx, volume.Engine)
+	if err != nil {
+		return false, err
+	}
+
+	return s.HasStorageLocally(ctx, volume)
+}
+
+func (e *Executor) GetVolumeSize(ctx context.Context, volume storage.StorageSpec) (uint64, error) {
+	storageProvider, err := e.getStorageProvider(ctx, volume.Engine)
+	if err != nil {
+		return 0, err
+	}
+	return storageProvider.GetVolumeSize(ctx, volume)
+}
+
+func (e *Executor) RunShard(ctx context.Context, j executor.Job, shardIndex int) (string, error) {
+	if j.Spec.Wasm.EntryModule == "" {
+		return "", fmt.Errorf("no entry module given for wasm job %s", j.ID)
+	}
+	return e.RunShardWithRuntime(ctx, j, shardIndex, func(ctx context.Context, inputs map[string]string) (Runtime, error) {
+		module, err := readModule(j.Spec.Wasm.EntryModule, inputs)
+		return Runtime{Module: module}, err
+	})
+}
+
+// run the shard like RunShard but with the module the loader gives us
+//
+//nolint:funlen // reads best top to bottom
+func (e *Executor) RunShardWithRuntime(
+	ctx context.Context,
+	j executor.Job,
+	shardIndex int,
+	loadRuntime RuntimeLoader,
+) (string, error) {
+	ctx, span := newSpan(ctx, "RunShard")
+	defer span.End()
+
+	log.Debug().Msgf("Running job %s shard %d on wasm executor", j.ID, shardIndex)
+
+	// WASI modules get no sockets
+	if j.Spec.Network.Type != executor.NetworkNone {
+		return "", fmt.Errorf("the wasm executor can't give jobs %s networking", j.Spec.Network.Type)
+	}
+
+	jobResultsDir, err := e.ensureShardResultsDir(j, shardIndex)
+	if err != nil {
+		return "", err
+	}
+
+	shard, err := jobutils.GetShard(ctx, j.Spec, e.StorageProviders, shardIndex)
+	if err != nil {
+		return "", err
+	}
+
+	fsConfig := wazero.NewFSConfig()
+	// guest path -> host path for every input so we can find the module
+	inputs := map[string]string{}
+
+	// files can't be mounted on their own so they go in a directory of
+	// their own that is mounted where the file's directory should be
+	stagingDir, err := ioutil.TempDir("", "bacalhau-wasm-inputs")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	inputVolumes := append(append([]storage.StorageSpec{}, j.Spec.Contexts...), shard...)
+	for i, inputStorage := range inputVolumes {
+		var storageProvider storage.StorageProvider
+		storageProvider, err = e.getStorageProvider(ctx, inputStorage.Engine)
+		if err != nil {
+			return "", err
+		}
+
+		var volume storage.StorageVolume
+		volume, err = storageProvider.PrepareStorage(ctx, inputStorage)
+		if err != nil {
+			return "", err
+		}
+		if volume.Type != storage.StorageVolumeConnectorBind {
+			return "", fmt.Errorf("unknown storage volume type: %s", volume.Type)
+		}
+
+		var stat os.FileInfo
+		stat, err = os.Stat(volume.Source)
+		if err != nil {
+			return "", err
+		}
+
+		log.Trace().Msgf("Input Volume: %+v %+v", inputStorage, volume)
+		inputs[volume.Target] = volume.Source
+		if stat.IsDir() {
+			fsConfig = fsConfig.WithReadOnlyDirMount(volume.Source, volume.Target)
+			continue
+		}
+
+		fileDir := filepath.Join(stagingDir, fmt.Sprintf("%d", i))
+		err = os.Mkdir(fileDir, util.OS_USER_RWX)
+		if err != nil {
+			return "", err
+		}
+		err = linkOrCopyFile(volume.Source, filepath.Join(fileDir, filepath.Base(volume.Target)))
+		if err != nil {
+			return "", err
+		}
+		fsConfig = fsConfig.WithReadOnlyDirMount(fileDir, filepath.Dir(volume.Target))
+	}
+
+	// for this phase of the outputs we ignore the engine because it's just about collecting the
+	// data from the job and keeping it locally
+	for _, output := range j.Spec.Outputs {
+		if output.Name == "" {
+			return "", fmt.Errorf("output volume has no name: %+v", output)
+		}
+
+		if output.Path == "" {
+			return "", fmt.Errorf("output volume has no path: %+v", output)
+		}
+
+		srcd := filepath.Join(jobResultsDir, output.Name)
+		err = os.Mkdir(srcd, util.OS_ALL_R|util.OS_ALL_X|util.OS_USER_W)
+		if err != nil {
+			return "", err
+		}
+
+		log.Trace().Msgf("Output Volume: %+v", output)
+		fsConfig = fsConfig.WithDirMount(srcd, output.Path)
+	}
+
+	runtime, err := loadRuntime(ctx, inputs)
+	if err != nil {
+		return "", err
+	}
+
+	// sorted so the preopened dirs are in the same order every time
+	runtimeMounts := []string{}
+	for guestPath := range runtime.Mounts {
+		runtimeMounts = append(runtimeMounts, guestPath)
+	}
+	sort.Strings(runtimeMounts)
+	for _, guestPath := range runtimeMounts {
+		fsConfig = fsConfig.WithReadOnlyDirMount(runtime.Mounts[guestPath], guestPath)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	exitCode, runErr := e.runModule(ctx, j, runtime.Module, fsConfig, stdout, stderr)
+	if runErr != nil {
+		log.Info().Msgf("wasm module error %s", runErr)
+		// make sure the user gets to see why the module was stopped
+		fmt.Fprintln(stderr, runErr.Error())
+	}
+
+	for filename, content := range map[string][]byte{
+		"exitCode": []byte(fmt.Sprintf("%d", exitCode)),
+		"stdout":   stdout.Bytes(),
+		"stderr":   stderr.Bytes(),
+	} {
+		err = os.WriteFile(
+			filepath.Join(jobResultsDir, filename),
+			content,
+			util.OS_ALL_R|util.OS_USER_RW,
+		)
+		if err != nil {
+			msg := fmt.Sprintf("could not write results to %s: %s", filename, err)
+			log.Error().Msg(msg)
+			return "", errors.New(msg)
+		}
+	}
+
+	return jobResultsDir, runErr
+}
+
+// runs the module to completion and returns its exit code - the error is
+// set whenever the exit code is not zero
+func (e *Executor) runModule(
+	ctx context.Context,
+	j executor.Job,
+	moduleBytes []byte,
+	fsConfig wazero.FSConfig,
+	stdout, stderr io.Writer,
+) (uint32, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.Config.Timeout)
+	defer cancel()
+
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithCompilationCache(e.cache)
+	memoryLimit := capacitymanager.ParseResourceUsageConfig(j.Spec.Resources).Memory
+	if memoryLimit > 0 {
+		runtimeConfig = runtimeConfig.WithMemoryLimitPages(memoryLimitPages(memoryLimit))
+	}
+
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	defer runtime.Close(ctx)
+
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	if j.Spec.Wasm.Fuel > 0 {
+		var err error
+		moduleBytes, err = meterModule(moduleBytes, j.Spec.Wasm.Fuel)
+		if err != nil {
+			return 1, fmt.Errorf("failed to add fuel to wasm module: %w", err)
+		}
+	}
+
+	compiled, err := runtime.CompileModule(ctx, moduleBytes)
+	if err != nil {
+		return 1, fmt.Errorf("failed to compile wasm module: %w", err)
+	}
+
+	moduleConfig := wazero.NewModuleConfig().
+		WithName(filepath.Base(j.Spec.Wasm.EntryModule)).
+		WithArgs(append([]string{filepath.Base(j.Spec.Wasm.EntryModule)}, j.Spec.Wasm.Parameters...)...).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithFSConfig(fsConfig).
+		// we call the entry point ourselves below
+		WithStartFunctions()
+	for _, env := range j.Spec.Wasm.Env {
+		key, value, _ := strings.Cut(env, "=")
+		moduleConfig = moduleConfig.WithEnv(key, value)
+	}
+
+	entryPoint := j.Spec.Wasm.EntryPoint
+	if entryPoint == "" {
+		entryPoint = DefaultEntryPoint
+	}
+
+	module, err := runtime.InstantiateModule(ctx, compiled, moduleConfig)
+	if err != nil {
+		return exitCodeFromError(err, false)
+	}
+	defer module.Close(ctx)
+
+	function := module.ExportedFunction(entryPoint)
+	if function == nil {
+		return 1, fmt.Errorf("wasm module does not export a function called %s", entryPoint)
+	}
+	_, err = function.Call(ctx)
+
+	// the module sets its fuel to -1 when it runs out
+	outOfFuel := false
+	if j.Spec.Wasm.Fuel > 0 {
+		if fuel := module.ExportedGlobal(fuelExportName); fuel != nil {
+			outOfFuel = fuel.Get() == math.MaxUint64
+		}
+	}
+	return exitCodeFromError(err, outOfFuel)
+}
+
+func exitCodeFromError(err error, outOfFuel bool) (uint32, error) {
+	if outOfFuel {
+		return ExitCodeOutOfFuel, errOutOfFuel
+	}
+	if err == nil {
+		return 0, nil
+	}
+	exitErr := &sys.ExitError{}
+	if !errors.As(err, &exitErr) {
+		// a trap or a module we couldn't start
+		return 1, err
+	}
+	switch exitErr.ExitCode() {
+	case 0:
+		return 0, nil
+	case sys.ExitCodeDeadlineExceeded, sys.ExitCodeContextCanceled:
+		return ExitCodeTimeout, fmt.Errorf("wasm module did not finish in time: %w", err)
+	default:
+		return exitErr.ExitCode(), fmt.Errorf("exit code was not zero: %d", exitErr.ExitCode())
+	}
+}
+
+func memoryLimitPages(memory uint64) uint32 {
+	pages := memory / wasmPageSize
+	if pages < 1 {
+		pages = 1
+	}
+	// wasm32 can't address any more than this anyway
+	if pages > 65536 {
+		pages = 65536
+	}
+	return uint32(pages)
+}
+
+// find the module in the input volumes - the path is from the point of
+// view of the module
+func readModule(entryModule string, inputs map[string]string) ([]byte, error) {
+	entryModule = filepath.Clean(entryModule)
+	for target, source := range inputs {
+		target = filepath.Clean(target)
+		if entryModule == target {
+			return os.ReadFile(source)
+		}
+		rel, err := filepath.Rel(target, entryModule)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		return os.ReadFile(filepath.Join(source, rel))
+	}
+	return nil, fmt.Errorf("wasm module %s is not in any of the job's input volumes", entryModule)
+}
+
+func linkOrCopyFile(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+func (e *Executor) GetResultsJobIDs(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(e.ResultsDir)
+	if err != nil {
+		return nil, err
+	}
+	jobIDs := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			jobIDs = append(jobIDs, entry.Name())
+		}
+	}
+	return jobIDs, nil
+}
+
+func (e *Executor) CleanupJobResults(ctx context.Context, jobID string) error {
+	if jobID == "" || filepath.Base(jobID) != jobID {
+		return fmt.Errorf("invalid job id: '%s'", jobID)
+	}
+	return os.RemoveAll(filepath.Join(e.ResultsDir, jobID))
+}
+
+func (e *Executor) shardResultsDir(job executor.Job, shardIndex int) string {
+	return fmt.Sprintf("%s/%s/%d", e.ResultsDir, job.ID, shardIndex)
+}
+
+func (e *Executor) ensureShardResultsDir(job executor.Job, shardIndex int) (string, error) {
+	dir := e.shardResultsDir(job, shardIndex)
+	err := os.MkdirAll(dir, util.OS_ALL_RWX)
+	return dir, err
+}
+
+func newSpan(ctx context.Context, apiName string) (context.Context, trace.Span) {
+	return system.Span(ctx, "executor/wasm", apiName)
+}
+
+// Compile-time interface checks:
+var _ executor.Executor = (*Executor)(nil)
+var _ executor.ResultsKeeper = (*Executor)(nil)
@@ -0,0 +1,252 @@
+package wasm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/capacitymanager"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	noop_storage "github.com/filecoin-project/bacalhau/pkg/storage/noop"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type WasmExecutorSuite struct {
+	suite.Suite
+	cm *system.CleanupManager
+	// where the noop storage provider finds volumes - by cid
+	storageDir string
+}
+
+func TestWasmExecutorSuite(t *testing.T) {
+	suite.Run(t, new(WasmExecutorSuite))
+}
+
+// Before each test
+func (suite *WasmExecutorSuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+	suite.cm = system.NewCleanupManager()
+	suite.storageDir = suite.T().TempDir()
+}
+
+func (suite *WasmExecutorSuite) TearDownTest() {
+	suite.cm.Cleanup()
+}
+
+func (suite *WasmExecutorSuite) newExecutor(config ExecutorConfig) *Executor {
+	storageProvider, err := noop_storage.NewStorageProviderWithConfig(suite.cm, noop_storage.StorageConfig{
+		ExternalHooks: noop_storage.StorageConfigExternalHooks{
+			PrepareStorage: func(ctx context.Context, spec storage.StorageSpec) (storage.StorageVolume, error) {
+				return storage.StorageVolume{
+					Type:   storage.StorageVolumeConnectorBind,
+					Source: filepath.Join(suite.storageDir, spec.Cid),
+					Target: spec.Path,
+				}, nil
+			},
+		},
+	})
+	require.NoError(suite.T(), err)
+	e, err := NewExecutor(suite.cm, map[storage.StorageSourceType]storage.StorageProvider{
+		storage.StorageSourceIPFS: storageProvider,
+	}, config)
+	require.NoError(suite.T(), err)
+	return e
+}
+
+// put a file into storage under the given cid
+func (suite *WasmExecutorSuite) addFile(cid, name string, content []byte) {
+	path := filepath.Join(suite.storageDir, cid, name)
+	if name == "" {
+		path = filepath.Join(suite.storageDir, cid)
+	}
+	require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	require.NoError(suite.T(), os.WriteFile(path, content, 0644))
+}
+
+func (suite *WasmExecutorSuite) job(module []byte, wasm executor.JobSpecWasm) executor.Job {
+	suite.addFile("module", "", module)
+	wasm.EntryModule = "/job/main.wasm"
+	return executor.Job{
+		ID: "job-1",
+		Spec: executor.JobSpec{
+			Engine: executor.EngineWasm,
+			Wasm:   wasm,
+			Contexts: []storage.StorageSpec{
+				{Engine: storage.StorageSourceIPFS, Cid: "module", Path: "/job/main.wasm"},
+			},
+		},
+		ExecutionPlan: executor.JobExecutionPlan{TotalShards: 1},
+	}
+}
+
+func (suite *WasmExecutorSuite) requireResults(resultsDir string, expected map[string]string) {
+	for name, content := range expected {
+		actual, err := os.ReadFile(filepath.Join(resultsDir, name))
+		require.NoError(suite.T(), err, name)
+		require.Equal(suite.T(), content, string(actual), name)
+	}
+}
+
+func (suite *WasmExecutorSuite) TestRunShard() {
+	e := suite.newExecutor(ExecutorConfig{})
+	suite.addFile("inputs", "in.txt", []byte("apple"))
+
+	// the context the module is in comes first, then the inputs then outputs
+	j := suite.job(catModule(4, 5), executor.JobSpecWasm{})
+	j.Spec.Inputs = []storage.StorageSpec{
+		{Engine: storage.StorageSourceIPFS, Cid: "inputs", Path: "/inputs"},
+	}
+	j.Spec.Outputs = []storage.StorageSpec{
+		{Name: "outputs", Path: "/outputs"},
+	}
+
+	resultsDir, err := e.RunShard(context.Background(), j, 0)
+	require.NoError(suite.T(), err)
+	suite.requireResults(resultsDir, map[string]string{
+		"stdout":          "apple",
+		"stderr":          "",
+		"exitCode":        "0",
+		"outputs/out.txt": "apple",
+	})
+
+	jobIDs, err := e.GetResultsJobIDs(context.Background())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), []string{"job-1"}, jobIDs)
+	require.NoError(suite.T(), e.CleanupJobResults(context.Background(), "job-1"))
+	_, err = os.Stat(resultsDir)
+	require.True(suite.T(), os.IsNotExist(err))
+}
+
+func (suite *WasmExecutorSuite) TestRunShardWithRuntime() {
+	e := suite.newExecutor(ExecutorConfig{})
+	libDir := suite.T().TempDir()
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(libDir, "in.txt"), []byte("banana"), 0644))
+
+	j := executor.Job{
+		ID: "job-1",
+		Spec: executor.JobSpec{
+			Engine: executor.EngineWasm,
+			Outputs: []storage.StorageSpec{
+				{Name: "outputs", Path: "/outputs"},
+			},
+		},
+		ExecutionPlan: executor.JobExecutionPlan{TotalShards: 1},
+	}
+
+	// the runtime's mounts come after the outputs
+	resultsDir, err := e.RunShardWithRuntime(context.Background(), j, 0,
+		func(ctx context.Context, inputs map[string]string) (Runtime, error) {
+			return Runtime{
+				Module: catModule(4, 3),
+				Mounts: map[string]string{"/lib": libDir},
+			}, nil
+		})
+	require.NoError(suite.T(), err)
+	suite.requireResults(resultsDir, map[string]string{
+		"stdout":          "banana",
+		"outputs/out.txt": "banana",
+	})
+}
+
+func (suite *WasmExecutorSuite) TestExitCode() {
+	e := suite.newExecutor(ExecutorConfig{})
+	resultsDir, err := e.RunShard(context.Background(), suite.job(exitModule(3), executor.JobSpecWasm{}), 0)
+	require.Error(suite.T(), err)
+	suite.requireResults(resultsDir, map[string]string{
+		"stdout":   "hello\n",
+		"exitCode": "3",
+	})
+}
+
+func (suite *WasmExecutorSuite) TestOutOfFuel() {
+	e := suite.newExecutor(ExecutorConfig{})
+	j := suite.job(callForeverModule(), executor.JobSpecWasm{Fuel: 1000})
+
+	// the module is stopped at the same place every time
+	for i := 0; i < 2; i++ {
+		resultsDir, err := e.RunShard(context.Background(), j, 0)
+		require.ErrorIs(suite.T(), err, errOutOfFuel)
+		suite.requireResults(resultsDir, map[string]string{
+			"stdout":   "hello\n",
+			"stderr":   "wasm module ran out of fuel\n",
+			"exitCode": "152",
+		})
+		require.NoError(suite.T(), e.CleanupJobResults(context.Background(), j.ID))
+	}
+}
+
+// fuel is used up on every loop iteration too so a loop that never calls
+// anything is still stopped long before the timeout
+func (suite *WasmExecutorSuite) TestOutOfFuelInLoop() {
+	e := suite.newExecutor(ExecutorConfig{Timeout: time.Minute})
+	j := suite.job(loopForeverModule(), executor.JobSpecWasm{Fuel: 1000})
+	resultsDir, err := e.RunShard(context.Background(), j, 0)
+	require.ErrorIs(suite.T(), err, errOutOfFuel)
+	suite.requireResults(resultsDir, map[string]string{
+		"stderr":   "wasm module ran out of fuel\n",
+		"exitCode": "152",
+	})
+}
+
+func (suite *WasmExecutorSuite) TestEnoughFuel() {
+	e := suite.newExecutor(ExecutorConfig{})
+	resultsDir, err := e.RunShard(context.Background(), suite.job(exitModule(0), executor.JobSpecWasm{Fuel: 1000}), 0)
+	require.NoError(suite.T(), err)
+	suite.requireResults(resultsDir, map[string]string{
+		"stdout":   "hello\n",
+		"exitCode": "0",
+	})
+}
+
+func (suite *WasmExecutorSuite) TestTimeout() {
+	e := suite.newExecutor(ExecutorConfig{Timeout: time.Millisecond * 100})
+	resultsDir, err := e.RunShard(context.Background(), suite.job(loopForeverModule(), executor.JobSpecWasm{}), 0)
+	require.Error(suite.T(), err)
+	suite.requireResults(resultsDir, map[string]string{
+		"exitCode": "137",
+	})
+}
+
+func (suite *WasmExecutorSuite) TestMemoryLimit() {
+	e := suite.newExecutor(ExecutorConfig{})
+
+	// plenty of room to grow
+	resultsDir, err := e.RunShard(context.Background(), suite.job(growModule(), executor.JobSpecWasm{}), 0)
+	require.NoError(suite.T(), err)
+	suite.requireResults(resultsDir, map[string]string{"exitCode": "0"})
+	require.NoError(suite.T(), e.CleanupJobResults(context.Background(), "job-1"))
+
+	j := suite.job(growModule(), executor.JobSpecWasm{})
+	j.Spec.Resources = capacitymanager.ResourceUsageConfig{Memory: "1Mb"}
+	resultsDir, err = e.RunShard(context.Background(), j, 0)
+	require.Error(suite.T(), err)
+	suite.requireResults(resultsDir, map[string]string{"exitCode": "7"})
+}
+
+func (suite *WasmExecutorSuite) TestMissingModule() {
+	e := suite.newExecutor(ExecutorConfig{})
+	j := suite.job(exitModule(0), executor.JobSpecWasm{})
+	j.Spec.Wasm.EntryModule = "/elsewhere/main.wasm"
+	_, err := e.RunShard(context.Background(), j, 0)
+	require.Error(suite.T(), err)
+}
+
+func (suite *WasmExecutorSuite) TestEntryPoint() {
+	e := suite.newExecutor(ExecutorConfig{})
+	j := suite.job(exitModule(0), executor.JobSpecWasm{EntryPoint: "nope"})
+	_, err := e.RunShard(context.Background(), j, 0)
+	require.Error(suite.T(), err)
+}
+
+func (suite *WasmExecutorSuite) TestNetwork() {
+	e := suite.newExecutor(ExecutorConfig{})
+	j := suite.job(exitModule(0), executor.JobSpecWasm{})
+	j.Spec.Network = executor.JobSpecNetwork{Type: executor.NetworkFull}
+	_, err := e.RunShard(context.Background(), j, 0)
+	require.Error(suite.T(), err)
+}
@@ -0,0 +1,531 @@
+package wasm
+
+/*
+Fuel is used up at the start of every function call and every iteration of
+every loop, so a module can't run forever without running out whatever it
+does - every other instruction runs a bounded number of times between two
+of those points. wazero has no way of counting instructions so the module
+is rewritten before it is compiled: it gets a global holding the fuel that
+is left and every function body and loop body starts by using up a unit of
+it. When there is none left the module sets the global to -1 and traps,
+which is the same place on every node.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// the exported global the module keeps its fuel in
+const fuelExportName = "bacalhau_fuel"
+
+var errOutOfFuel = errors.New("wasm module ran out of fuel")
+
+const (
+	sectionCustom = 0
+	sectionImport = 2
+	sectionGlobal = 6
+	sectionExport = 7
+	sectionCode   = 10
+
+	importFunc   = 0x00
+	importTable  = 0x01
+	importMemory = 0x02
+	importGlobal = 0x03
+
+	exportGlobal = 0x03
+
+	blockTypeEmpty = 0x40
+	valTypeI64     = 0x7e
+	globalMutable  = 0x01
+
+	opUnreachable = 0x00
+	opLoop        = 0x03
+	opIf          = 0x04
+	opEnd         = 0x0b
+	opGlobalGet   = 0x23
+	opGlobalSet   = 0x24
+	opI64Const    = 0x42
+	opI64Eqz      = 0x50
+	opI64Sub      = 0x7d
+	opPrefixMisc  = 0xfc
+	opPrefixSIMD  = 0xfd
+)
+
+// where each section has to go relative to the others - the data count
+// section is numbered after the code section but comes before it
+var sectionOrder = map[byte]int{
+	1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 13: 6, 6: 7, 7: 8, 8: 9, 9: 10, 12: 11, 10: 12, 11: 13,
+}
+
+type wasmSection struct {
+	id      byte
+	content []byte
+}
+
+// rewrite the module so it stops once it has used up the given fuel - see
+// the top of this file
+func meterModule(module []byte, fuel uint64) ([]byte, error) {
+	if len(module) < 8 || !bytes.Equal(module[:4], []byte{0x00, 'a', 's', 'm'}) {
+		return nil, fmt.Errorf("not a wasm module")
+	}
+	if fuel > math.MaxInt64 {
+		fuel = math.MaxInt64
+	}
+
+	sections := []wasmSection{}
+	r := &wasmReader{data: module, pos: 8}
+	for r.pos < len(r.data) {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		content, err := r.vec()
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, wasmSection{id: id, content: content})
+	}
+
+	importedGlobals := uint64(0)
+	for _, section := range sections {
+		if section.id == sectionImport {
+			var err error
+			importedGlobals, err = countImportedGlobals(section.content)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the fuel global goes at the end of the global section so
+	// the indexes of the module's own globals stay the same
+	fuelGlobal := importedGlobals
+	globalEntry := concat(
+		[]byte{valTypeI64, globalMutable, opI64Const}, sleb(int64(fuel)), []byte{opEnd},
+	)
+
+	var err error
+	sections, err = appendToSection(sections, sectionGlobal, globalEntry, func(count uint64) {
+		fuelGlobal = importedGlobals + count
+	})
+	if err != nil {
+		return nil, err
+	}
+	exportEntry := concat(str(fuelExportName), []byte{exportGlobal}, uleb(fuelGlobal))
+	sections, err = appendToSection(sections, sectionExport, exportEntry, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, section := range sections {
+		if section.id != sectionCode {
+			continue
+		}
+		sections[i].content, err = meterCode(section.content, fuelGlobal)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ret := append([]byte{}, module[:8]...)
+	for _, section := range sections {
+		ret = append(ret, section.id)
+		ret = append(ret, uleb(uint64(len(section.content)))...)
+		ret = append(ret, section.content...)
+	}
+	return ret, nil
+}
+
+// add an entry to the end of a vector section - creating the section if
+// the module doesn't have one - and tell the caller how many entries
+// there were before
+func appendToSection(
+	sections []wasmSection,
+	id byte,
+	entry []byte,
+	existing func(count uint64),
+) ([]wasmSection, error) {
+	for i, section := range sections {
+		if section.id != id {
+			continue
+		}
+		r := &wasmReader{data: section.content}
+		count, err := r.uleb()
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			existing(count)
+		}
+		sections[i].content = concat(uleb(count+1), section.content[r.pos:], entry)
+		return sections, nil
+	}
+
+	if existing != nil {
+		existing(0)
+	}
+	// custom sections can go anywhere so we go before the first
+	// section that has to come after us
+	insertAt := len(sections)
+	for i, section := range sections {
+		if section.id != sectionCustom && sectionOrder[section.id] > sectionOrder[id] {
+			insertAt = i
+			break
+		}
+	}
+	newSection := wasmSection{id: id, content: concat(uleb(1), entry)}
+	return append(sections[:insertAt], append([]wasmSection{newSection}, sections[insertAt:]...)...), nil
+}
+
+func countImportedGlobals(content []byte) (uint64, error) {
+	r := &wasmReader{data: content}
+	count, err := r.uleb()
+	if err != nil {
+		return 0, err
+	}
+	globals := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		// module and field names
+		if _, err = r.vec(); err != nil {
+			return 0, err
+		}
+		if _, err = r.vec(); err != nil {
+			return 0, err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		switch kind {
+		case importFunc:
+			_, err = r.uleb()
+		case importTable:
+			if _, err = r.byte(); err == nil {
+				err = r.limits()
+			}
+		case importMemory:
+			err = r.limits()
+		case importGlobal:
+			globals++
+			err = r.skip(2)
+		default:
+			return 0, fmt.Errorf("unsupported import kind: %d", kind)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return globals, nil
+}
+
+// the instructions that use up a unit of fuel - or set the fuel to -1
+// and trap if there is none left
+func burnFuel(fuelGlobal uint64) []byte {
+	return concat(
+		[]byte{opGlobalGet}, uleb(fuelGlobal),
+		[]byte{opI64Eqz, opIf, blockTypeEmpty, opI64Const}, sleb(-1),
+		[]byte{opGlobalSet}, uleb(fuelGlobal),
+		[]byte{opUnreachable, opEnd},
+		[]byte{opGlobalGet}, uleb(fuelGlobal),
+		[]byte{opI64Const}, sleb(1),
+		[]byte{opI64Sub, opGlobalSet}, uleb(fuelGlobal),
+	)
+}
+
+func meterCode(content []byte, fuelGlobal uint64) ([]byte, error) {
+	r := &wasmReader{data: content}
+	count, err := r.uleb()
+	if err != nil {
+		return nil, err
+	}
+	ret := uleb(count)
+	for i := uint64(0); i < count; i++ {
+		body, err := r.vec()
+		if err != nil {
+			return nil, err
+		}
+		body, err = meterFunction(body, fuelGlobal)
+		if err != nil {
+			return nil, fmt.Errorf("function %d: %w", i, err)
+		}
+		ret = append(ret, uleb(uint64(len(body)))...)
+		ret = append(ret, body...)
+	}
+	return ret, nil
+}
+
+func meterFunction(body []byte, fuelGlobal uint64) ([]byte, error) {
+	burn := burnFuel(fuelGlobal)
+	r := &wasmReader{data: body}
+	localGroups, err := r.uleb()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < localGroups; i++ {
+		if _, err = r.uleb(); err != nil {
+			return nil, err
+		}
+		if _, err = r.byte(); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := append(append([]byte{}, body[:r.pos]...), burn...)
+	copied := r.pos
+	for r.pos < len(body) {
+		op, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		err = r.skipImmediates(op)
+		if err != nil {
+			return nil, err
+		}
+		// branching back to a loop goes to the start of its body
+		if op == opLoop {
+			ret = append(ret, body[copied:r.pos]...)
+			ret = append(ret, burn...)
+			copied = r.pos
+		}
+	}
+	return append(ret, body[copied:]...), nil
+}
+
+type wasmReader struct {
+	data []byte
+	pos  int
+}
+
+var errTruncated = errors.New("wasm module is truncated")
+
+func (r *wasmReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *wasmReader) skip(n int) error {
+	if r.pos+n > len(r.data) {
+		return errTruncated
+	}
+	r.pos += n
+	return nil
+}
+
+func (r *wasmReader) uleb() (uint64, error) {
+	value := uint64(0)
+	for shift := 0; shift < 70; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid leb128 value")
+}
+
+// signed values are the same length as unsigned ones and we
+// only ever need to skip over them
+func (r *wasmReader) sleb() error {
+	_, err := r.uleb()
+	return err
+}
+
+// a length followed by that many bytes
+func (r *wasmReader) vec() ([]byte, error) {
+	length, err := r.uleb()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.data)-r.pos) {
+		return nil, errTruncated
+	}
+	start := r.pos
+	r.pos += int(length)
+	return r.data[start:r.pos], nil
+}
+
+func (r *wasmReader) limits() error {
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if _, err = r.uleb(); err != nil {
+		return err
+	}
+	if flags&0x01 != 0 {
+		_, err = r.uleb()
+	}
+	return err
+}
+
+func (r *wasmReader) ulebs(n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := r.uleb(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isValType(b byte) bool {
+	switch b {
+	case 0x7f, 0x7e, 0x7d, 0x7c, 0x7b, 0x70, 0x6f:
+		return true
+	}
+	return false
+}
+
+// move past whatever follows the opcode - we have to know every opcode
+// to do this so modules using proposals wazero doesn't support either
+// are rejected
+//
+//nolint:gocyclo // one case per kind of instruction
+func (r *wasmReader) skipImmediates(op byte) error {
+	switch {
+	case op == 0x02 || op == opLoop || op == opIf:
+		// block type - empty, a value type or a type index
+		if r.pos < len(r.data) && (r.data[r.pos] == blockTypeEmpty || isValType(r.data[r.pos])) {
+			return r.skip(1)
+		}
+		return r.sleb()
+	case op == 0x0c || op == 0x0d || op == 0x10 || op == 0xd2 || (op >= 0x20 && op <= 0x26):
+		// branches, calls, locals, globals, tables and ref.func
+		return r.ulebs(1)
+	case op == 0x0e:
+		targets, err := r.uleb()
+		if err != nil {
+			return err
+		}
+		return r.ulebs(int(targets) + 1)
+	case op == 0x11:
+		return r.ulebs(2)
+	case op == 0x1c:
+		types, err := r.uleb()
+		if err != nil {
+			return err
+		}
+		return r.skip(int(types))
+	case op >= 0x28 && op <= 0x3e:
+		// memory alignment and offset
+		return r.ulebs(2)
+	case op == 0x3f || op == 0x40:
+		return r.ulebs(1)
+	case op == 0x41 || op == opI64Const:
+		return r.sleb()
+	case op == 0x43:
+		return r.skip(4)
+	case op == 0x44:
+		return r.skip(8)
+	case op == 0xd0:
+		return r.skip(1)
+	case op == opPrefixMisc:
+		return r.skipMiscImmediates()
+	case op == opPrefixSIMD:
+		return r.skipSIMDImmediates()
+	case op == opUnreachable || op == 0x01 || op == 0x05 || op == opEnd || op == 0x0f ||
+		op == 0x1a || op == 0x1b || op == 0xd1 || (op >= 0x45 && op <= 0xc4):
+		return nil
+	default:
+		return fmt.Errorf("unsupported wasm instruction: 0x%02x", op)
+	}
+}
+
+// saturating truncation, bulk memory and table instructions
+func (r *wasmReader) skipMiscImmediates() error {
+	op, err := r.uleb()
+	if err != nil {
+		return err
+	}
+	switch {
+	case op <= 7:
+		return nil
+	case op == 8:
+		if err = r.ulebs(1); err != nil {
+			return err
+		}
+		return r.skip(1)
+	case op == 9 || op == 13 || (op >= 15 && op <= 17):
+		return r.ulebs(1)
+	case op == 10:
+		return r.skip(2)
+	case op == 11:
+		return r.skip(1)
+	case op == 12 || op == 14:
+		return r.ulebs(2)
+	default:
+		return fmt.Errorf("unsupported wasm instruction: 0xfc %d", op)
+	}
+}
+
+func (r *wasmReader) skipSIMDImmediates() error {
+	op, err := r.uleb()
+	if err != nil {
+		return err
+	}
+	switch {
+	case op <= 11 || op == 92 || op == 93:
+		// loads and stores
+		return r.ulebs(2)
+	case op == 12 || op == 13:
+		// v128.const and i8x16.shuffle
+		return r.skip(16)
+	case op >= 21 && op <= 34:
+		// lane index
+		return r.skip(1)
+	case op >= 84 && op <= 91:
+		// lane loads and stores
+		if err = r.ulebs(2); err != nil {
+			return err
+		}
+		return r.skip(1)
+	default:
+		return nil
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	ret := []byte{}
+	for _, part := range parts {
+		ret = append(ret, part...)
+	}
+	return ret
+}
+
+func str(s string) []byte {
+	return append(uleb(uint64(len(s))), s...)
+}
+
+func uleb(v uint64) []byte {
+	ret := []byte{}
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			ret = append(ret, b|0x80)
+			continue
+		}
+		return append(ret, b)
+	}
+}
+
+func sleb(v int64) []byte {
+	ret := []byte{}
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(ret, b)
+		}
+		ret = append(ret, b|0x80)
+	}
+}
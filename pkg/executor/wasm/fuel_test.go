@@ -0,0 +1,22 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeterModuleRejectsUnknownInstructions(t *testing.T) {
+	_, err := meterModule(buildModule(code{0xff}), 10)
+	require.Error(t, err)
+
+	_, err = meterModule([]byte("not wasm"), 10)
+	require.Error(t, err)
+}
+
+func TestMeterModuleAddsFuelGlobal(t *testing.T) {
+	module, err := meterModule(loopForeverModule(), 10)
+	require.NoError(t, err)
+	require.Contains(t, string(module), fuelExportName)
+	require.Greater(t, len(module), len(loopForeverModule()))
+}
@@ -0,0 +1,226 @@
+package wasm
+
+import (
+	"bytes"
+)
+
+// there's no toolchain for building wasm in CI so the modules the tests
+// run are put together by hand here - they all import the same WASI
+// functions and have one page of memory laid out like this:
+//
+//	0    iovec (pointer, length)
+//	16   bytes read / written
+//	20   the fd returned by path_open
+//	100  a 64 byte buffer
+//	200  "in.txt"
+//	210  "out.txt"
+//	300  "hello\n"
+
+const (
+	fnFdWrite = iota
+	fnFdRead
+	fnPathOpen
+	fnProcExit
+	// the functions each module defines come after the imports
+	fnStart
+	fnNoop
+)
+
+const (
+	typeRWIO = iota // fd_write, fd_read
+	typePathOpen
+	typeProcExit
+	typeVoid
+)
+
+const (
+	opBlockVoid  = 0x40
+	opBr         = 0x0c
+	opCall       = 0x10
+	opDrop       = 0x1a
+	opI32Load    = 0x28
+	opI32Store   = 0x36
+	opMemoryGrow = 0x40
+	opI32Const   = 0x41
+	opI32Eq      = 0x46
+	valI32       = 0x7f
+	valI64       = 0x7e
+)
+
+type code []byte
+
+func i32(v int64) code {
+	return append(code{opI32Const}, sleb(v)...)
+}
+
+func i64(v int64) code {
+	return append(code{opI64Const}, sleb(v)...)
+}
+
+func call(fn int) code {
+	return append(code{opCall}, uleb(uint64(fn))...)
+}
+
+func load(addr int64) code {
+	return join(i32(addr), code{opI32Load, 0x02, 0x00})
+}
+
+func store(addr int64, value code) code {
+	return join(i32(addr), value, code{opI32Store, 0x02, 0x00})
+}
+
+func join(codes ...code) code {
+	ret := code{}
+	for _, c := range codes {
+		ret = append(ret, c...)
+	}
+	return ret
+}
+
+// write length bytes from buf to fd
+func write(fd code, buf, length code) code {
+	return join(
+		store(0, buf),
+		store(4, length),
+		fd, i32(0), i32(1), i32(16), call(fnFdWrite), code{opDrop},
+	)
+}
+
+func hello() code {
+	return write(i32(1), i32(300), i32(6))
+}
+
+// path_open relative to a preopened dir - the fd ends up at 20
+func pathOpen(dirFd, path, pathLen, oflags, rights int64) code {
+	return join(
+		i32(dirFd), i32(0), i32(path), i32(pathLen), i32(oflags),
+		i64(rights), i64(rights), i32(0), i32(20),
+		call(fnPathOpen), code{opDrop},
+	)
+}
+
+func exit(exitCode int64) code {
+	return join(i32(exitCode), call(fnProcExit))
+}
+
+// copies in.txt in one preopened dir to stdout and to out.txt in another -
+// the preopened dirs are numbered from 3 in the order they are mounted
+func catModule(inputsFd, outputsFd int64) []byte {
+	return buildModule(join(
+		// FD_READ
+		pathOpen(inputsFd, 200, 6, 0, 0x2),
+		store(0, i32(100)),
+		store(4, i32(64)),
+		load(20), i32(0), i32(1), i32(16), call(fnFdRead), code{opDrop},
+		write(i32(1), i32(100), load(16)),
+		// O_CREAT and FD_WRITE
+		pathOpen(outputsFd, 210, 7, 1, 0x40),
+		write(load(20), i32(100), load(16)),
+	))
+}
+
+func exitModule(exitCode int64) []byte {
+	return buildModule(join(hello(), exit(exitCode)))
+}
+
+// calls a function that does nothing over and over
+func callForeverModule() []byte {
+	return buildModule(join(
+		hello(),
+		code{opLoop, opBlockVoid},
+		call(fnNoop),
+		code{opBr, 0x00, opEnd},
+	))
+}
+
+// loops without calling anything
+func loopForeverModule() []byte {
+	return buildModule(code{opLoop, opBlockVoid, opBr, 0x00, opEnd})
+}
+
+// exits with 7 if it can't grow its memory by 100 pages
+func growModule() []byte {
+	return buildModule(join(
+		i32(100), code{opMemoryGrow, 0x00},
+		i32(-1), code{opI32Eq},
+		code{opIf, opBlockVoid},
+		exit(7),
+		code{opEnd},
+	))
+}
+
+func buildModule(start code) []byte {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00})
+
+	section(buf, 1, vec(
+		funcType([]byte{valI32, valI32, valI32, valI32}, []byte{valI32}),
+		funcType([]byte{valI32, valI32, valI32, valI32, valI32, valI64, valI64, valI32, valI32}, []byte{valI32}),
+		funcType([]byte{valI32}, nil),
+		funcType(nil, nil),
+	))
+
+	imp := func(name string, typ int) []byte {
+		return join(str("wasi_snapshot_preview1"), str(name), code{0x00}, uleb(uint64(typ)))
+	}
+	section(buf, 2, vec(
+		imp("fd_write", typeRWIO),
+		imp("fd_read", typeRWIO),
+		imp("path_open", typePathOpen),
+		imp("proc_exit", typeProcExit),
+	))
+
+	section(buf, 3, vec([]byte{typeVoid}, []byte{typeVoid}))
+
+	// one page of memory with no maximum
+	section(buf, 5, vec([]byte{0x00, 0x01}))
+
+	section(buf, 7, vec(
+		join(str("_start"), code{0x00}, uleb(fnStart)),
+		join(str("memory"), code{0x02, 0x00}),
+	))
+
+	body := func(c code) []byte {
+		// no locals
+		b := join(code{0x00}, c, code{opEnd})
+		return join(uleb(uint64(len(b))), b)
+	}
+	section(buf, 10, vec(body(start), body(nil)))
+
+	data := func(offset int64, content string) []byte {
+		return join(code{0x00}, i32(offset), code{opEnd}, uleb(uint64(len(content))), []byte(content))
+	}
+	section(buf, 11, vec(
+		data(200, "in.txt"),
+		data(210, "out.txt"),
+		data(300, "hello\n"),
+	))
+
+	return buf.Bytes()
+}
+
+func section(buf *bytes.Buffer, id byte, content []byte) {
+	buf.WriteByte(id)
+	buf.Write(uleb(uint64(len(content))))
+	buf.Write(content)
+}
+
+func vec(items ...[]byte) []byte {
+	ret := uleb(uint64(len(items)))
+	for _, item := range items {
+		ret = append(ret, item...)
+	}
+	return ret
+}
+
+func funcType(params, results []byte) []byte {
+	return join(code{0x60}, vec(bytesToItems(params)...), vec(bytesToItems(results)...))
+}
+
+func bytesToItems(b []byte) [][]byte {
+	ret := [][]byte{}
+	for _, v := range b {
+		ret = append(ret, []byte{v})
+	}
+	return ret
+}
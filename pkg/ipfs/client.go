@@ -178,6 +178,19 @@ func (cl *Client) Put(ctx context.Context, inputPath string) (string, error) {
 	return cid, nil
 }
 
+// Unpin removes the pin on a file or directory so the node can garbage
+// collect it. It errors if the CID was not pinned by the node.
+func (cl *Client) Unpin(ctx context.Context, cid string) error {
+	ctx, span := newSpan(ctx, "Unpin")
+	defer span.End()
+
+	err := cl.api.Pin().Rm(ctx, icorepath.New(cid))
+	if err != nil {
+		return fmt.Errorf("failed to unpin '%s': %w", cid, err)
+	}
+	return nil
+}
+
 type IPLDType int
 
 const (
@@ -0,0 +1,221 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	files "github.com/ipfs/go-ipfs-files"
+	icore "github.com/ipfs/interface-go-ipfs-core"
+	icoreoptions "github.com/ipfs/interface-go-ipfs-core/options"
+	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
+	"github.com/rs/zerolog/log"
+)
+
+// CombineJobResults combines the result set the verifier has for the job
+// into a single directory - the verifiers that publish results to ipfs
+// all combine them this way
+func (cl *Client) CombineJobResults(
+	ctx context.Context,
+	jobID string,
+	jobLoader func(ctx context.Context, id string) (executor.Job, error),
+	v verifier.Verifier,
+) (storage.StorageSpec, error) {
+	j, err := jobLoader(ctx, jobID)
+	if err != nil {
+		return storage.StorageSpec{}, err
+	}
+	results, err := v.GetJobResultSet(ctx, jobID)
+	if err != nil {
+		return storage.StorageSpec{}, err
+	}
+	cid, err := cl.CombineResults(ctx, j, results)
+	if err != nil {
+		return storage.StorageSpec{}, err
+	}
+	return storage.StorageSpec{
+		Name:   "results",
+		Path:   "results",
+		Engine: storage.StorageSourceIPFS,
+		Cid:    cid,
+	}, nil
+}
+
+// CombineResults builds a single directory out of the results of every
+// shard of a job, without fetching anything but the logs, and pins it.
+// the layout is the same as what DownloadJob writes to the output dir:
+//
+//	volumes/<name>/...        every shard's output volume merged together
+//	shards/shard<N>/stdout    (and stderr, exitCode) for shard N
+//	stdout, stderr            every shard's logs concatenated
+//
+// files with the same path in more than one shard are taken from the
+// last shard in the list, like DownloadJob does.
+func (cl *Client) CombineResults(
+	ctx context.Context,
+	job executor.Job,
+	results []storage.StorageSpec,
+) (string, error) {
+	ctx, span := newSpan(ctx, "CombineResults")
+	defer span.End()
+
+	if len(results) == 0 {
+		return "", fmt.Errorf("no results to combine for job %s", job.ID)
+	}
+
+	emptyDir, err := cl.api.Object().New(ctx, icoreoptions.Object.Type("unixfs-dir"))
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	emptyDirPath := icorepath.IpfsPath(emptyDir.Cid())
+
+	// output volumes are always there even if no shard wrote to them
+	root := emptyDirPath
+	for _, outputVolume := range job.Spec.Outputs {
+		root, err = cl.addLink(ctx, root, path.Join("volumes", outputVolume.Name), emptyDirPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	logs := map[string]*bytes.Buffer{
+		"stdout": {},
+		"stderr": {},
+	}
+
+	for _, result := range results {
+		if result.Engine != storage.StorageSourceIPFS {
+			return "", fmt.Errorf("cannot combine %s result %s - only results on ipfs can be combined", result.Engine, result.Name)
+		}
+		log.Debug().Msgf("Combining result CID %s '%s'", result.Name, result.Cid)
+		shardPath := icorepath.New(result.Cid)
+
+		for _, outputVolume := range job.Spec.Outputs {
+			root, err = cl.mergeDirectory(
+				ctx,
+				root,
+				path.Join("volumes", outputVolume.Name),
+				icorepath.Join(shardPath, outputVolume.Name),
+			)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		for _, filename := range []string{
+			"stdout",
+			"stderr",
+			"exitCode",
+		} {
+			filePath := icorepath.Join(shardPath, filename)
+			root, err = cl.addLink(ctx, root, path.Join("shards", result.Name, filename), filePath)
+			if err != nil {
+				return "", err
+			}
+
+			buf, ok := logs[filename]
+			if !ok {
+				continue
+			}
+			err = cl.readFile(ctx, filePath, buf)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	for _, filename := range []string{
+		"stdout",
+		"stderr",
+	} {
+		logPath, err := cl.api.Unixfs().Add(ctx, files.NewBytesFile(logs[filename].Bytes()))
+		if err != nil {
+			return "", fmt.Errorf("failed to add combined %s: %w", filename, err)
+		}
+		root, err = cl.addLink(ctx, root, filename, logPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// pinning the root pins everything under it which stops the
+	// shard results being garbage collected while the combined
+	// result is still around
+	err = cl.api.Pin().Add(ctx, root)
+	if err != nil {
+		return "", fmt.Errorf("failed to pin combined results: %w", err)
+	}
+
+	return root.Cid().String(), nil
+}
+
+// link everything under src into the directory at dst in root
+func (cl *Client) mergeDirectory(
+	ctx context.Context,
+	root icorepath.Resolved,
+	dst string,
+	src icorepath.Path,
+) (icorepath.Resolved, error) {
+	entriesChan, err := cl.api.Unixfs().Ls(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list '%s': %w", src, err)
+	}
+
+	// read them all before making more requests of the node
+	entries := []icore.DirEntry{}
+	for entry := range entriesChan {
+		if entry.Err != nil {
+			return nil, fmt.Errorf("failed to list '%s': %w", src, entry.Err)
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, entry := range entries {
+		entryPath := path.Join(dst, entry.Name)
+		if entry.Type == icore.TDirectory {
+			root, err = cl.mergeDirectory(ctx, root, entryPath, icorepath.IpfsPath(entry.Cid))
+		} else {
+			root, err = cl.addLink(ctx, root, entryPath, icorepath.IpfsPath(entry.Cid))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return root, nil
+}
+
+// adds child to root at the given path, making any missing directories
+// on the way and replacing whatever was at the path before
+func (cl *Client) addLink(
+	ctx context.Context,
+	root icorepath.Resolved,
+	name string,
+	child icorepath.Path,
+) (icorepath.Resolved, error) {
+	newRoot, err := cl.api.Object().AddLink(ctx, root, name, child, icoreoptions.Object.Create(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to link '%s' to '%s': %w", child, name, err)
+	}
+	return newRoot, nil
+}
+
+func (cl *Client) readFile(ctx context.Context, filePath icorepath.Path, w io.Writer) error {
+	node, err := cl.api.Unixfs().Get(ctx, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to get '%s': %w", filePath, err)
+	}
+	defer node.Close()
+
+	file := files.ToFile(node)
+	if file == nil {
+		return fmt.Errorf("'%s' is not a file", filePath)
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
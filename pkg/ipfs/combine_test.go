@@ -0,0 +1,206 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type CombineSuite struct {
+	suite.Suite
+}
+
+func TestCombineSuite(t *testing.T) {
+	suite.Run(t, new(CombineSuite))
+}
+
+// Before each test
+func (suite *CombineSuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+}
+
+func (suite *CombineSuite) writeShard(files map[string]string) string {
+	dir := suite.T().TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if strings.HasSuffix(name, "/") {
+			require.NoError(suite.T(), os.MkdirAll(path, os.ModePerm))
+			continue
+		}
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func (suite *CombineSuite) TestCombineResults() {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(30*time.Second))
+	defer cancel()
+
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	n, err := NewLocalNode(cm, nil)
+	require.NoError(suite.T(), err)
+	cl, err := n.Client()
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), cl.WaitUntilAvailable(ctx))
+
+	job := executor.Job{
+		Spec: executor.JobSpec{
+			Outputs: []storage.StorageSpec{
+				{Name: "outputs", Path: "/outputs"},
+				{Name: "empty", Path: "/empty"},
+			},
+		},
+	}
+
+	results := []storage.StorageSpec{}
+	for i, shardFiles := range []map[string]string{
+		{
+			"stdout":                "apple\n",
+			"stderr":                "",
+			"exitCode":              "0",
+			"outputs/apple.txt":     "apple",
+			"outputs/fruit/one.txt": "apple",
+			"empty/":                "",
+		},
+		{
+			"stdout":                "banana\n",
+			"stderr":                "oops\n",
+			"exitCode":              "1",
+			"outputs/banana.txt":    "banana",
+			"outputs/fruit/one.txt": "banana",
+			"empty/":                "",
+		},
+	} {
+		cid, err := cl.Put(ctx, suite.writeShard(shardFiles))
+		require.NoError(suite.T(), err)
+		results = append(results, storage.StorageSpec{
+			Name:   []string{"shard0", "shard1"}[i],
+			Path:   []string{"shard0", "shard1"}[i],
+			Engine: storage.StorageSourceIPFS,
+			Cid:    cid,
+		})
+	}
+
+	cid, err := cl.CombineResults(ctx, job, results)
+	require.NoError(suite.T(), err)
+
+	_, isPinned, err := cl.api.Pin().IsPinned(ctx, icorepath.New(cid))
+	require.NoError(suite.T(), err)
+	require.True(suite.T(), isPinned)
+
+	outputDir := filepath.Join(suite.T().TempDir(), "combined")
+	require.NoError(suite.T(), cl.Get(ctx, cid, outputDir))
+
+	// nothing was written to this volume but it should still be there
+	stat, err := os.Stat(filepath.Join(outputDir, "volumes", "empty"))
+	require.NoError(suite.T(), err)
+	require.True(suite.T(), stat.IsDir())
+
+	for name, expected := range map[string]string{
+		"stdout":                        "apple\nbanana\n",
+		"stderr":                        "oops\n",
+		"volumes/outputs/apple.txt":     "apple",
+		"volumes/outputs/banana.txt":    "banana",
+		"volumes/outputs/fruit/one.txt": "banana",
+		"shards/shard0/stdout":          "apple\n",
+		"shards/shard0/exitCode":        "0",
+		"shards/shard1/stderr":          "oops\n",
+		"shards/shard1/exitCode":        "1",
+	} {
+		content, err := os.ReadFile(filepath.Join(outputDir, name))
+		require.NoError(suite.T(), err, name)
+		require.Equal(suite.T(), expected, string(content), name)
+	}
+}
+
+func (suite *CombineSuite) TestCombineResultsOnlyFromIPFS() {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(30*time.Second))
+	defer cancel()
+
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	n, err := NewLocalNode(cm, nil)
+	require.NoError(suite.T(), err)
+	cl, err := n.Client()
+	require.NoError(suite.T(), err)
+
+	_, err = cl.CombineResults(ctx, executor.Job{}, []storage.StorageSpec{
+		{
+			Name:   "shard0",
+			Engine: storage.StorageSourceURLDownload,
+			URL:    "http://127.0.0.1/localfs/abc",
+		},
+	})
+	require.Error(suite.T(), err)
+}
+
+// has the result set of one job
+type resultSetVerifier struct {
+	verifier.Verifier
+	jobID   string
+	results []storage.StorageSpec
+}
+
+func (v *resultSetVerifier) GetJobResultSet(ctx context.Context, jobID string) ([]storage.StorageSpec, error) {
+	if jobID != v.jobID {
+		return nil, fmt.Errorf("job (%s) has not completed yet", jobID)
+	}
+	return v.results, nil
+}
+
+func (suite *CombineSuite) TestCombineJobResults() {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(30*time.Second))
+	defer cancel()
+
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	n, err := NewLocalNode(cm, nil)
+	require.NoError(suite.T(), err)
+	cl, err := n.Client()
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), cl.WaitUntilAvailable(ctx))
+
+	cid, err := cl.Put(ctx, suite.writeShard(map[string]string{"stdout": "apple\n", "stderr": "", "exitCode": "0"}))
+	require.NoError(suite.T(), err)
+	v := &resultSetVerifier{
+		jobID: "job-1",
+		results: []storage.StorageSpec{
+			{Name: "shard0", Path: "shard0", Engine: storage.StorageSourceIPFS, Cid: cid},
+		},
+	}
+	jobLoader := func(ctx context.Context, id string) (executor.Job, error) {
+		return executor.Job{ID: id}, nil
+	}
+
+	combined, err := cl.CombineJobResults(ctx, "job-1", jobLoader, v)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "results", combined.Name)
+	require.Equal(suite.T(), storage.StorageSourceIPFS, combined.Engine)
+
+	outputDir := filepath.Join(suite.T().TempDir(), "combined")
+	require.NoError(suite.T(), cl.Get(ctx, combined.Cid, outputDir))
+	content, err := os.ReadFile(filepath.Join(outputDir, "shards", "shard0", "stdout"))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "apple\n", string(content))
+
+	// the verifier has no results for jobs that have not finished
+	_, err = cl.CombineJobResults(ctx, "job-2", jobLoader, v)
+	require.Error(suite.T(), err)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/executor"
 	"github.com/filecoin-project/bacalhau/pkg/storage"
 	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/localfs"
 	"github.com/rs/zerolog/log"
 )
 
@@ -45,16 +46,21 @@ func DownloadJob( //nolint:funlen,gocyclo
 
 	// NOTE: we have to spin up a temporary IPFS node as we don't
 	// generally have direct access to a remote node's API server.
-	log.Debug().Msg("Spinning up IPFS node...")
-	n, err := NewNode(cm, strings.Split(settings.IPFSSwarmAddrs, ","))
-	if err != nil {
-		return err
-	}
+	// we only do this if some of the results are on IPFS
+	var cl *Client
+	getClient := func() (*Client, error) {
+		if cl != nil {
+			return cl, nil
+		}
+		log.Debug().Msg("Spinning up IPFS node...")
+		n, err := NewNode(cm, strings.Split(settings.IPFSSwarmAddrs, ","))
+		if err != nil {
+			return nil, err
+		}
 
-	log.Debug().Msg("Connecting client to new IPFS node...")
-	cl, err := n.Client()
-	if err != nil {
-		return err
+		log.Debug().Msg("Connecting client to new IPFS node...")
+		cl, err = n.Client()
+		return cl, err
 	}
 
 	scratchFolder, err := ioutil.TempDir("", "bacalhau-ipfs-job-downloader")
@@ -69,16 +75,25 @@ func DownloadJob( //nolint:funlen,gocyclo
 	// so we write the shard output to our scratch folder
 	// and then merge each outout volume into the global results
 	for _, result := range results {
-		shardDownloadDir := filepath.Join(scratchFolder, result.Cid)
+		shardDownloadDir := filepath.Join(scratchFolder, result.Name)
 
 		err = func() error {
-			log.Info().Msgf("Downloading result CID %s '%s' to '%s'...", result.Name, result.Cid, shardDownloadDir)
-
 			ctx, cancel := context.WithDeadline(context.Background(),
 				time.Now().Add(time.Second*time.Duration(settings.TimeoutSecs)))
 			defer cancel()
 
-			return cl.Get(ctx, result.Cid, shardDownloadDir)
+			// results kept by the localfs verifier are served over HTTP
+			if result.Engine == storage.StorageSourceURLDownload {
+				log.Info().Msgf("Downloading result URL %s '%s' to '%s'...", result.Name, result.URL, shardDownloadDir)
+				return localfs.DownloadResults(ctx, result.URL, shardDownloadDir)
+			}
+
+			log.Info().Msgf("Downloading result CID %s '%s' to '%s'...", result.Name, result.Cid, shardDownloadDir)
+			ipfsClient, err := getClient()
+			if err != nil {
+				return err
+			}
+			return ipfsClient.Get(ctx, result.Cid, shardDownloadDir)
 		}()
 
 		if err != nil {
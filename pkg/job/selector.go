@@ -0,0 +1,43 @@
+package job
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// node selectors use the kubernetes label selector syntax, e.g.
+//
+//	region=us-east-1,gpu in (a100, h100),!spot
+//
+// see https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#label-selectors
+func ParseNodeSelector(selector string) (labels.Selector, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selector %q: %w", selector, err)
+	}
+	return parsed, nil
+}
+
+// does a compute node with the given labels satisfy the selector
+// an empty selector matches every node
+func MatchNodeSelector(selector string, nodeLabels map[string]string) (bool, error) {
+	if selector == "" {
+		return true, nil
+	}
+	parsed, err := ParseNodeSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return parsed.Matches(labels.Set(nodeLabels)), nil
+}
+
+// check the labels a compute node advertises are valid label keys and values
+// so that selectors are able to match them
+func ValidateNodeLabels(nodeLabels map[string]string) error {
+	_, err := labels.ValidatedSelectorFromSet(nodeLabels)
+	if err != nil {
+		return fmt.Errorf("invalid node labels: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchNodeSelector(t *testing.T) {
+	nodeLabels := map[string]string{
+		"region": "us-east-1",
+		"gpu":    "a100",
+	}
+
+	testCases := []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"region=us-east-1", true},
+		{"region=eu-west-1", false},
+		{"region!=eu-west-1", true},
+		{"gpu in (a100, h100)", true},
+		{"gpu notin (a100)", false},
+		{"gpu", true},
+		{"spot", false},
+		{"!spot", true},
+		{"region=us-east-1,!spot,gpu in (h100)", false},
+	}
+
+	for _, test := range testCases {
+		matches, err := MatchNodeSelector(test.selector, nodeLabels)
+		require.NoError(t, err, test.selector)
+		require.Equal(t, test.matches, matches, test.selector)
+	}
+
+	_, err := MatchNodeSelector("region in (", nodeLabels)
+	require.Error(t, err)
+}
+
+func TestValidateNodeLabels(t *testing.T) {
+	require.NoError(t, ValidateNodeLabels(nil))
+	require.NoError(t, ValidateNodeLabels(map[string]string{"region": "us-east-1"}))
+	require.Error(t, ValidateNodeLabels(map[string]string{"region": "not a valid value"}))
+}
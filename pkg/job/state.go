@@ -159,9 +159,8 @@ func (resolver *StateResolver) WaitUntilComplete(ctx context.Context, jobID stri
 			executor.JobStateCancelled,
 			executor.JobStateError,
 		}),
-		WaitForJobStates(map[executor.JobStateType]int{
-			executor.JobStateComplete: totalShards,
-		}),
+		// the requester node finalizes each shard once it has verified the results
+		WaitForFinalizedShards(job),
 	)
 }
 
@@ -183,12 +182,12 @@ func (resolver *StateResolver) GetResults(ctx context.Context, jobID string) ([]
 	totalShards := GetJobTotalShards(job)
 	groupedShardResults := GroupShardStates(GetCompletedShardStates(jobState))
 
-	// we have already filtered down to complete results
+	// we have already filtered down to accepted results
 	// so there must be totalShards entries in the groupedShardResults
 	// and it means we have a complete result set
 	if len(groupedShardResults) < totalShards {
 		return results, fmt.Errorf(
-			"job (%s) has not completed yet - %d shards out of %d are complete",
+			"job (%s) has not completed yet - %d shards out of %d have accepted results",
 			jobID,
 			len(groupedShardResults),
 			totalShards,
@@ -227,6 +226,44 @@ func (resolver *StateResolver) GetResults(ctx context.Context, jobID string) ([]
 	return results, nil
 }
 
+// like GetResults but only returns results that the requester node
+// has verified and accepted - this will error until every shard has
+// been finalized
+func (resolver *StateResolver) GetAcceptedResults(ctx context.Context, jobID string) ([]ResultsShard, error) {
+	results := []ResultsShard{}
+	job, err := resolver.jobLoader(ctx, jobID)
+	if err != nil {
+		return results, err
+	}
+	jobState, err := resolver.stateLoader(ctx, jobID)
+	if err != nil {
+		return results, err
+	}
+
+	acceptedResults := map[int]string{}
+	for _, shardState := range GetFilteredShardStates(jobState, executor.JobStateFinalized) {
+		if shardState.ResultsAccepted {
+			acceptedResults[shardState.ShardIndex] = shardState.ResultsID
+		}
+	}
+
+	for shardIndex := 0; shardIndex < GetJobTotalShards(job); shardIndex++ {
+		resultsID, ok := acceptedResults[shardIndex]
+		if !ok {
+			return results, fmt.Errorf(
+				"job (%s) has no accepted results at shard index %d",
+				jobID,
+				shardIndex,
+			)
+		}
+		results = append(results, ResultsShard{
+			ShardIndex: shardIndex,
+			ResultsID:  resultsID,
+		})
+	}
+	return results, nil
+}
+
 func FlattenShardStates(jobState executor.JobState) []executor.JobShardState {
 	ret := []executor.JobShardState{}
 	for _, nodeState := range jobState.Nodes {
@@ -247,8 +284,16 @@ func GetFilteredShardStates(jobState executor.JobState, filterState executor.Job
 	return ret
 }
 
+// the shards that have results we can use - complete shards don't count
+// until the requester node has verified and accepted their results
 func GetCompletedShardStates(jobState executor.JobState) []executor.JobShardState {
-	return GetFilteredShardStates(jobState, executor.JobStateComplete)
+	ret := []executor.JobShardState{}
+	for _, shardState := range GetFilteredShardStates(jobState, executor.JobStateFinalized) {
+		if shardState.ResultsAccepted {
+			ret = append(ret, shardState)
+		}
+	}
+	return ret
 }
 
 func HasShardReachedCapacity(job executor.Job, jobState executor.JobState, shardIndex int) bool {
@@ -336,6 +381,30 @@ func WaitForJobStates(requiredStateCounts map[executor.JobStateType]int) CheckSt
 	}
 }
 
+// wait until the requester node has finalized every execution of every shard
+// verifiers can have shards run on more nodes than the deal asks for so we
+// check that each shard has been run at least concurrency times and that
+// nothing is still running or waiting to be verified
+func WaitForFinalizedShards(job executor.Job) CheckStatesFunction {
+	return func(jobState executor.JobState) (bool, error) {
+		finalizedCount := map[int]int{}
+		for _, shardState := range FlattenShardStates(jobState) {
+			switch shardState.State {
+			case executor.JobStateWaiting, executor.JobStateRunning, executor.JobStateComplete:
+				return false, nil
+			case executor.JobStateFinalized:
+				finalizedCount[shardState.ShardIndex]++
+			}
+		}
+		for shardIndex := 0; shardIndex < GetJobTotalShards(job); shardIndex++ {
+			if finalizedCount[shardIndex] < GetJobConcurrency(job) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
 // if there are > X states then error
 func WaitDontExceedCount(count int) CheckStatesFunction {
 	return func(jobState executor.JobState) (bool, error) {
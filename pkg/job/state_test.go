@@ -0,0 +1,101 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStateResolver(state executor.JobStateType) *StateResolver {
+	resolver := NewStateResolver(
+		func(ctx context.Context, id string) (executor.Job, error) {
+			return executor.Job{
+				ID:   id,
+				Deal: executor.JobDeal{Concurrency: 1},
+			}, nil
+		},
+		func(ctx context.Context, id string) (executor.JobState, error) {
+			return executor.JobState{
+				Nodes: map[string]executor.JobNodeState{
+					"node-1": {
+						Shards: map[int]executor.JobShardState{
+							0: {NodeID: "node-1", ShardIndex: 0, State: state, ResultsAccepted: true},
+						},
+					},
+				},
+			}, nil
+		},
+	)
+	resolver.SetWaitTime(3, time.Millisecond)
+	return resolver
+}
+
+func TestWaitUntilComplete(t *testing.T) {
+	ctx := context.Background()
+
+	require.NoError(t, newTestStateResolver(executor.JobStateFinalized).WaitUntilComplete(ctx, "job-1"))
+
+	// the requester node has not finalized the shard yet so we keep
+	// waiting rather than giving up because nothing else will happen
+	err := newTestStateResolver(executor.JobStateComplete).WaitUntilComplete(ctx, "job-1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "max attempts reached")
+
+	err = newTestStateResolver(executor.JobStateError).WaitUntilComplete(ctx, "job-1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error state")
+}
+
+func TestWaitGivesUpOnTerminalStates(t *testing.T) {
+	err := newTestStateResolver(executor.JobStateCancelled).Wait(context.Background(), "job-1", 1,
+		WaitForJobStates(map[executor.JobStateType]int{
+			executor.JobStateFinalized: 1,
+		}),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "all jobs are in terminal states")
+}
+
+// results only count once the requester node has accepted them
+func TestGetResultsOnlyReturnsAcceptedResults(t *testing.T) {
+	ctx := context.Background()
+	shards := map[string]executor.JobShardState{
+		"complete": {NodeID: "complete", State: executor.JobStateComplete, ResultsID: "unverified"},
+		"rejected": {NodeID: "rejected", State: executor.JobStateFinalized, ResultsID: "rejected"},
+		"accepted": {NodeID: "accepted", State: executor.JobStateFinalized, ResultsID: "accepted", ResultsAccepted: true},
+	}
+	resolverWith := func(nodeIDs ...string) *StateResolver {
+		return NewStateResolver(
+			func(ctx context.Context, id string) (executor.Job, error) {
+				return executor.Job{ID: id}, nil
+			},
+			func(ctx context.Context, id string) (executor.JobState, error) {
+				jobState := executor.JobState{Nodes: map[string]executor.JobNodeState{}}
+				for _, nodeID := range nodeIDs {
+					jobState.Nodes[nodeID] = executor.JobNodeState{
+						Shards: map[int]executor.JobShardState{0: shards[nodeID]},
+					}
+				}
+				return jobState, nil
+			},
+		)
+	}
+
+	resolver := resolverWith("complete", "rejected")
+	_, err := resolver.GetResults(ctx, "job-1")
+	require.Error(t, err)
+	summary, err := resolver.ResultSummary(ctx, "job-1")
+	require.NoError(t, err)
+	require.Empty(t, summary)
+
+	resolver = resolverWith("complete", "rejected", "accepted")
+	results, err := resolver.GetResults(ctx, "job-1")
+	require.NoError(t, err)
+	require.Equal(t, []ResultsShard{{ShardIndex: 0, ResultsID: "accepted"}}, results)
+	summary, err = resolver.ResultSummary(ctx, "job-1")
+	require.NoError(t, err)
+	require.Equal(t, "/ipfs/accepted", summary)
+}
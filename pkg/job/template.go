@@ -0,0 +1,161 @@
+package job
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+)
+
+// what docker job arguments can refer to so that each shard of a sharded
+// job knows which slice of the inputs it has, e.g.
+// process --shard {{.ShardIndex}} --of {{.TotalShards}} {{join .InputPaths " "}}
+// jobs have to ask for this (JobSpecDocker.TemplateArgs) so that arguments
+// that happen to contain {{ are left alone
+type ShardTemplateData struct {
+	JobID       string
+	ShardIndex  int
+	TotalShards int
+	// where the shard's inputs are mounted in the container
+	InputPaths []string
+}
+
+func NewShardTemplateData(jobID string, shardIndex, totalShards int, shard []storage.StorageSpec) ShardTemplateData {
+	inputPaths := []string{}
+	for _, input := range shard {
+		inputPaths = append(inputPaths, input.Path)
+	}
+	// a job with no inputs to shard is still one shard
+	if totalShards == 0 {
+		totalShards = 1
+	}
+	return ShardTemplateData{
+		JobID:       jobID,
+		ShardIndex:  shardIndex,
+		TotalShards: totalShards,
+		InputPaths:  inputPaths,
+	}
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+func parseArgTemplate(arg string) (*template.Template, error) {
+	tmpl, err := template.New("arg").Funcs(templateFuncs).Option("missingkey=error").Parse(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template in argument '%s': %w", arg, err)
+	}
+	return tmpl, nil
+}
+
+// check the arguments will expand before we submit the job rather than
+// finding out when it runs - we don't know what the shards will have yet
+// (e.g. how many input paths) so we only check the syntax and that the
+// fields they refer to exist
+func ValidateArgTemplates(args []string) error {
+	for _, arg := range args {
+		tmpl, err := parseArgTemplate(arg)
+		if err != nil {
+			return err
+		}
+		err = checkTemplateFields(tmpl.Tree.Root, true)
+		if err != nil {
+			return fmt.Errorf("invalid template in argument '%s': %w", arg, err)
+		}
+	}
+	return nil
+}
+
+// dotIsData is false inside range and with where . is something else
+func checkTemplateFields(node parse.Node, dotIsData bool) error {
+	checkField := func(name string) error {
+		if _, ok := reflect.TypeOf(ShardTemplateData{}).FieldByName(name); !ok {
+			return fmt.Errorf("there is no field %s", name)
+		}
+		return nil
+	}
+	checkAll := func(dotIsData bool, nodes ...parse.Node) error {
+		for _, child := range nodes {
+			if err := checkTemplateFields(child, dotIsData); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		return checkAll(dotIsData, n.Nodes...)
+	case *parse.ActionNode:
+		return checkTemplateFields(n.Pipe, dotIsData)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkAll(dotIsData, cmd.Args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.ChainNode:
+		return checkTemplateFields(n.Node, dotIsData)
+	case *parse.FieldNode:
+		if !dotIsData {
+			return nil
+		}
+		return checkField(n.Ident[0])
+	case *parse.VariableNode:
+		if n.Ident[0] != "$" || len(n.Ident) < 2 {
+			return nil
+		}
+		return checkField(n.Ident[1])
+	case *parse.IfNode:
+		return checkAll(dotIsData, n.Pipe, n.List, n.ElseList)
+	case *parse.RangeNode:
+		if err := checkAll(dotIsData, n.Pipe, n.ElseList); err != nil {
+			return err
+		}
+		return checkTemplateFields(n.List, false)
+	case *parse.WithNode:
+		if err := checkAll(dotIsData, n.Pipe, n.ElseList); err != nil {
+			return err
+		}
+		return checkTemplateFields(n.List, false)
+	}
+	return nil
+}
+
+// fill in the shard's values in each argument - arguments without {{ are
+// left alone
+func ExpandArgTemplates(args []string, data ShardTemplateData) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
+	}
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.Contains(arg, "{{") {
+			expanded = append(expanded, arg)
+			continue
+		}
+		tmpl, err := parseArgTemplate(arg)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, data)
+		if err != nil {
+			return nil, fmt.Errorf("error expanding argument '%s': %w", arg, err)
+		}
+		expanded = append(expanded, buf.String())
+	}
+	return expanded, nil
+}
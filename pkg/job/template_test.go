@@ -0,0 +1,73 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandArgTemplates(t *testing.T) {
+	data := NewShardTemplateData("job-1", 2, 5, []storage.StorageSpec{
+		{Path: "/inputs/a.jpg"},
+		{Path: "/inputs/b.jpg"},
+	})
+
+	expanded, err := ExpandArgTemplates([]string{
+		"process",
+		"--job={{.JobID}}",
+		"--shard={{.ShardIndex}}/{{.TotalShards}}",
+		`{{join .InputPaths " "}}`,
+		"{{range .InputPaths}}[{{.}}]{{end}}",
+		"$HOME",
+	}, data)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"process",
+		"--job=job-1",
+		"--shard=2/5",
+		"/inputs/a.jpg /inputs/b.jpg",
+		"[/inputs/a.jpg][/inputs/b.jpg]",
+		"$HOME",
+	}, expanded)
+}
+
+func TestExpandArgTemplatesUnsharded(t *testing.T) {
+	// jobs without sharded inputs are one shard with no input paths
+	data := NewShardTemplateData("job-1", 0, 0, nil)
+	expanded, err := ExpandArgTemplates([]string{"{{.ShardIndex}} of {{.TotalShards}}: {{len .InputPaths}}"}, data)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0 of 1: 0"}, expanded)
+
+	expanded, err = ExpandArgTemplates(nil, data)
+	require.NoError(t, err)
+	require.Empty(t, expanded)
+}
+
+func TestExpandArgTemplatesErrors(t *testing.T) {
+	data := NewShardTemplateData("job-1", 0, 1, nil)
+	for _, arg := range []string{"{{.ShardIndex", "{{.Nope}}", "{{nope .InputPaths}}"} {
+		_, err := ExpandArgTemplates([]string{arg}, data)
+		require.Error(t, err, arg)
+		require.Error(t, ValidateArgTemplates([]string{arg}), arg)
+	}
+}
+
+func TestValidateArgTemplates(t *testing.T) {
+	// we don't know how many input paths the shards will have
+	for _, arg := range []string{
+		"{{index .InputPaths 0}}",
+		"{{range .InputPaths}}{{.Path}}{{end}}",
+		"{{with .InputPaths}}{{.Anything}} {{$.JobID}}{{end}}",
+		"{{if .InputPaths}}{{.ShardIndex}}{{else}}{{.TotalShards}}{{end}}",
+	} {
+		require.NoError(t, ValidateArgTemplates([]string{arg}), arg)
+	}
+	for _, arg := range []string{
+		"{{range .InputPaths}}{{$.Nope}}{{end}}",
+		"{{if .Nope}}x{{end}}",
+		"{{len (.Nope)}}",
+	} {
+		require.Error(t, ValidateArgTemplates([]string{arg}), arg)
+	}
+}
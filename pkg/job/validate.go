@@ -3,6 +3,7 @@ package job
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 
 	"github.com/filecoin-project/bacalhau/pkg/executor"
 )
@@ -16,5 +17,50 @@ func VerifyJob(spec executor.JobSpec, deal executor.JobDeal) error {
 		return fmt.Errorf("job spec is empty")
 	}
 
+	if spec.NodeSelector != "" {
+		if _, err := ParseNodeSelector(spec.NodeSelector); err != nil {
+			return err
+		}
+	}
+
+	if spec.Engine == executor.EngineDocker && spec.Docker.TemplateArgs {
+		if err := ValidateArgTemplates(spec.Docker.Entrypoint); err != nil {
+			return err
+		}
+		if err := ValidateArgTemplates(spec.Docker.Cmd); err != nil {
+			return err
+		}
+	}
+
+	return ValidateNetwork(spec.Network)
+}
+
+// example.com or *.example.com for it and all of its subdomains
+var domainRegex = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// top level domains are never all digits so this
+// catches IP addresses (e.g. 169.254.169.254)
+var numericTLDRegex = regexp.MustCompile(`(^|\.)[0-9]+$`)
+
+// compute nodes check this again before they run the job as the domains
+// end up in the config of the proxy the job's requests go through
+func ValidateNetwork(network executor.JobSpecNetwork) error {
+	if network.Type != executor.NetworkHTTP {
+		if len(network.Domains) > 0 {
+			return fmt.Errorf("domains can only be given for http networking")
+		}
+		return nil
+	}
+	if len(network.Domains) == 0 {
+		return fmt.Errorf("http networking needs at least one domain")
+	}
+	for _, domain := range network.Domains {
+		if !domainRegex.MatchString(domain) {
+			return fmt.Errorf("invalid domain: '%s'", domain)
+		}
+		if numericTLDRegex.MatchString(domain) {
+			return fmt.Errorf("IP addresses are not allowed as domains: '%s'", domain)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,65 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyJobNetwork(t *testing.T) {
+	tests := []struct {
+		network executor.JobSpecNetwork
+		valid   bool
+	}{
+		{executor.JobSpecNetwork{}, true},
+		{executor.JobSpecNetwork{Type: executor.NetworkFull}, true},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP, Domains: []string{"example.com", "*.pypi.org"}}, true},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP}, false},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP, Domains: []string{"http://example.com"}}, false},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP, Domains: []string{"example.*"}}, false},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP, Domains: []string{"a.com\nhttp_access allow all"}}, false},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP, Domains: []string{"169.254.169.254"}}, false},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP, Domains: []string{"*.0.0.127"}}, false},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP, Domains: []string{"2130706433"}}, false},
+		{executor.JobSpecNetwork{Type: executor.NetworkHTTP, Domains: []string{"1password.com"}}, true},
+		{executor.JobSpecNetwork{Type: executor.NetworkNone, Domains: []string{"example.com"}}, false},
+	}
+	for _, test := range tests {
+		err := VerifyJob(executor.JobSpec{Engine: executor.EngineDocker, Network: test.network}, executor.JobDeal{Concurrency: 1})
+		if test.valid {
+			require.NoError(t, err, test.network)
+		} else {
+			require.Error(t, err, test.network)
+		}
+	}
+}
+
+func TestVerifyJobArgTemplates(t *testing.T) {
+	tests := []struct {
+		docker executor.JobSpecDocker
+		valid  bool
+	}{
+		{executor.JobSpecDocker{Entrypoint: []string{"echo", "{{.ShardIndex}}"}}, true},
+		{executor.JobSpecDocker{Cmd: []string{`{{join .InputPaths ","}}`}}, true},
+		{executor.JobSpecDocker{Cmd: []string{"{{index .InputPaths 0}}"}}, true},
+		{executor.JobSpecDocker{Entrypoint: []string{"echo", "{{.ShardIndex"}}, false},
+		{executor.JobSpecDocker{Cmd: []string{"{{.Shard}}"}}, false},
+	}
+	for _, test := range tests {
+		test.docker.TemplateArgs = true
+		err := VerifyJob(executor.JobSpec{Engine: executor.EngineDocker, Docker: test.docker}, executor.JobDeal{Concurrency: 1})
+		if test.valid {
+			require.NoError(t, err, test.docker)
+		} else {
+			require.Error(t, err, test.docker)
+		}
+	}
+
+	// jobs that don't ask for templating can have {{ in their arguments
+	err := VerifyJob(executor.JobSpec{
+		Engine: executor.EngineDocker,
+		Docker: executor.JobSpecDocker{Cmd: []string{"{{.Shard"}},
+	}, executor.JobDeal{Concurrency: 1})
+	require.NoError(t, err)
+}
@@ -144,6 +144,20 @@ func (d *InMemoryDatastore) UpdateJobDeal(ctx context.Context, jobID string, dea
 	return nil
 }
 
+func (d *InMemoryDatastore) DeleteJob(ctx context.Context, jobID string) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	_, ok := d.jobs[jobID]
+	if !ok {
+		return fmt.Errorf("no job found: %s", jobID)
+	}
+	delete(d.jobs, jobID)
+	delete(d.states, jobID)
+	delete(d.events, jobID)
+	delete(d.localEvents, jobID)
+	return nil
+}
+
 func (d *InMemoryDatastore) GetJobState(ctx context.Context, jobID string) (executor.JobState, error) {
 	d.mtx.RLock()
 	defer d.mtx.RUnlock()
@@ -208,6 +222,18 @@ func (d *InMemoryDatastore) UpdateShardState(
 		shardSate.ResultsID = update.ResultsID
 	}
 
+	if update.ResultsHash != "" {
+		shardSate.ResultsHash = update.ResultsHash
+	}
+
+	if update.State == executor.JobStateFinalized {
+		shardSate.ResultsAccepted = update.ResultsAccepted
+	}
+
+	if !update.FinishedAt.IsZero() {
+		shardSate.FinishedAt = update.FinishedAt
+	}
+
 	nodeState.Shards[shardIndex] = shardSate
 	jobState.Nodes[nodeID] = nodeState
 	d.states[jobID] = jobState
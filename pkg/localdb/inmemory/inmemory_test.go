@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/localdb"
 	_ "github.com/filecoin-project/bacalhau/pkg/logger"
 	"github.com/stretchr/testify/require"
 )
@@ -75,3 +76,36 @@ func TestInMemoryDataStore(t *testing.T) {
 	require.Equal(t, executor.JobStateBidding, shardState.State)
 	require.Equal(t, "hello", shardState.Status)
 }
+
+func TestInMemoryDataStoreDeleteJob(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewInMemoryDatastore()
+	require.NoError(t, err)
+
+	for _, id := range []string{"123", "456"} {
+		err = store.AddJob(ctx, executor.Job{ID: id})
+		require.NoError(t, err)
+		err = store.AddEvent(ctx, id, executor.JobEvent{JobID: id, EventName: executor.JobEventBid})
+		require.NoError(t, err)
+		err = store.UpdateShardState(ctx, id, "node", 0, executor.JobShardState{State: executor.JobStateBidding})
+		require.NoError(t, err)
+	}
+
+	err = store.DeleteJob(ctx, "123")
+	require.NoError(t, err)
+
+	_, err = store.GetJob(ctx, "123")
+	require.Error(t, err)
+	_, err = store.GetJobState(ctx, "123")
+	require.Error(t, err)
+	_, err = store.GetJobEvents(ctx, "123")
+	require.Error(t, err)
+	err = store.DeleteJob(ctx, "123")
+	require.Error(t, err)
+
+	// other jobs are left alone
+	jobs, err := store.GetJobs(ctx, localdb.JobQuery{})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "456", jobs[0].ID)
+}
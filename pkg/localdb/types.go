@@ -27,6 +27,8 @@ type LocalDB interface {
 	AddEvent(ctx context.Context, jobID string, event executor.JobEvent) error
 	AddLocalEvent(ctx context.Context, jobID string, event executor.JobLocalEvent) error
 	UpdateJobDeal(ctx context.Context, jobID string, deal executor.JobDeal) error
+	// forget everything about the job - used to garbage collect old jobs
+	DeleteJob(ctx context.Context, jobID string) error
 	UpdateShardState(
 		ctx context.Context,
 		jobID, nodeID string,
@@ -13,6 +13,7 @@ import (
 
 	"github.com/filecoin-project/bacalhau/pkg/executor"
 	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/reputation"
 	"github.com/filecoin-project/bacalhau/pkg/storage"
 	"github.com/filecoin-project/bacalhau/pkg/system"
 	"github.com/rs/zerolog/log"
@@ -186,6 +187,26 @@ func (apiClient *APIClient) GetResults(ctx context.Context, jobID string) (resul
 	return res.Results, nil
 }
 
+// GetCombinedResults asks the requester node to merge the results of every
+// shard of the job into one, so the whole job can be used as a single input.
+func (apiClient *APIClient) GetCombinedResults(ctx context.Context, jobID string) (results storage.StorageSpec, err error) {
+	if jobID == "" {
+		return storage.StorageSpec{}, fmt.Errorf("jobID must be non-empty in a GetCombinedResults call")
+	}
+
+	req := combinedResultsRequest{
+		ClientID: system.GetClientID(),
+		JobID:    jobID,
+	}
+
+	var res combinedResultsResponse
+	if err := apiClient.post(ctx, "combined_results", req, &res); err != nil {
+		return storage.StorageSpec{}, err
+	}
+
+	return res.Results, nil
+}
+
 // Submit submits a new job to the node's transport.
 func (apiClient *APIClient) Submit(
 	ctx context.Context,
@@ -241,6 +262,20 @@ func (apiClient *APIClient) Version(ctx context.Context) (*executor.VersionInfo,
 	return res.VersionInfo, nil
 }
 
+// Nodes returns the reputation the requester node has recorded for each compute node.
+func (apiClient *APIClient) Nodes(ctx context.Context) ([]reputation.NodeReputation, error) {
+	req := nodesRequest{
+		ClientID: system.GetClientID(),
+	}
+
+	var res nodesResponse
+	if err := apiClient.post(ctx, "nodes", req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Nodes, nil
+}
+
 func (apiClient *APIClient) post(ctx context.Context, api string, reqData, resData interface{}) error {
 	var body bytes.Buffer
 	if err := json.NewEncoder(&body).Encode(reqData); err != nil {
@@ -32,3 +32,27 @@ func TestGet(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, job2.ID, job.ID)
 }
+
+func TestNodes(t *testing.T) {
+	c, cm := SetupTests(t)
+	defer cm.Cleanup()
+
+	// no jobs have run so the requester node has no opinion on anyone yet
+	nodes, err := c.Nodes(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+}
+
+func TestGetCombinedResults(t *testing.T) {
+	c, cm := SetupTests(t)
+	defer cm.Cleanup()
+
+	spec, deal := MakeGenericJob()
+	job, err := c.Submit(context.Background(), spec, deal, nil)
+	require.NoError(t, err)
+
+	// the noop verifier has nothing to combine
+	_, err = c.GetCombinedResults(context.Background(), job.ID)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot combine results")
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -23,10 +24,13 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/executor"
 	"github.com/filecoin-project/bacalhau/pkg/job"
 	"github.com/filecoin-project/bacalhau/pkg/localdb"
+	"github.com/filecoin-project/bacalhau/pkg/reputation"
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
 	"github.com/filecoin-project/bacalhau/pkg/storage"
 	"github.com/filecoin-project/bacalhau/pkg/system"
 	"github.com/filecoin-project/bacalhau/pkg/transport/libp2p"
 	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/localfs"
 	"github.com/filecoin-project/bacalhau/pkg/version"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
@@ -47,6 +51,8 @@ const ServerReadHeaderTimeout = 10 * time.Second
 type APIServer struct {
 	Controller  *controller.Controller
 	Verifiers   map[verifier.VerifierType]verifier.Verifier
+	Reputation  *reputation.Store
+	Quotas      *requesternode.QuotaManager
 	Host        string
 	Port        int
 	componentMu sync.Mutex
@@ -58,10 +64,14 @@ func NewServer(
 	port int,
 	c *controller.Controller,
 	verifiers map[verifier.VerifierType]verifier.Verifier,
+	reputationStore *reputation.Store,
+	quotas *requesternode.QuotaManager,
 ) *APIServer {
 	a := &APIServer{
 		Controller: c,
 		Verifiers:  verifiers,
+		Reputation: reputationStore,
+		Quotas:     quotas,
 		Host:       host,
 		Port:       port,
 	}
@@ -87,12 +97,14 @@ func (apiServer *APIServer) ListenAndServe(ctx context.Context, cm *system.Clean
 	sm.Handle("/list", instrument("list", apiServer.list))
 	sm.Handle("/states", instrument("states", apiServer.states))
 	sm.Handle("/results", instrument("results", apiServer.results))
+	sm.Handle("/combined_results", instrument("combined_results", apiServer.combinedResults))
 	sm.Handle("/events", instrument("events", apiServer.events))
 	sm.Handle("/local_events", instrument("local_events", apiServer.localEvents))
 	sm.Handle("/id", instrument("id", apiServer.id))
 	sm.Handle("/peers", instrument("peers", apiServer.peers))
 	sm.Handle("/submit", instrument("submit", apiServer.submit))
 	sm.Handle("/version", instrument("version", apiServer.version))
+	sm.Handle("/nodes", instrument("nodes", apiServer.nodes))
 	sm.Handle("/healthz", instrument("healthz", apiServer.healthz))
 	sm.Handle("/logz", instrument("logz", apiServer.logz))
 	sm.Handle("/varz", instrument("varz", apiServer.varz))
@@ -100,6 +112,11 @@ func (apiServer *APIServer) ListenAndServe(ctx context.Context, cm *system.Clean
 	sm.Handle("/readyz", instrument("readyz", apiServer.readyz))
 	sm.Handle("/metrics", promhttp.Handler())
 
+	// the localfs verifier serves the results it keeps from here
+	if handler, ok := apiServer.Verifiers[verifier.VerifierLocalFS].(http.Handler); ok {
+		sm.Handle(localfs.URLPath, instrument("localfs", handler.ServeHTTP))
+	}
+
 	srv := http.Server{
 		Handler:           sm,
 		Addr:              fmt.Sprintf("%s:%d", apiServer.Host, apiServer.Port),
@@ -150,6 +167,15 @@ type resultsResponse struct {
 	Results []storage.StorageSpec `json:"results"`
 }
 
+type combinedResultsRequest struct {
+	ClientID string `json:"client_id"`
+	JobID    string `json:"job_id"`
+}
+
+type combinedResultsResponse struct {
+	Results storage.StorageSpec `json:"results"`
+}
+
 type eventsRequest struct {
 	ClientID string `json:"client_id"`
 	JobID    string `json:"job_id"`
@@ -176,6 +202,14 @@ type versionResponse struct {
 	VersionInfo *executor.VersionInfo `json:"version_info"`
 }
 
+type nodesRequest struct {
+	ClientID string `json:"client_id"`
+}
+
+type nodesResponse struct {
+	Nodes []reputation.NodeReputation `json:"nodes"`
+}
+
 func (apiServer *APIServer) id(res http.ResponseWriter, req *http.Request) {
 	switch apiTransport := apiServer.Controller.GetTransport().(type) { //nolint:gocritic
 	case *libp2p.LibP2PTransport:
@@ -303,6 +337,73 @@ func (apiServer *APIServer) results(res http.ResponseWriter, req *http.Request)
 	}
 }
 
+func (apiServer *APIServer) combinedResults(res http.ResponseWriter, req *http.Request) {
+	var combinedReq combinedResultsRequest
+	if err := json.NewDecoder(req.Body).Decode(&combinedReq); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := apiServer.Controller.GetJob(req.Context(), combinedReq.JobID)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	v, err := apiServer.getVerifier(req.Context(), job.Spec.Verifier)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	combiner, ok := v.(verifier.ResultsCombiner)
+	if !ok {
+		http.Error(res, fmt.Sprintf("the %s verifier cannot combine results", job.Spec.Verifier), http.StatusBadRequest)
+		return
+	}
+
+	results, err := combiner.CombineJobResults(req.Context(), combinedReq.JobID)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// the combined results are pinned here so we unpin them with the job
+	err = apiServer.Controller.RecordCombinedResults(req.Context(), combinedReq.JobID, results.Cid)
+	if err != nil {
+		log.Warn().Msgf("error recording combined results for job %s: %s", combinedReq.JobID, err)
+	}
+	res.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(res).Encode(combinedResultsResponse{
+		Results: results,
+	})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (apiServer *APIServer) nodes(res http.ResponseWriter, req *http.Request) {
+	var nodesReq nodesRequest
+	if err := json.NewDecoder(req.Body).Decode(&nodesReq); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nodes := []reputation.NodeReputation{}
+	if apiServer.Reputation != nil {
+		nodes = apiServer.Reputation.GetNodes()
+	}
+
+	res.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(res).Encode(nodesResponse{
+		Nodes: nodes,
+	})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 //nolint:dupl
 func (apiServer *APIServer) events(res http.ResponseWriter, req *http.Request) {
 	var eventsReq eventsRequest
@@ -403,9 +504,24 @@ func (apiServer *APIServer) submit(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// the quotas are checked before we do any work for the job - if we
+	// don't get as far as submitting it the reservation is released
+	// (releasing a confirmed reservation does nothing)
+	var reservation *requesternode.QuotaReservation
+	if apiServer.Quotas != nil {
+		var err error
+		reservation, err = apiServer.Quotas.Reserve(
+			req.Context(), submitReq.Data.ClientID, submitReq.Data.Spec, submitReq.Data.Deal)
+		if err != nil {
+			writeQuotaError(res, err)
+			return
+		}
+		defer apiServer.Quotas.Release(reservation)
+	}
+
 	// If we have a build context, pin it to IPFS and mount it in the job:
 	if submitReq.Data.Context != "" {
-		// TODO: gc pinned contexts
+		// the requester node unpins this once the job is garbage collected
 		decoded, err := base64.StdEncoding.DecodeString(submitReq.Data.Context)
 		if err != nil {
 			log.Debug().Msgf("====> DecodeContext error: %s", err)
@@ -453,6 +569,9 @@ func (apiServer *APIServer) submit(res http.ResponseWriter, req *http.Request) {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if reservation != nil {
+		apiServer.Quotas.Confirm(reservation, j.ID)
+	}
 
 	res.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(res).Encode(submitResponse{
@@ -464,6 +583,19 @@ func (apiServer *APIServer) submit(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// clients over a quota get a 429 and, if we know it, when to try again
+func writeQuotaError(res http.ResponseWriter, err error) {
+	var quotaErr *requesternode.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if quotaErr.RetryAfter > 0 {
+		res.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(quotaErr.RetryAfter.Seconds()))))
+	}
+	http.Error(res, quotaErr.Error(), http.StatusTooManyRequests)
+}
+
 func (apiServer *APIServer) getVerifier(ctx context.Context, typ verifier.VerifierType) (verifier.Verifier, error) {
 	apiServer.componentMu.Lock()
 	defer apiServer.componentMu.Unlock()
@@ -4,12 +4,26 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
 	"github.com/filecoin-project/bacalhau/pkg/types"
 	"github.com/rs/zerolog/log"
 )
 
 var LINESOFLOGTOPRINT = 100
 
+// Struct to report for VarZ - the requester node's quotas live here
+// rather than in types.VarZ so that package doesn't depend on it
+type VarZ struct {
+	types.VarZ
+	Quotas *QuotaVarZ `json:"quotas,omitempty"`
+}
+
+// the requester node's client quotas and how each client is doing against them
+type QuotaVarZ struct {
+	Config  requesternode.QuotaConfig        `json:"config"`
+	Clients []requesternode.ClientQuotaUsage `json:"clients"`
+}
+
 func GenerateHealthData() types.HealthInfo {
 	var healthInfo types.HealthInfo
 
@@ -89,11 +103,24 @@ func (apiServer *APIServer) healthz(res http.ResponseWriter, req *http.Request)
 }
 
 func (apiServer *APIServer) varz(res http.ResponseWriter, req *http.Request) {
-	// TODO: Fill in with the configuration settings for this node
-	res.WriteHeader(http.StatusOK)
-	res.Header().Add("Content-Type", "application/json")
+	// TODO: Fill in with the rest of the configuration settings for this node
+	var varz VarZ
+	if apiServer.Quotas != nil {
+		varz.Quotas = &QuotaVarZ{
+			Config:  apiServer.Quotas.GetConfig(),
+			Clients: apiServer.Quotas.GetUsage(req.Context()),
+		}
+	}
 
-	_, err := res.Write([]byte("{}"))
+	varzJSONBlob, err := json.Marshal(varz)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Add("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	_, err = res.Write(varzJSONBlob)
 	if err != nil {
 		log.Warn().Msg("Error writing body for varz request.")
 	}
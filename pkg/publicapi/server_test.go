@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
 	"github.com/filecoin-project/bacalhau/pkg/types"
 	"github.com/filecoin-project/bacalhau/pkg/verifier"
 	"github.com/stretchr/testify/require"
@@ -135,3 +136,35 @@ func testEndpoint(t *testing.T, endpoint string, contentToCheck string) []byte {
 	require.Contains(t, string(body), contentToCheck, "%s body does not contain '%s'.", endpoint, contentToCheck)
 	return body
 }
+
+func (suite *ServerSuite) TestSubmitOverQuota() {
+	ctx := context.Background()
+	c, cm := SetupTestsWithConfig(suite.T(), requesternode.RequesterNodeConfig{
+		Quotas: requesternode.QuotaConfig{
+			JobsPerHour: 1,
+		},
+	})
+	defer cm.Cleanup()
+
+	spec, deal := MakeGenericJob()
+	_, err := c.Submit(ctx, spec, deal, nil)
+	require.NoError(suite.T(), err)
+
+	_, err = c.Submit(ctx, spec, deal, nil)
+	require.Error(suite.T(), err)
+	require.Contains(suite.T(), err.Error(), "429")
+	require.Contains(suite.T(), err.Error(), string(requesternode.QuotaJobsPerHour))
+
+	res, err := http.Get(c.BaseURI + "/varz")
+	require.NoError(suite.T(), err)
+	defer res.Body.Close()
+
+	var varZ VarZ
+	err = json.NewDecoder(res.Body).Decode(&varZ)
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), varZ.Quotas)
+	require.Equal(suite.T(), 1, varZ.Quotas.Config.JobsPerHour)
+	require.Len(suite.T(), varZ.Quotas.Clients, 1)
+	require.Equal(suite.T(), 1, varZ.Quotas.Clients[0].Admitted)
+	require.Equal(suite.T(), 1, varZ.Quotas.Clients[0].Rejected)
+}
@@ -30,6 +30,14 @@ const TimeToWaitForHealthy = 50
 
 // SetupTests sets up a client for a requester node's API server, for testing.
 func SetupTests(t *testing.T) (*APIClient, *system.CleanupManager) {
+	return SetupTestsWithConfig(t, requesternode.RequesterNodeConfig{})
+}
+
+// SetupTestsWithConfig is SetupTests for a requester node with the given config.
+func SetupTestsWithConfig(
+	t *testing.T,
+	config requesternode.RequesterNodeConfig, //nolint:gocritic
+) (*APIClient, *system.CleanupManager) {
 	system.InitConfigForTesting(t)
 
 	cleanupManager := system.NewCleanupManager()
@@ -55,11 +63,11 @@ func SetupTests(t *testing.T) (*APIClient, *system.CleanupManager) {
 	)
 	require.NoError(t, err)
 
-	_, err = requesternode.NewRequesterNode(
+	requesterNode, err := requesternode.NewRequesterNode(
 		cleanupManager,
 		c,
 		noopVerifiers,
-		requesternode.RequesterNodeConfig{},
+		config,
 	)
 	require.NoError(t, err)
 
@@ -67,7 +75,7 @@ func SetupTests(t *testing.T) (*APIClient, *system.CleanupManager) {
 	port, err := freeport.GetFreePort()
 	require.NoError(t, err)
 
-	s := NewServer(host, port, c, noopVerifiers)
+	s := NewServer(host, port, c, noopVerifiers, requesterNode.GetReputationStore(), requesterNode.GetQuotaManager())
 	cl := NewAPIClient(s.GetURI())
 	go func() {
 		require.NoError(t, s.ListenAndServe(context.Background(), cleanupManager))
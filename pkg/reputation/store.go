@@ -0,0 +1,124 @@
+package reputation
+
+import (
+	"sort"
+	"time"
+
+	sync "github.com/lukemarsden/golang-mutex-tracer"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+)
+
+// the score of a node we know nothing about
+const DefaultScore = 0.5
+
+// what a requester node remembers about how a compute node has done
+// on the shards it has been given
+type NodeReputation struct {
+	NodeID string `json:"node_id"`
+	// shards the node finished running
+	Completed int `json:"completed"`
+	// shards the node reported an error for
+	Errored int `json:"errored"`
+	// shards the node did not finish in time
+	TimedOut int `json:"timed_out"`
+	// shards whose results the verifier accepted
+	Accepted int `json:"accepted"`
+	// shards whose results the verifier rejected
+	Rejected int `json:"rejected"`
+	// between 0 and 1 - higher is better
+	Score float64 `json:"score"`
+}
+
+// we start every node at 0.5 and move towards the fraction of shards
+// whose results were accepted - errors, timeouts and rejected results
+// all count against the node
+func (r NodeReputation) getScore() float64 {
+	good := float64(r.Accepted)
+	bad := float64(r.Errored + r.TimedOut + r.Rejected)
+	return (good + 1) / (good + bad + 2) //nolint:gomnd
+}
+
+type Store struct {
+	nodes map[string]*NodeReputation
+	mtx   sync.RWMutex
+}
+
+func NewStore() *Store {
+	store := &Store{
+		nodes: map[string]*NodeReputation{},
+	}
+	store.mtx.EnableTracerWithOpts(sync.Opts{
+		Threshold: 10 * time.Millisecond,
+		Id:        "ReputationStore.mtx",
+	})
+	return store
+}
+
+// record the outcome of a shard the node was running
+// states that are not an outcome (e.g. running) are ignored
+func (store *Store) RecordShardState(shardState executor.JobShardState) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+
+	node := store.getNode(shardState.NodeID)
+	switch shardState.State {
+	case executor.JobStateComplete:
+		node.Completed++
+	case executor.JobStateError:
+		node.Errored++
+	case executor.JobStateFinalized:
+		if shardState.ResultsAccepted {
+			node.Accepted++
+		} else {
+			node.Rejected++
+		}
+	}
+}
+
+func (store *Store) RecordTimeout(nodeID string) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	store.getNode(nodeID).TimedOut++
+}
+
+func (store *Store) GetScore(nodeID string) float64 {
+	store.mtx.RLock()
+	defer store.mtx.RUnlock()
+	node, ok := store.nodes[nodeID]
+	if !ok {
+		return DefaultScore
+	}
+	return node.getScore()
+}
+
+// all the nodes we know about - best first
+func (store *Store) GetNodes() []NodeReputation {
+	store.mtx.RLock()
+	defer store.mtx.RUnlock()
+	nodes := []NodeReputation{}
+	for _, node := range store.nodes {
+		reputation := *node
+		reputation.Score = node.getScore()
+		nodes = append(nodes, reputation)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Score != nodes[j].Score {
+			return nodes[i].Score > nodes[j].Score
+		}
+		return nodes[i].NodeID < nodes[j].NodeID
+	})
+	return nodes
+}
+
+// must be called with the mutex held
+func (store *Store) getNode(nodeID string) *NodeReputation {
+	node, ok := store.nodes[nodeID]
+	if !ok {
+		node = &NodeReputation{
+			NodeID: nodeID,
+		}
+		store.nodes[nodeID] = node
+	}
+	return node
+}
@@ -0,0 +1,55 @@
+package reputation
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore()
+	require.Equal(t, DefaultScore, store.GetScore("unknown"))
+
+	record := func(nodeID string, state executor.JobStateType, accepted bool) {
+		store.RecordShardState(executor.JobShardState{
+			NodeID:          nodeID,
+			State:           state,
+			ResultsAccepted: accepted,
+		})
+	}
+
+	// good runs and has its results accepted
+	record("good", executor.JobStateComplete, false)
+	record("good", executor.JobStateFinalized, true)
+
+	// bad errors on one shard and has its results rejected on another
+	record("bad", executor.JobStateError, false)
+	record("bad", executor.JobStateComplete, false)
+	record("bad", executor.JobStateFinalized, false)
+
+	// slow never finishes
+	store.RecordTimeout("slow")
+
+	// states that are not an outcome don't count for anything
+	record("running", executor.JobStateRunning, false)
+
+	require.Greater(t, store.GetScore("good"), DefaultScore)
+	require.Less(t, store.GetScore("bad"), store.GetScore("slow"))
+	require.Less(t, store.GetScore("slow"), DefaultScore)
+	require.Equal(t, DefaultScore, store.GetScore("running"))
+
+	nodes := store.GetNodes()
+	nodeIDs := []string{}
+	for _, node := range nodes {
+		nodeIDs = append(nodeIDs, node.NodeID)
+	}
+	require.Equal(t, []string{"good", "running", "slow", "bad"}, nodeIDs)
+	require.Equal(t, NodeReputation{
+		NodeID:    "bad",
+		Completed: 1,
+		Errored:   1,
+		Rejected:  1,
+		Score:     store.GetScore("bad"),
+	}, nodes[3])
+}
@@ -0,0 +1,123 @@
+package requesternode
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/reputation"
+)
+
+// decides which of the bids collected for a job shard we would rather accept
+type BidStrategy interface {
+	// return the bids best first - bids that tie keep the order they arrived in
+	RankBids(bids []executor.JobEvent, reputationStore *reputation.Store) []executor.JobEvent
+}
+
+type BidStrategyType string
+
+const (
+	// the cheapest bids first
+	BidStrategyPrice BidStrategyType = "price"
+	// bids from the nodes with the best reputation first
+	BidStrategyReputation BidStrategyType = "reputation"
+	// bids from the nodes that already have most of the job's inputs (and
+	// its image) first
+	BidStrategyLocality BidStrategyType = "locality"
+	// bids from the least busy nodes first
+	BidStrategyLoad BidStrategyType = "load"
+)
+
+// the order we break ties in - whatever the strategy cares about
+// most goes first and the soonest to start always comes last
+var bidStrategyTypes = []BidStrategyType{
+	BidStrategyPrice,
+	BidStrategyReputation,
+	BidStrategyLocality,
+	BidStrategyLoad,
+}
+
+// lower is better
+type bidCost func(bid executor.JobEvent, reputationStore *reputation.Store) float64
+
+var bidCosts = map[BidStrategyType]bidCost{
+	BidStrategyPrice: func(bid executor.JobEvent, reputationStore *reputation.Store) float64 {
+		// a node that did not name a price could ask anything so
+		// it comes after every node that did
+		if bid.JobBid.Price == 0 {
+			return math.Inf(1)
+		}
+		return bid.JobBid.Price
+	},
+	BidStrategyReputation: func(bid executor.JobEvent, reputationStore *reputation.Store) float64 {
+		return -reputationStore.GetScore(bid.SourceNodeID)
+	},
+	BidStrategyLocality: func(bid executor.JobEvent, reputationStore *reputation.Store) float64 {
+		// not having to pull the image counts as much as having an input
+		local := bid.JobBid.LocalInputs
+		if bid.JobBid.HasImage {
+			local++
+		}
+		return -float64(local)
+	},
+	BidStrategyLoad: func(bid executor.JobEvent, reputationStore *reputation.Store) float64 {
+		return bid.JobBid.Load
+	},
+}
+
+func etaBidCost(bid executor.JobEvent, reputationStore *reputation.Store) float64 {
+	return float64(bid.JobBid.ETASeconds)
+}
+
+func ParseBidStrategyType(str string) (BidStrategyType, error) {
+	for _, typ := range bidStrategyTypes {
+		if string(typ) == str {
+			return typ, nil
+		}
+	}
+	return "", fmt.Errorf("requesternode: unknown bid strategy '%s' - must be one of %v", str, bidStrategyTypes)
+}
+
+// compares bids on what the given strategy cares about
+// and then on everything else in turn
+type costBidStrategy struct {
+	costs []bidCost
+}
+
+func NewBidStrategy(typ BidStrategyType) (BidStrategy, error) {
+	typ, err := ParseBidStrategyType(string(typ))
+	if err != nil {
+		return nil, err
+	}
+	costs := []bidCost{bidCosts[typ]}
+	for _, otherType := range bidStrategyTypes {
+		if otherType != typ {
+			costs = append(costs, bidCosts[otherType])
+		}
+	}
+	return &costBidStrategy{
+		costs: append(costs, etaBidCost),
+	}, nil
+}
+
+func (strategy *costBidStrategy) RankBids(
+	bids []executor.JobEvent,
+	reputationStore *reputation.Store,
+) []executor.JobEvent {
+	ranked := make([]executor.JobEvent, len(bids))
+	copy(ranked, bids)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		for _, cost := range strategy.costs {
+			costI, costJ := cost(ranked[i], reputationStore), cost(ranked[j], reputationStore)
+			if costI != costJ {
+				return costI < costJ
+			}
+		}
+		return false
+	})
+	return ranked
+}
+
+// Compile-time check that costBidStrategy implements the correct interface:
+var _ BidStrategy = (*costBidStrategy)(nil)
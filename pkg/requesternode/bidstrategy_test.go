@@ -0,0 +1,112 @@
+package requesternode
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/reputation"
+	"github.com/stretchr/testify/require"
+)
+
+func bid(nodeID string, jobBid executor.JobBid) executor.JobEvent {
+	return executor.JobEvent{
+		SourceNodeID: nodeID,
+		JobBid:       jobBid,
+	}
+}
+
+func rankedNodeIDs(t *testing.T, typ BidStrategyType, bids []executor.JobEvent, store *reputation.Store) []string {
+	strategy, err := NewBidStrategy(typ)
+	require.NoError(t, err)
+	nodeIDs := []string{}
+	for _, ranked := range strategy.RankBids(bids, store) {
+		nodeIDs = append(nodeIDs, ranked.SourceNodeID)
+	}
+	return nodeIDs
+}
+
+func TestPriceBidStrategy(t *testing.T) {
+	bids := []executor.JobEvent{
+		bid("expensive", executor.JobBid{Price: 10}),
+		bid("cheap-slow", executor.JobBid{Price: 1, ETASeconds: 60}),
+		bid("cheap-fast", executor.JobBid{Price: 1, ETASeconds: 5}),
+		bid("unreliable-fast", executor.JobBid{Price: 1, ETASeconds: 1}),
+		bid("unpriced-first", executor.JobBid{}),
+		bid("unpriced-second", executor.JobBid{}),
+	}
+
+	// a node that errors loses out to other bids at the same price
+	store := reputation.NewStore()
+	store.RecordShardState(executor.JobShardState{
+		NodeID: "unreliable-fast",
+		State:  executor.JobStateError,
+	})
+
+	require.Equal(t, []string{
+		"cheap-fast",
+		"cheap-slow",
+		"unreliable-fast",
+		"expensive",
+		"unpriced-first",
+		"unpriced-second",
+	}, rankedNodeIDs(t, BidStrategyPrice, bids, store))
+
+	// we don't reorder the bids we were given
+	require.Equal(t, "expensive", bids[0].SourceNodeID)
+}
+
+func TestBidStrategies(t *testing.T) {
+	bids := []executor.JobEvent{
+		bid("cheap", executor.JobBid{Price: 1, LocalInputs: 0, Load: 0.5}),
+		bid("local", executor.JobBid{Price: 2, LocalInputs: 2, Load: 0.5}),
+		bid("idle", executor.JobBid{Price: 2, LocalInputs: 0, Load: 0}),
+		bid("trusted", executor.JobBid{Price: 2, LocalInputs: 1, Load: 0.9}),
+	}
+
+	store := reputation.NewStore()
+	store.RecordShardState(executor.JobShardState{
+		NodeID:          "trusted",
+		State:           executor.JobStateFinalized,
+		ResultsAccepted: true,
+	})
+
+	testCases := []struct {
+		typ      BidStrategyType
+		expected []string
+	}{
+		// ties on price are broken by reputation and then locality
+		{BidStrategyPrice, []string{"cheap", "trusted", "local", "idle"}},
+		{BidStrategyReputation, []string{"trusted", "cheap", "local", "idle"}},
+		{BidStrategyLocality, []string{"local", "trusted", "cheap", "idle"}},
+		{BidStrategyLoad, []string{"idle", "cheap", "local", "trusted"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(string(testCase.typ), func(t *testing.T) {
+			require.Equal(t, testCase.expected, rankedNodeIDs(t, testCase.typ, bids, store))
+		})
+	}
+}
+
+func TestLocalityCountsImage(t *testing.T) {
+	bids := []executor.JobEvent{
+		bid("nothing", executor.JobBid{}),
+		bid("image", executor.JobBid{HasImage: true}),
+		bid("input", executor.JobBid{LocalInputs: 1, Load: 0.5}),
+		bid("both", executor.JobBid{LocalInputs: 1, HasImage: true}),
+	}
+	require.Equal(t, []string{"both", "image", "input", "nothing"},
+		rankedNodeIDs(t, BidStrategyLocality, bids, reputation.NewStore()))
+}
+
+func TestParseBidStrategyType(t *testing.T) {
+	typ, err := ParseBidStrategyType("locality")
+	require.NoError(t, err)
+	require.Equal(t, BidStrategyLocality, typ)
+
+	_, err = ParseBidStrategyType("random")
+	require.Error(t, err)
+
+	_, err = NewBidStrategy("random")
+	require.Error(t, err)
+}
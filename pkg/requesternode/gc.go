@@ -0,0 +1,223 @@
+package requesternode
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/localdb"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/ipfs/go-cid"
+	"github.com/rs/zerolog/log"
+)
+
+const DefaultJobGCInterval = time.Minute
+
+// removes pins from the local ipfs node - *ipfs.Client does this
+type Unpinner interface {
+	Unpin(ctx context.Context, cid string) error
+}
+
+type JobGCConfig struct {
+	// finished jobs are forgotten this long after they finished
+	// - zero means we keep every job forever
+	Retention time.Duration
+	// how often we look for jobs to forget - defaults to DefaultJobGCInterval
+	Interval time.Duration
+	// if given the build contexts, results and combined results of the
+	// jobs we forget are unpinned from the local ipfs node
+	Unpinner Unpinner
+}
+
+// a job has finished once every execution of every shard has been
+// finalized or has given up
+func hasJobFinished(j executor.Job, jobState executor.JobState) bool {
+	finalized, err := job.WaitForFinalizedShards(j)(jobState)
+	if err == nil && finalized {
+		return true
+	}
+	shardStates := job.FlattenShardStates(jobState)
+	if len(shardStates) < job.GetJobTotalExecutionCount(j) {
+		return false
+	}
+	for _, shardState := range shardStates {
+		if shardState.State != executor.JobStateFinalized &&
+			shardState.State != executor.JobStateError &&
+			shardState.State != executor.JobStateCancelled {
+			return false
+		}
+	}
+	return true
+}
+
+func (node *RequesterNode) jobGCLoopSetup(cm *system.CleanupManager) {
+	interval := node.config.JobGC.Interval
+	if interval <= 0 {
+		interval = DefaultJobGCInterval
+	}
+	ticker := time.NewTicker(interval)
+	ctx, cancelFunction := context.WithCancel(context.Background())
+
+	cm.RegisterCallback(func() error {
+		cancelFunction()
+		return nil
+	})
+
+	for {
+		select {
+		case <-ticker.C:
+			node.collectJobs(ctx, time.Now())
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// forget every job this node knows about that finished longer ago than
+// the retention period - not just the jobs we are the requester for
+func (node *RequesterNode) collectJobs(ctx context.Context, now time.Time) {
+	jobs, err := node.controller.GetJobs(ctx, localdb.JobQuery{})
+	if err != nil {
+		log.Error().Msgf("error listing jobs to garbage collect: %s", err)
+		return
+	}
+	expiredJobs := []executor.Job{}
+	// cids the jobs we are keeping still need pinned
+	keptCIDs := map[string]bool{}
+	for _, j := range jobs {
+		expired, err := node.hasJobExpired(ctx, j, now)
+		if err != nil {
+			log.Warn().Msgf("error checking if job %s has expired: %s", j.ID, err)
+		}
+		if expired {
+			expiredJobs = append(expiredJobs, j)
+			continue
+		}
+		for _, c := range node.getJobCIDs(ctx, j) {
+			keptCIDs[c] = true
+		}
+	}
+	for _, j := range expiredJobs {
+		log.Debug().Msgf("Requester node %s garbage collecting job %s", node.id, j.ID)
+		node.unpinJob(ctx, j, keptCIDs)
+		node.deleteJobResults(ctx, j)
+		err = node.controller.DeleteJob(ctx, j.ID)
+		if err != nil {
+			log.Warn().Msgf("error deleting job %s: %s", j.ID, err)
+		}
+		node.forgetVerifiedShards(j.ID)
+		node.forgetDroppedSamples(j.ID)
+	}
+}
+
+func (node *RequesterNode) forgetVerifiedShards(jobID string) {
+	node.verifyMutex.Lock()
+	defer node.verifyMutex.Unlock()
+	for key := range node.verifiedShards {
+		if strings.HasPrefix(key, jobID+":") {
+			delete(node.verifiedShards, key)
+		}
+	}
+	for key := range node.awaitingSamples {
+		if strings.HasPrefix(key, jobID+":") {
+			delete(node.awaitingSamples, key)
+		}
+	}
+}
+
+// we keep rejecting late bids for the samples we dropped until the job is gone
+func (node *RequesterNode) forgetDroppedSamples(jobID string) {
+	node.sampleMutex.Lock()
+	defer node.sampleMutex.Unlock()
+	for key := range node.droppedSamples {
+		if strings.HasPrefix(key, jobID+":") {
+			delete(node.droppedSamples, key)
+		}
+	}
+}
+
+func (node *RequesterNode) hasJobExpired(ctx context.Context, j executor.Job, now time.Time) (bool, error) {
+	jobState, err := node.controller.GetJobState(ctx, j.ID)
+	if err != nil {
+		return false, err
+	}
+	if !hasJobFinished(j, jobState) {
+		return false, nil
+	}
+	finishedAt := j.CreatedAt
+	for _, shardState := range job.FlattenShardStates(jobState) {
+		if shardState.FinishedAt.After(finishedAt) {
+			finishedAt = shardState.FinishedAt
+		}
+	}
+	return now.Sub(finishedAt) >= node.config.JobGC.Retention, nil
+}
+
+// unpin the job's build contexts, any results that were published to
+// ipfs and its combined results - most results will have been pinned by
+// other nodes so failing to unpin them is expected. anything a job we
+// are keeping still uses stays pinned
+func (node *RequesterNode) unpinJob(ctx context.Context, j executor.Job, keptCIDs map[string]bool) {
+	unpinner := node.config.JobGC.Unpinner
+	if unpinner == nil {
+		return
+	}
+	for _, c := range node.getJobCIDs(ctx, j) {
+		if keptCIDs[c] {
+			log.Debug().Msgf("not unpinning %s for job %s as another job uses it", c, j.ID)
+			continue
+		}
+		err := unpinner.Unpin(ctx, c)
+		if err != nil {
+			log.Debug().Msgf("could not unpin %s for job %s: %s", c, j.ID, err)
+		}
+	}
+}
+
+// the cids of the job's build contexts, results and combined results
+func (node *RequesterNode) getJobCIDs(ctx context.Context, j executor.Job) []string {
+	cids := []string{}
+	for _, buildContext := range j.Spec.Contexts {
+		if buildContext.Engine == storage.StorageSourceIPFS && buildContext.Cid != "" {
+			cids = append(cids, buildContext.Cid)
+		}
+	}
+	jobState, err := node.controller.GetJobState(ctx, j.ID)
+	if err != nil {
+		log.Warn().Msgf("error getting state of job %s to find its results: %s", j.ID, err)
+	}
+	for _, shardState := range job.FlattenShardStates(jobState) {
+		// results from verifiers that don't use ipfs are not cids
+		if _, err := cid.Decode(shardState.ResultsID); err == nil {
+			cids = append(cids, shardState.ResultsID)
+		}
+	}
+	localEvents, err := node.controller.GetJobLocalEvents(ctx, j.ID)
+	if err != nil {
+		log.Warn().Msgf("error getting local events of job %s to find its combined results: %s", j.ID, err)
+	}
+	for _, localEvent := range localEvents {
+		if localEvent.EventName == executor.JobLocalEventResultsCombined && localEvent.ResultsID != "" {
+			cids = append(cids, localEvent.ResultsID)
+		}
+	}
+	return cids
+}
+
+// results that are not in ipfs are kept by the job's verifier - these
+// will only be on the compute nodes that ran the job
+func (node *RequesterNode) deleteJobResults(ctx context.Context, j executor.Job) {
+	cleaner, ok := node.verifiers[j.Spec.Verifier].(verifier.ResultsCleaner)
+	if !ok {
+		return
+	}
+	err := cleaner.DeleteJobResults(ctx, j.ID)
+	if err != nil {
+		log.Warn().Msgf("error deleting results for job %s: %s", j.ID, err)
+	}
+}
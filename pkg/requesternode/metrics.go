@@ -0,0 +1,25 @@
+package requesternode
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for monitoring requester nodes:
+var (
+	quotaAdmitted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quota_jobs_admitted",
+			Help: "Number of jobs the requester node admitted within the client's quotas.",
+		},
+		[]string{"node_id", "client_id"},
+	)
+
+	quotaRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quota_jobs_rejected",
+			Help: "Number of jobs the requester node rejected because the client was over a quota.",
+		},
+		[]string{"node_id", "client_id", "quota"},
+	)
+)
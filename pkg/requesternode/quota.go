@@ -0,0 +1,371 @@
+package requesternode
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	sync "github.com/lukemarsden/golang-mutex-tracer"
+
+	"github.com/filecoin-project/bacalhau/pkg/capacitymanager"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// the window JobsPerHour is counted over
+const quotaWindow = time.Hour
+
+// a job no compute node has been given this long after it was submitted
+// will most likely never run so it stops counting against quotas
+const DefaultQuotaBidTimeout = time.Minute * 5
+
+// the limits the requester node puts on what a single client can submit
+// zero (or empty) values mean there is no limit
+type QuotaConfig struct {
+	// how many jobs a client can submit in any hour
+	JobsPerHour int `json:"jobs_per_hour"`
+	// how many of a client's jobs can be unfinished at once
+	MaxConcurrentJobs int `json:"max_concurrent_jobs"`
+	// the total CPU (e.g. 500m, 2, 8) a client's unfinished jobs can ask
+	// for - each job asks for its resources once per execution in the deal
+	MaxCPU string `json:"max_cpu"`
+	// the total memory (e.g. 500Mb, 2Gb, 8Gb) a client's unfinished
+	// jobs can ask for - counted the same way as MaxCPU
+	MaxMemory string `json:"max_memory"`
+}
+
+type QuotaType string
+
+const (
+	QuotaJobsPerHour       QuotaType = "jobs_per_hour"
+	QuotaMaxConcurrentJobs QuotaType = "max_concurrent_jobs"
+	QuotaMaxCPU            QuotaType = "max_cpu"
+	QuotaMaxMemory         QuotaType = "max_memory"
+)
+
+// returned when admitting a job would take a client over one of its quotas
+type QuotaExceededError struct {
+	ClientID string
+	Quota    QuotaType
+	Message  string
+	// how long until the client can expect the job to be admitted -
+	// zero if we can't know (e.g. we are waiting on other jobs to finish)
+	RetryAfter time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota %s exceeded for client %s: %s", e.Quota, e.ClientID, e.Message)
+}
+
+// what a client is currently using and how often we have said no
+type ClientQuotaUsage struct {
+	ClientID       string  `json:"client_id"`
+	JobsLastHour   int     `json:"jobs_last_hour"`
+	ConcurrentJobs int     `json:"concurrent_jobs"`
+	CPU            float64 `json:"cpu"`
+	Memory         uint64  `json:"memory"`
+	Admitted       int     `json:"admitted"`
+	Rejected       int     `json:"rejected"`
+}
+
+// a job that has been admitted but that the caller has not yet
+// confirmed was submitted - it counts against the client's quotas
+// until it is released
+type QuotaReservation struct {
+	clientID string
+	key      string
+}
+
+type clientQuota struct {
+	// when each of the client's jobs in the last hour was admitted
+	submissions []time.Time
+	// job id (or reservation key) -> the resources it asked for
+	jobs map[string]capacitymanager.ResourceUsageData
+	// reservation key -> when it was admitted
+	reservations map[string]time.Time
+	// job id -> when it was submitted
+	confirmations map[string]time.Time
+	admitted      int
+	rejected      int
+}
+
+// keeps track of what each client has submitted to the requester node
+// and decides if they are allowed to submit any more
+type QuotaManager struct {
+	nodeID      string
+	config      QuotaConfig
+	limits      capacitymanager.ResourceUsageData
+	jobLoader   job.JobLoader
+	stateLoader job.StateLoader
+	clients     map[string]*clientQuota
+	// how long a job can go without any bid being accepted
+	// before it no longer counts against quotas
+	bidTimeout time.Duration
+	mtx        sync.Mutex
+}
+
+func NewQuotaManager(
+	nodeID string,
+	config QuotaConfig,
+	jobLoader job.JobLoader,
+	stateLoader job.StateLoader,
+) *QuotaManager {
+	manager := &QuotaManager{
+		nodeID: nodeID,
+		config: config,
+		limits: capacitymanager.ParseResourceUsageConfig(capacitymanager.ResourceUsageConfig{
+			CPU:    config.MaxCPU,
+			Memory: config.MaxMemory,
+		}),
+		jobLoader:   jobLoader,
+		stateLoader: stateLoader,
+		clients:     map[string]*clientQuota{},
+		bidTimeout:  DefaultQuotaBidTimeout,
+	}
+	manager.mtx.EnableTracerWithOpts(sync.Opts{
+		Threshold: 10 * time.Millisecond,
+		Id:        "QuotaManager.mtx",
+	})
+	return manager
+}
+
+func (manager *QuotaManager) GetConfig() QuotaConfig {
+	return manager.config
+}
+
+// check the job fits in the client's quotas and if so reserve room
+// for it - the caller must either Confirm or Release the reservation
+func (manager *QuotaManager) Reserve(
+	ctx context.Context,
+	clientID string,
+	spec executor.JobSpec,
+	deal executor.JobDeal,
+) (*QuotaReservation, error) {
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+
+	client := manager.getClient(clientID)
+	now := time.Now()
+	manager.refreshClient(ctx, client, now)
+
+	requested := getRequestedResources(spec, deal)
+	err := manager.checkQuotas(clientID, client, requested, now)
+	if err != nil {
+		client.rejected++
+		quotaRejected.WithLabelValues(manager.nodeID, clientID, string(err.Quota)).Inc()
+		return nil, err
+	}
+
+	reservation := &QuotaReservation{
+		clientID: clientID,
+		key:      fmt.Sprintf("reservation-%s", uuid.NewString()),
+	}
+	client.submissions = append(client.submissions, now)
+	client.jobs[reservation.key] = requested
+	client.reservations[reservation.key] = now
+	return reservation, nil
+}
+
+// the reserved job was submitted - it counts against the client's
+// quotas until it finishes
+func (manager *QuotaManager) Confirm(reservation *QuotaReservation, jobID string) {
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+
+	client := manager.getClient(reservation.clientID)
+	requested, ok := client.jobs[reservation.key]
+	if !ok {
+		return
+	}
+	delete(client.jobs, reservation.key)
+	delete(client.reservations, reservation.key)
+	client.jobs[jobID] = requested
+	client.confirmations[jobID] = time.Now()
+	client.admitted++
+	quotaAdmitted.WithLabelValues(manager.nodeID, reservation.clientID).Inc()
+}
+
+// the reserved job was never submitted so it doesn't count against
+// any of the client's quotas
+func (manager *QuotaManager) Release(reservation *QuotaReservation) {
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+
+	client := manager.getClient(reservation.clientID)
+	admittedAt, ok := client.reservations[reservation.key]
+	if !ok {
+		return
+	}
+	delete(client.jobs, reservation.key)
+	delete(client.reservations, reservation.key)
+	for i, submittedAt := range client.submissions {
+		if submittedAt.Equal(admittedAt) {
+			client.submissions = append(client.submissions[:i], client.submissions[i+1:]...)
+			break
+		}
+	}
+}
+
+// what every client we have heard from is using - sorted by client id
+func (manager *QuotaManager) GetUsage(ctx context.Context) []ClientQuotaUsage {
+	manager.mtx.Lock()
+	defer manager.mtx.Unlock()
+
+	now := time.Now()
+	ret := []ClientQuotaUsage{}
+	for clientID, client := range manager.clients {
+		manager.refreshClient(ctx, client, now)
+		usage := ClientQuotaUsage{
+			ClientID:       clientID,
+			JobsLastHour:   len(client.submissions),
+			ConcurrentJobs: len(client.jobs),
+			Admitted:       client.admitted,
+			Rejected:       client.rejected,
+		}
+		for _, requested := range client.jobs {
+			usage.CPU += requested.CPU
+			usage.Memory += requested.Memory
+		}
+		ret = append(ret, usage)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ClientID < ret[j].ClientID
+	})
+	return ret
+}
+
+func (manager *QuotaManager) checkQuotas(
+	clientID string,
+	client *clientQuota,
+	requested capacitymanager.ResourceUsageData,
+	now time.Time,
+) *QuotaExceededError {
+	if manager.config.JobsPerHour > 0 && len(client.submissions) >= manager.config.JobsPerHour {
+		// submissions are in the order they were admitted so the first
+		// one is the next to drop out of the window
+		return &QuotaExceededError{
+			ClientID:   clientID,
+			Quota:      QuotaJobsPerHour,
+			Message:    fmt.Sprintf("%d jobs have been submitted in the last hour (limit %d)", len(client.submissions), manager.config.JobsPerHour),
+			RetryAfter: client.submissions[0].Add(quotaWindow).Sub(now),
+		}
+	}
+
+	if manager.config.MaxConcurrentJobs > 0 && len(client.jobs) >= manager.config.MaxConcurrentJobs {
+		return &QuotaExceededError{
+			ClientID: clientID,
+			Quota:    QuotaMaxConcurrentJobs,
+			Message:  fmt.Sprintf("%d jobs are still running (limit %d)", len(client.jobs), manager.config.MaxConcurrentJobs),
+		}
+	}
+
+	using := capacitymanager.ResourceUsageData{}
+	for _, jobRequested := range client.jobs {
+		using.CPU += jobRequested.CPU
+		using.Memory += jobRequested.Memory
+	}
+
+	if manager.limits.CPU > 0 && using.CPU+requested.CPU > manager.limits.CPU {
+		return &QuotaExceededError{
+			ClientID: clientID,
+			Quota:    QuotaMaxCPU,
+			Message: fmt.Sprintf("the job asks for %g CPU on top of the %g already in use (limit %g)",
+				requested.CPU, using.CPU, manager.limits.CPU),
+		}
+	}
+
+	if manager.limits.Memory > 0 && using.Memory+requested.Memory > manager.limits.Memory {
+		return &QuotaExceededError{
+			ClientID: clientID,
+			Quota:    QuotaMaxMemory,
+			Message: fmt.Sprintf("the job asks for %d bytes of memory on top of the %d already in use (limit %d)",
+				requested.Memory, using.Memory, manager.limits.Memory),
+		}
+	}
+
+	return nil
+}
+
+// forget submissions that have dropped out of the window, jobs that
+// have finished and jobs no compute node was given in time
+func (manager *QuotaManager) refreshClient(ctx context.Context, client *clientQuota, now time.Time) {
+	submissions := []time.Time{}
+	for _, submittedAt := range client.submissions {
+		if now.Sub(submittedAt) < quotaWindow {
+			submissions = append(submissions, submittedAt)
+		}
+	}
+	client.submissions = submissions
+
+	for jobID := range client.jobs {
+		if _, ok := client.reservations[jobID]; ok {
+			continue
+		}
+		finished, err := manager.isJobFinished(ctx, jobID, now.Sub(client.confirmations[jobID]))
+		if err != nil {
+			// the job has most likely been garbage collected
+			log.Debug().Msgf("could not load job %s so it no longer counts towards quotas: %s", jobID, err)
+			finished = true
+		}
+		if finished {
+			delete(client.jobs, jobID)
+			delete(client.confirmations, jobID)
+		}
+	}
+}
+
+// a job that has been waiting longer than the bid timeout without
+// any bid being accepted counts as finished as well
+func (manager *QuotaManager) isJobFinished(ctx context.Context, jobID string, age time.Duration) (bool, error) {
+	j, err := manager.jobLoader(ctx, jobID)
+	if err != nil {
+		return false, err
+	}
+	jobState, err := manager.stateLoader(ctx, jobID)
+	if err != nil {
+		return false, err
+	}
+	if hasJobFinished(j, jobState) {
+		return true, nil
+	}
+	return age >= manager.bidTimeout && !hasAcceptedBids(jobState), nil
+}
+
+// has any compute node been given the job to run
+func hasAcceptedBids(jobState executor.JobState) bool {
+	for _, shardState := range job.FlattenShardStates(jobState) {
+		if shardState.State != executor.JobStateBidding && shardState.State != executor.JobStateCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+func (manager *QuotaManager) getClient(clientID string) *clientQuota {
+	client, ok := manager.clients[clientID]
+	if !ok {
+		client = &clientQuota{
+			jobs:          map[string]capacitymanager.ResourceUsageData{},
+			reservations:  map[string]time.Time{},
+			confirmations: map[string]time.Time{},
+		}
+		manager.clients[clientID] = client
+	}
+	return client
+}
+
+// what a job asks for across every execution the deal asks for
+func getRequestedResources(spec executor.JobSpec, deal executor.JobDeal) capacitymanager.ResourceUsageData {
+	perExecution := capacitymanager.ParseResourceUsageConfig(spec.Resources)
+	executions := deal.Concurrency
+	if executions < 1 {
+		executions = 1
+	}
+	return capacitymanager.ResourceUsageData{
+		CPU:    perExecution.CPU * float64(executions),
+		Memory: perExecution.Memory * uint64(executions),
+	}
+}
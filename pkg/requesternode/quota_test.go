@@ -0,0 +1,180 @@
+package requesternode
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/capacitymanager"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/stretchr/testify/require"
+)
+
+// jobs that are in the states map have finished
+type fakeJobs struct {
+	jobs   map[string]executor.Job
+	states map[string]executor.JobState
+}
+
+func newFakeJobs() *fakeJobs {
+	return &fakeJobs{
+		jobs:   map[string]executor.Job{},
+		states: map[string]executor.JobState{},
+	}
+}
+
+func (f *fakeJobs) getJob(ctx context.Context, id string) (executor.Job, error) {
+	j, ok := f.jobs[id]
+	if !ok {
+		return executor.Job{}, fmt.Errorf("job not found: %s", id)
+	}
+	return j, nil
+}
+
+func (f *fakeJobs) getJobState(ctx context.Context, id string) (executor.JobState, error) {
+	return f.states[id], nil
+}
+
+func (f *fakeJobs) finish(id string, state executor.JobStateType) {
+	f.states[id] = executor.JobState{
+		Nodes: map[string]executor.JobNodeState{
+			"node-a": {
+				Shards: map[int]executor.JobShardState{
+					0: {NodeID: "node-a", ShardIndex: 0, State: state},
+				},
+			},
+		},
+	}
+}
+
+func (f *fakeJobs) submit(manager *QuotaManager, clientID string, spec executor.JobSpec) (string, error) {
+	reservation, err := manager.Reserve(context.Background(), clientID, spec, executor.JobDeal{Concurrency: 1})
+	if err != nil {
+		return "", err
+	}
+	id := fmt.Sprintf("job-%d", len(f.jobs))
+	f.jobs[id] = executor.Job{ID: id, Deal: executor.JobDeal{Concurrency: 1}}
+	manager.Confirm(reservation, id)
+	return id, nil
+}
+
+func requireQuotaExceeded(t *testing.T, err error, quota QuotaType) {
+	require.Error(t, err)
+	quotaErr, ok := err.(*QuotaExceededError)
+	require.True(t, ok, "expected a quota error but got: %s", err)
+	require.Equal(t, quota, quotaErr.Quota)
+}
+
+func TestQuotaJobsPerHour(t *testing.T) {
+	jobs := newFakeJobs()
+	manager := NewQuotaManager("node", QuotaConfig{JobsPerHour: 2}, jobs.getJob, jobs.getJobState)
+
+	for i := 0; i < 2; i++ {
+		id, err := jobs.submit(manager, "alice", executor.JobSpec{})
+		require.NoError(t, err)
+		// finished jobs still count towards the hourly limit
+		jobs.finish(id, executor.JobStateFinalized)
+	}
+
+	_, err := jobs.submit(manager, "alice", executor.JobSpec{})
+	requireQuotaExceeded(t, err, QuotaJobsPerHour)
+	require.Greater(t, err.(*QuotaExceededError).RetryAfter.Minutes(), 59.0)
+
+	// quotas are per client
+	_, err = jobs.submit(manager, "bob", executor.JobSpec{})
+	require.NoError(t, err)
+
+	usage := manager.GetUsage(context.Background())
+	require.Equal(t, []ClientQuotaUsage{
+		{ClientID: "alice", JobsLastHour: 2, Admitted: 2, Rejected: 1},
+		{ClientID: "bob", JobsLastHour: 1, ConcurrentJobs: 1, Admitted: 1},
+	}, usage)
+}
+
+func TestQuotaMaxConcurrentJobs(t *testing.T) {
+	jobs := newFakeJobs()
+	manager := NewQuotaManager("node", QuotaConfig{MaxConcurrentJobs: 1}, jobs.getJob, jobs.getJobState)
+
+	id, err := jobs.submit(manager, "alice", executor.JobSpec{})
+	require.NoError(t, err)
+
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{})
+	requireQuotaExceeded(t, err, QuotaMaxConcurrentJobs)
+
+	// jobs that errored have finished as well
+	jobs.finish(id, executor.JobStateError)
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{})
+	require.NoError(t, err)
+}
+
+func TestQuotaJobsNobodyRuns(t *testing.T) {
+	jobs := newFakeJobs()
+	manager := NewQuotaManager("node", QuotaConfig{MaxConcurrentJobs: 2}, jobs.getJob, jobs.getJobState)
+	manager.bidTimeout = 50 * time.Millisecond
+
+	running, err := jobs.submit(manager, "alice", executor.JobSpec{})
+	require.NoError(t, err)
+	jobs.finish(running, executor.JobStateRunning)
+	// no compute node bids on this one
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{})
+	require.NoError(t, err)
+
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{})
+	requireQuotaExceeded(t, err, QuotaMaxConcurrentJobs)
+
+	// once the bid timeout has passed only the job that is running counts
+	time.Sleep(100 * time.Millisecond)
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{})
+	require.NoError(t, err)
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{})
+	requireQuotaExceeded(t, err, QuotaMaxConcurrentJobs)
+}
+
+func TestQuotaResources(t *testing.T) {
+	jobs := newFakeJobs()
+	manager := NewQuotaManager("node", QuotaConfig{
+		MaxCPU:    "2",
+		MaxMemory: "1Gb",
+	}, jobs.getJob, jobs.getJobState)
+
+	id, err := jobs.submit(manager, "alice", executor.JobSpec{
+		Resources: capacitymanager.ResourceUsageConfig{CPU: "1.5", Memory: "100Mb"},
+	})
+	require.NoError(t, err)
+
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{
+		Resources: capacitymanager.ResourceUsageConfig{CPU: "1", Memory: "100Mb"},
+	})
+	requireQuotaExceeded(t, err, QuotaMaxCPU)
+
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{
+		Resources: capacitymanager.ResourceUsageConfig{CPU: "0.5", Memory: "1Gb"},
+	})
+	requireQuotaExceeded(t, err, QuotaMaxMemory)
+
+	jobs.finish(id, executor.JobStateFinalized)
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{
+		Resources: capacitymanager.ResourceUsageConfig{CPU: "2", Memory: "1Gb"},
+	})
+	require.NoError(t, err)
+}
+
+func TestQuotaRelease(t *testing.T) {
+	jobs := newFakeJobs()
+	manager := NewQuotaManager("node", QuotaConfig{
+		JobsPerHour:       1,
+		MaxConcurrentJobs: 1,
+	}, jobs.getJob, jobs.getJobState)
+
+	reservation, err := manager.Reserve(context.Background(), "alice", executor.JobSpec{}, executor.JobDeal{})
+	require.NoError(t, err)
+
+	// the reservation counts until it is released
+	_, err = manager.Reserve(context.Background(), "alice", executor.JobSpec{}, executor.JobDeal{})
+	requireQuotaExceeded(t, err, QuotaJobsPerHour)
+
+	manager.Release(reservation)
+	_, err = jobs.submit(manager, "alice", executor.JobSpec{})
+	require.NoError(t, err)
+}
@@ -2,6 +2,7 @@ package requesternode
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	sync "github.com/lukemarsden/golang-mutex-tracer"
@@ -9,6 +10,7 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/controller"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
 	"github.com/filecoin-project/bacalhau/pkg/logger"
+	"github.com/filecoin-project/bacalhau/pkg/reputation"
 	"github.com/filecoin-project/bacalhau/pkg/system"
 	"github.com/filecoin-project/bacalhau/pkg/verifier"
 	"github.com/rs/zerolog/log"
@@ -16,14 +18,54 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-type RequesterNodeConfig struct{}
+// long enough for the compute nodes that are going to bid on a
+// shard to have done so without holding up the job for long
+const DefaultBidCollectionWindow = time.Second * 2
+
+type RequesterNodeConfig struct {
+	// how long to collect bids for a job shard before choosing between
+	// them with the bid strategy - zero means we accept bids first come
+	// first served as they arrive
+	BidCollectionWindow time.Duration
+	// how we choose between the bids we collected - if this is not
+	// given we prefer the cheapest bids
+	BidStrategy BidStrategy
+	// reject bids from nodes whose results verifiers have seen disagree
+	// with other nodes this many times - zero means we never do
+	MaxNodeDisagreements int
+	// reject bids from nodes whose reputation score (0 - 1) is below
+	// this - zero means we accept bids from anyone
+	MinNodeReputation float64
+	// count it against a node's reputation if a shard we gave it has not
+	// finished after this long - zero means we never do
+	ShardTimeout time.Duration
+	// the limits on what each client can submit
+	Quotas QuotaConfig
+	// when to forget about finished jobs
+	JobGC JobGCConfig
+}
 
 type RequesterNode struct {
 	id         string
 	config     RequesterNodeConfig //nolint:gocritic
 	controller *controller.Controller
 	verifiers  map[verifier.VerifierType]verifier.Verifier
-	bidMutex   sync.Mutex
+	// job shard -> the bids we are collecting for it
+	pendingBids map[string][]executor.JobEvent
+	bidMutex    sync.Mutex
+	// job shards whose results we have already verified
+	verifiedShards map[string]bool
+	// job shards we are giving other nodes a chance to bid on
+	// for the extra executions the verifier asked for
+	awaitingSamples map[string]bool
+	verifyMutex     sync.Mutex
+	// job shards whose extra executions no node bid on in time
+	droppedSamples map[string]bool
+	sampleMutex    sync.Mutex
+	// how the compute nodes we have given work to have done
+	reputation *reputation.Store
+	// what each client has submitted to us
+	quotas *QuotaManager
 }
 
 func NewRequesterNode(
@@ -43,22 +85,54 @@ func NewRequesterNode(
 		threadLogger.Error().Err(err)
 		return nil, err
 	}
+	if config.BidStrategy == nil {
+		config.BidStrategy, err = NewBidStrategy(BidStrategyPrice)
+		if err != nil {
+			return nil, err
+		}
+	}
 	requesterNode := &RequesterNode{
-		id:         nodeID,
-		config:     config,
-		controller: c,
-		verifiers:  verifiers,
+		id:              nodeID,
+		config:          config,
+		controller:      c,
+		verifiers:       verifiers,
+		pendingBids:     map[string][]executor.JobEvent{},
+		verifiedShards:  map[string]bool{},
+		awaitingSamples: map[string]bool{},
+		droppedSamples:  map[string]bool{},
+		reputation:      reputation.NewStore(),
+		quotas:          NewQuotaManager(nodeID, config.Quotas, c.GetJob, c.GetJobState),
 	}
 	requesterNode.bidMutex.EnableTracerWithOpts(sync.Opts{
 		Threshold: 10 * time.Millisecond,
 		Id:        "RequesterNode.bidMutex",
 	})
+	requesterNode.verifyMutex.EnableTracerWithOpts(sync.Opts{
+		Threshold: 10 * time.Millisecond,
+		Id:        "RequesterNode.verifyMutex",
+	})
+	requesterNode.sampleMutex.EnableTracerWithOpts(sync.Opts{
+		Threshold: 10 * time.Millisecond,
+		Id:        "RequesterNode.sampleMutex",
+	})
 
 	requesterNode.subscriptionSetup()
 
+	if config.JobGC.Retention > 0 {
+		go requesterNode.jobGCLoopSetup(cm)
+	}
+
 	return requesterNode, nil
 }
 
+func (node *RequesterNode) GetReputationStore() *reputation.Store {
+	return node.reputation
+}
+
+func (node *RequesterNode) GetQuotaManager() *QuotaManager {
+	return node.quotas
+}
+
 /*
 subscriptions
 */
@@ -73,8 +147,17 @@ func (node *RequesterNode) subscriptionSetup() {
 		if job.RequesterNodeID != node.id {
 			return
 		}
-		if jobEvent.EventName == executor.JobEventBid {
+		switch jobEvent.EventName {
+		case executor.JobEventBid:
 			node.subscriptionEventBid(ctx, job, jobEvent)
+		case executor.JobEventBidAccepted:
+			node.subscriptionEventBidAccepted(ctx, job, jobEvent)
+		case executor.JobEventCompleted, executor.JobEventError:
+			node.recordShardOutcome(ctx, job, jobEvent)
+			node.subscriptionEventShardCompleted(ctx, job, jobEvent)
+		case executor.JobEventResultsAccepted, executor.JobEventResultsRejected:
+			node.recordShardOutcome(ctx, job, jobEvent)
+			node.subscriptionEventResultsVerified(ctx, job)
 		}
 	})
 }
@@ -83,6 +166,38 @@ func (node *RequesterNode) subscriptionEventBid(ctx context.Context, job executo
 	node.bidMutex.Lock()
 	defer node.bidMutex.Unlock()
 
+	if node.config.BidCollectionWindow <= 0 {
+		node.processBids(ctx, job, []executor.JobEvent{jobEvent})
+		return
+	}
+
+	// the first bid for a shard opens the window and when it closes
+	// we choose between everything that arrived in the meantime
+	key := fmt.Sprintf("%s:%d", job.ID, jobEvent.ShardIndex)
+	bids, collecting := node.pendingBids[key]
+	node.pendingBids[key] = append(bids, jobEvent)
+	if collecting {
+		return
+	}
+	time.AfterFunc(node.config.BidCollectionWindow, func() {
+		node.bidMutex.Lock()
+		defer node.bidMutex.Unlock()
+		collectedBids := node.pendingBids[key]
+		delete(node.pendingBids, key)
+		// the context of the first bid event will be long gone by now
+		node.processBids(context.Background(), job, collectedBids)
+	})
+}
+
+// accept the best bids until the shard reaches its concurrency and reject the rest
+// must be called with the bidMutex held
+func (node *RequesterNode) processBids(ctx context.Context, job executor.Job, bids []executor.JobEvent) {
+	for _, jobEvent := range node.config.BidStrategy.RankBids(bids, node.reputation) {
+		node.processBid(ctx, job, jobEvent)
+	}
+}
+
+func (node *RequesterNode) processBid(ctx context.Context, job executor.Job, jobEvent executor.JobEvent) {
 	// Need to declare span separately to prevent shadowing
 	var span trace.Span
 	ctx, span = node.newSpanForJob(ctx, job.ID, "JobEventBid")
@@ -90,6 +205,7 @@ func (node *RequesterNode) subscriptionEventBid(ctx context.Context, job executo
 
 	threadLogger := logger.LoggerWithNodeAndJobInfo(node.id, job.ID)
 
+	alreadyAssigned := false
 	accepted := func() bool {
 		// let's see how many bids we have already accepted
 		// it's important this comes from "local events"
@@ -103,6 +219,8 @@ func (node *RequesterNode) subscriptionEventBid(ctx context.Context, job executo
 
 		// a map of shard index onto an array of node ids we have farmed the job out to
 		assignedNodes := map[int][]string{}
+		// the runtimes the bids we accepted for this shard will run it in
+		assignedRuntimes := map[string]bool{}
 
 		for _, localEvent := range localEvents {
 			if localEvent.EventName == executor.JobLocalEventBidAccepted {
@@ -112,6 +230,9 @@ func (node *RequesterNode) subscriptionEventBid(ctx context.Context, job executo
 				}
 				assignedNodesForShard = append(assignedNodesForShard, localEvent.TargetNodeID)
 				assignedNodes[localEvent.ShardIndex] = assignedNodesForShard
+				if localEvent.ShardIndex == jobEvent.ShardIndex {
+					assignedRuntimes[localEvent.JobBid.Runtime] = true
+				}
 			}
 		}
 
@@ -122,23 +243,63 @@ func (node *RequesterNode) subscriptionEventBid(ctx context.Context, job executo
 
 		// we have already reached concurrency for this shard
 		// so let's reject this bid
-		if len(assignedNodesForShard) >= job.Deal.Concurrency {
+		concurrency := node.getShardConcurrency(job, jobEvent.ShardIndex)
+		if len(assignedNodesForShard) >= concurrency {
 			//nolint:lll // Error message needs long line
-			threadLogger.Debug().Msgf("Rejected: Job shard %s %d already reached concurrency of %d %+v", job.ID, jobEvent.ShardIndex, job.Deal.Concurrency, assignedNodesForShard)
+			threadLogger.Debug().Msgf("Rejected: Job shard %s %d already reached concurrency of %d %+v", job.ID, jobEvent.ShardIndex, concurrency, assignedNodesForShard)
+			return false
+		}
+
+		// every execution of a shard runs on a different node otherwise
+		// comparing the results tells us nothing
+		for _, assignedNodeID := range assignedNodesForShard {
+			if assignedNodeID == jobEvent.SourceNodeID {
+				alreadyAssigned = true
+				return false
+			}
+		}
+
+		// results from different runtimes can't be compared so every
+		// execution of a shard runs in the runtime of the first we accepted
+		if len(assignedRuntimes) > 0 && !assignedRuntimes[jobEvent.JobBid.Runtime] {
+			//nolint:lll // Error message needs long line
+			threadLogger.Debug().Msgf("Rejected: Node %s would run job shard %s %d in runtime %q which the other executions don't use", jobEvent.SourceNodeID, job.ID, jobEvent.ShardIndex, jobEvent.JobBid.Runtime)
+			return false
+		}
+
+		// this node's results have disagreed with other nodes too often
+		disagreements := node.getNodeDisagreements(jobEvent.SourceNodeID)
+		if node.config.MaxNodeDisagreements > 0 && disagreements >= node.config.MaxNodeDisagreements {
+			//nolint:lll // Error message needs long line
+			threadLogger.Debug().Msgf("Rejected: Node %s has disagreed with other nodes %d times", jobEvent.SourceNodeID, disagreements)
+			return false
+		}
+
+		// this node has let us down too often
+		score := node.reputation.GetScore(jobEvent.SourceNodeID)
+		if score < node.config.MinNodeReputation {
+			threadLogger.Debug().Msgf("Rejected: Node %s has a reputation of %f", jobEvent.SourceNodeID, score)
 			return false
 		}
 
 		return true
 	}()
 
+	// rejecting the bid would undo the one we already accepted from the node
+	if alreadyAssigned {
+		threadLogger.Debug().Msgf("Ignored: Node %s is already running job shard %s %d",
+			jobEvent.SourceNodeID, job.ID, jobEvent.ShardIndex)
+		return
+	}
+
 	if accepted {
-		log.Debug().Msgf("Requester node %s accepting bid: %s %d", node.id, jobEvent.JobID, jobEvent.ShardIndex)
-		err := node.controller.AcceptJobBid(ctx, jobEvent.JobID, jobEvent.SourceNodeID, jobEvent.ShardIndex)
+		log.Debug().Msgf("Requester node %s accepting bid: %s %d %+v", node.id, jobEvent.JobID, jobEvent.ShardIndex, jobEvent.JobBid)
+		err := node.controller.AcceptJobBid(ctx, jobEvent.JobID, jobEvent.SourceNodeID, jobEvent.ShardIndex, jobEvent.JobBid)
 		if err != nil {
 			threadLogger.Error().Err(err)
 		}
 	} else {
-		log.Debug().Msgf("Requester node %s rejecting bid: %s %d", node.id, jobEvent.JobID, jobEvent.ShardIndex)
+		log.Debug().Msgf("Requester node %s rejecting bid: %s %d %+v", node.id, jobEvent.JobID, jobEvent.ShardIndex, jobEvent.JobBid)
 		err := node.controller.RejectJobBid(ctx, jobEvent.JobID, jobEvent.SourceNodeID, jobEvent.ShardIndex)
 		if err != nil {
 			threadLogger.Error().Err(err)
@@ -146,6 +307,247 @@ func (node *RequesterNode) subscriptionEventBid(ctx context.Context, job executo
 	}
 }
 
+// if the node has not finished the shard by the time it times out
+// that counts against its reputation
+func (node *RequesterNode) subscriptionEventBidAccepted(
+	ctx context.Context,
+	job executor.Job,
+	jobEvent executor.JobEvent,
+) {
+	if node.config.ShardTimeout <= 0 {
+		return
+	}
+	time.AfterFunc(node.config.ShardTimeout, func() {
+		// the context of the bid accepted event will be long gone by now
+		jobState, err := node.controller.GetJobState(context.Background(), job.ID)
+		if err != nil {
+			log.Error().Msgf("error getting job state: %s", err)
+			return
+		}
+		shardState := jobState.Nodes[jobEvent.TargetNodeID].Shards[jobEvent.ShardIndex]
+		if shardState.State == executor.JobStateWaiting || shardState.State == executor.JobStateRunning {
+			log.Debug().Msgf("Requester node %s: node %s timed out on shard %s %d",
+				node.id, jobEvent.TargetNodeID, job.ID, jobEvent.ShardIndex)
+			node.reputation.RecordTimeout(jobEvent.TargetNodeID)
+		}
+	})
+}
+
+// feed the state the event leaves the shard in into the
+// reputation of the node that was running it
+func (node *RequesterNode) recordShardOutcome(
+	ctx context.Context,
+	job executor.Job,
+	jobEvent executor.JobEvent,
+) {
+	// the requester node targets the node it is accepting or rejecting
+	// results for whereas compute nodes report on themselves
+	nodeID := jobEvent.SourceNodeID
+	if jobEvent.TargetNodeID != "" {
+		nodeID = jobEvent.TargetNodeID
+	}
+	node.reputation.RecordShardState(executor.JobShardState{
+		NodeID:          nodeID,
+		ShardIndex:      jobEvent.ShardIndex,
+		State:           executor.GetStateFromEvent(jobEvent.EventName),
+		ResultsAccepted: jobEvent.EventName == executor.JobEventResultsAccepted,
+	})
+}
+
+// once every execution of a shard has finished we ask the job's verifier
+// which results to accept and publish the verdict for each node - this
+// moves the shards into the finalized state which is when the job
+// counts as complete
+func (node *RequesterNode) subscriptionEventShardCompleted(
+	ctx context.Context,
+	job executor.Job,
+	jobEvent executor.JobEvent,
+) {
+	var span trace.Span
+	ctx, span = node.newSpanForJob(ctx, job.ID, "JobEventShardCompleted")
+	defer span.End()
+
+	threadLogger := logger.LoggerWithNodeAndJobInfo(node.id, job.ID)
+
+	node.verifyMutex.Lock()
+	defer node.verifyMutex.Unlock()
+
+	key := fmt.Sprintf("%s:%d", job.ID, jobEvent.ShardIndex)
+	if node.verifiedShards[key] {
+		return
+	}
+
+	jobState, err := node.controller.GetJobState(ctx, job.ID)
+	if err != nil {
+		threadLogger.Error().Msgf("error getting job state: %s", err)
+		return
+	}
+
+	localEvents, err := node.controller.GetJobLocalEvents(ctx, job.ID)
+	if err != nil {
+		threadLogger.Error().Msgf("error getting job local events: %s", err)
+		return
+	}
+
+	// we wait for every execution we want (which can be more than the
+	// deal's concurrency if the verifier asked for extra executions) and
+	// every bid we accepted to finish - the bids for the extra executions
+	// may not have been accepted yet when the first execution finishes
+	acceptedBids := 0
+	for _, localEvent := range localEvents {
+		if localEvent.EventName == executor.JobLocalEventBidAccepted && localEvent.ShardIndex == jobEvent.ShardIndex {
+			acceptedBids++
+		}
+	}
+	expectedExecutions := acceptedBids
+	concurrency := node.getShardConcurrency(job, jobEvent.ShardIndex)
+	if expectedExecutions < concurrency {
+		expectedExecutions = concurrency
+	}
+
+	executions := 0
+	results := []verifier.ShardResult{}
+	for _, nodeState := range jobState.Nodes {
+		shardState, ok := nodeState.Shards[jobEvent.ShardIndex]
+		if !ok {
+			continue
+		}
+		if shardState.State == executor.JobStateWaiting || shardState.State == executor.JobStateRunning {
+			// still waiting for some of the executions to finish
+			return
+		}
+		if !shardState.State.IsComplete() {
+			continue
+		}
+		executions++
+		if shardState.State == executor.JobStateComplete {
+			results = append(results, verifier.ShardResult{
+				NodeID:      shardState.NodeID,
+				ResultsID:   shardState.ResultsID,
+				ResultsHash: shardState.ResultsHash,
+			})
+		}
+	}
+	if executions < expectedExecutions {
+		// every node we gave the shard to has finished and we are only
+		// waiting for someone to bid on the extra executions - there may
+		// be no other node to run them so we don't wait forever
+		if executions >= acceptedBids && acceptedBids >= job.Deal.Concurrency {
+			node.awaitSample(job, jobEvent)
+		}
+		return
+	}
+	// every execution has already been finalized (or errored) so
+	// there is nothing left to verify
+	if len(results) == 0 {
+		return
+	}
+	node.verifiedShards[key] = true
+
+	jobVerifier, ok := node.verifiers[job.Spec.Verifier]
+	if !ok {
+		threadLogger.Error().Msgf("no verifier found for type: %s", job.Spec.Verifier.String())
+		return
+	}
+	verificationResults, err := jobVerifier.VerifyShard(ctx, job.ID, jobEvent.ShardIndex, results)
+	if err != nil {
+		threadLogger.Error().Msgf("error verifying results for shard %d: %s", jobEvent.ShardIndex, err)
+		return
+	}
+
+	for _, verificationResult := range verificationResults {
+		if verificationResult.Accepted {
+			log.Debug().Msgf("Requester node %s accepting results: %s %d %s",
+				node.id, job.ID, jobEvent.ShardIndex, verificationResult.NodeID)
+			err = node.controller.AcceptResults(ctx, job.ID, verificationResult.NodeID, jobEvent.ShardIndex)
+		} else {
+			log.Debug().Msgf("Requester node %s rejecting results: %s %d %s",
+				node.id, job.ID, jobEvent.ShardIndex, verificationResult.NodeID)
+			err = node.controller.RejectResults(ctx, job.ID, verificationResult.NodeID, jobEvent.ShardIndex)
+		}
+		if err != nil {
+			threadLogger.Error().Err(err)
+		}
+	}
+}
+
+// once every shard of the job has been finalized we won't be asked to
+// verify it again so we can forget which of its shards we verified
+func (node *RequesterNode) subscriptionEventResultsVerified(
+	ctx context.Context,
+	job executor.Job,
+) {
+	jobState, err := node.controller.GetJobState(ctx, job.ID)
+	if err != nil {
+		log.Error().Msgf("error getting job state: %s", err)
+		return
+	}
+	if hasJobFinished(job, jobState) {
+		node.forgetVerifiedShards(job.ID)
+	}
+}
+
+// give other nodes one bid collection window to bid on the extra
+// executions of a shard and if none of them do we drop the sample and
+// verify the shard with the results we have
+// must be called with the verifyMutex held
+func (node *RequesterNode) awaitSample(job executor.Job, jobEvent executor.JobEvent) {
+	key := fmt.Sprintf("%s:%d", job.ID, jobEvent.ShardIndex)
+	if node.awaitingSamples[key] {
+		return
+	}
+	node.awaitingSamples[key] = true
+	window := node.config.BidCollectionWindow
+	if window <= 0 {
+		window = DefaultBidCollectionWindow
+	}
+	time.AfterFunc(window, func() {
+		node.verifyMutex.Lock()
+		forgotten := !node.awaitingSamples[key]
+		node.verifyMutex.Unlock()
+		// the job finished and was forgotten in the meantime
+		if forgotten {
+			return
+		}
+		node.sampleMutex.Lock()
+		node.droppedSamples[key] = true
+		node.sampleMutex.Unlock()
+		log.Debug().Msgf("Requester node %s: no node bid on the extra executions of shard %s %d",
+			node.id, job.ID, jobEvent.ShardIndex)
+		// if a bid was accepted in the meantime this waits for it to
+		// finish otherwise it verifies the results we already have
+		node.subscriptionEventShardCompleted(context.Background(), job, jobEvent)
+	})
+}
+
+// how many nodes we want to run a shard - the deal's concurrency
+// plus any extra executions the job's verifier has asked for
+func (node *RequesterNode) getShardConcurrency(job executor.Job, shardIndex int) int {
+	concurrency := job.Deal.Concurrency
+	node.sampleMutex.Lock()
+	dropped := node.droppedSamples[fmt.Sprintf("%s:%d", job.ID, shardIndex)]
+	node.sampleMutex.Unlock()
+	if dropped {
+		return concurrency
+	}
+	if sampler, ok := node.verifiers[job.Spec.Verifier].(verifier.ShardSampler); ok {
+		concurrency += sampler.GetExtraExecutions(job.ID, shardIndex)
+	}
+	return concurrency
+}
+
+// how many times any of our verifiers have seen the
+// results of this node disagree with other nodes
+func (node *RequesterNode) getNodeDisagreements(nodeID string) int {
+	disagreements := 0
+	for _, v := range node.verifiers {
+		if tracker, ok := v.(verifier.DisagreementTracker); ok {
+			disagreements += tracker.GetDisagreements(nodeID)
+		}
+	}
+	return disagreements
+}
+
 func (node *RequesterNode) newSpanForJob(ctx context.Context, jobID, name string) (context.Context, trace.Span) {
 	return system.Span(ctx, "requestor_node/requester_node", name,
 		trace.WithSpanKind(trace.SpanKindInternal),
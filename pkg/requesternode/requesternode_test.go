@@ -0,0 +1,185 @@
+package requesternode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/transport/inprocess"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/noop"
+	"github.com/stretchr/testify/require"
+)
+
+// accepts everything but wants every shard run on one more node
+type samplingVerifier struct {
+	noop.Verifier
+}
+
+func (v *samplingVerifier) GetExtraExecutions(jobID string, shardIndex int) int {
+	return 1
+}
+
+// a requester node running a job with one shard that the
+// samplingVerifier wants run twice
+type testRequester struct {
+	t         *testing.T
+	ctx       context.Context
+	ctrl      *controller.Controller
+	transport *inprocess.InProcessTransport
+	node      *RequesterNode
+	job       executor.Job
+}
+
+func newTestRequester(t *testing.T, config RequesterNodeConfig) *testRequester {
+	system.InitConfigForTesting(t)
+	ctx := context.Background()
+	cm := system.NewCleanupManager()
+	t.Cleanup(cm.Cleanup)
+
+	transport, err := inprocess.NewInprocessTransport()
+	require.NoError(t, err)
+	datastore, err := inmemory.NewInMemoryDatastore()
+	require.NoError(t, err)
+	storageProviders, err := executor_util.NewNoopStorageProviders(cm)
+	require.NoError(t, err)
+	ctrl, err := controller.NewController(cm, datastore, transport, storageProviders)
+	require.NoError(t, err)
+
+	node, err := NewRequesterNode(cm, ctrl, map[verifier.VerifierType]verifier.Verifier{
+		verifier.VerifierNoop: &samplingVerifier{},
+	}, config)
+	require.NoError(t, err)
+	require.NoError(t, ctrl.Start(ctx))
+
+	j, err := ctrl.SubmitJob(ctx, executor.JobCreatePayload{
+		ClientID: "123",
+		Spec: executor.JobSpec{
+			Engine:   executor.EngineNoop,
+			Verifier: verifier.VerifierNoop,
+		},
+		Deal: executor.JobDeal{Concurrency: 1},
+	})
+	require.NoError(t, err)
+
+	return &testRequester{
+		t:         t,
+		ctx:       ctx,
+		ctrl:      ctrl,
+		transport: transport,
+		node:      node,
+		job:       j,
+	}
+}
+
+func (r *testRequester) publish(nodeID string, eventName executor.JobEventType) {
+	r.publishBid(nodeID, eventName, executor.JobBid{})
+}
+
+func (r *testRequester) publishBid(nodeID string, eventName executor.JobEventType, bid executor.JobBid) {
+	require.NoError(r.t, r.transport.Publish(r.ctx, executor.JobEvent{
+		JobID:        r.job.ID,
+		SourceNodeID: nodeID,
+		EventName:    eventName,
+		JobBid:       bid,
+		ResultsID:    "results",
+		EventTime:    time.Now(),
+	}))
+}
+
+func (r *testRequester) shardState(nodeID string) executor.JobStateType {
+	jobState, err := r.ctrl.GetJobState(r.ctx, r.job.ID)
+	require.NoError(r.t, err)
+	nodeState, ok := jobState.Nodes[nodeID]
+	if !ok {
+		return executor.JobStateType(0)
+	}
+	return nodeState.Shards[0].State
+}
+
+func (r *testRequester) isFinalized() bool {
+	jobState, err := r.ctrl.GetJobState(r.ctx, r.job.ID)
+	require.NoError(r.t, err)
+	finalized, err := job.WaitForFinalizedShards(r.job)(jobState)
+	return err == nil && finalized
+}
+
+func TestVerifyWaitsForSampledExecutions(t *testing.T) {
+	r := newTestRequester(t, RequesterNodeConfig{})
+
+	r.publish("node-a", executor.JobEventBid)
+	require.Eventually(t, func() bool {
+		return r.shardState("node-a") == executor.JobStateWaiting
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// nobody has even bid on the sampled execution yet so the shard
+	// is not verified
+	r.publish("node-a", executor.JobEventCompleted)
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(t, executor.JobStateComplete, r.shardState("node-a"))
+
+	r.publish("node-b", executor.JobEventBid)
+	require.Eventually(t, func() bool {
+		return r.shardState("node-b") == executor.JobStateWaiting
+	}, 5*time.Second, 10*time.Millisecond)
+	r.publish("node-b", executor.JobEventCompleted)
+	require.Eventually(t, r.isFinalized, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, executor.JobStateFinalized, r.shardState("node-a"))
+	require.Equal(t, executor.JobStateFinalized, r.shardState("node-b"))
+
+	// once the job has finished we don't need to remember it was verified
+	require.Eventually(t, func() bool {
+		r.node.verifyMutex.Lock()
+		defer r.node.verifyMutex.Unlock()
+		return len(r.node.verifiedShards) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestVerifyDropsSampleNobodyBidsOn(t *testing.T) {
+	r := newTestRequester(t, RequesterNodeConfig{
+		BidCollectionWindow: 100 * time.Millisecond,
+	})
+
+	// the only node on the network runs the shard and nobody is
+	// left to bid on the sampled execution
+	r.publish("node-a", executor.JobEventBid)
+	require.Eventually(t, func() bool {
+		return r.shardState("node-a") == executor.JobStateWaiting
+	}, 5*time.Second, 10*time.Millisecond)
+	r.publish("node-a", executor.JobEventCompleted)
+
+	require.Eventually(t, r.isFinalized, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, executor.JobStateFinalized, r.shardState("node-a"))
+
+	// a bid that turns up after we gave up on the sample is rejected
+	r.publish("node-b", executor.JobEventBid)
+	require.Eventually(t, func() bool {
+		return r.shardState("node-b") == executor.JobStateCancelled
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSampledExecutionsUseTheSameRuntime(t *testing.T) {
+	r := newTestRequester(t, RequesterNodeConfig{})
+
+	r.publishBid("node-a", executor.JobEventBid, executor.JobBid{Runtime: "pyodide"})
+	require.Eventually(t, func() bool {
+		return r.shardState("node-a") == executor.JobStateWaiting
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// its results could differ from node-a's for no fault of either
+	r.publishBid("node-b", executor.JobEventBid, executor.JobBid{Runtime: "cpython-wasi"})
+	require.Eventually(t, func() bool {
+		return r.shardState("node-b") == executor.JobStateCancelled
+	}, 5*time.Second, 10*time.Millisecond)
+
+	r.publishBid("node-c", executor.JobEventBid, executor.JobBid{Runtime: "pyodide"})
+	require.Eventually(t, func() bool {
+		return r.shardState("node-c") == executor.JobStateWaiting
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/filecoin-project/bacalhau/pkg/computenode"
 	"github.com/filecoin-project/bacalhau/pkg/config"
+	"github.com/filecoin-project/bacalhau/pkg/docker"
 	noop_executor "github.com/filecoin-project/bacalhau/pkg/executor/noop"
 	_ "github.com/filecoin-project/bacalhau/pkg/logger"
 	"github.com/filecoin-project/bacalhau/pkg/system"
@@ -54,7 +55,7 @@ func (suite *ComputeNodeJobSelectionSuite) TestJobSelectionNoVolumes() {
 		}, noop_executor.ExecutorConfig{})
 		defer cm.Cleanup()
 
-		result, _, err := computeNode.SelectJob(context.Background(), GetProbeData(""))
+		result, _, _, err := computeNode.SelectJob(context.Background(), GetProbeData(""))
 		require.NoError(suite.T(), err)
 		require.Equal(suite.T(), result, expectedResult)
 	}
@@ -90,7 +91,7 @@ func (suite *ComputeNodeJobSelectionSuite) TestJobSelectionLocality() {
 			require.NoError(suite.T(), err)
 		}
 
-		result, _, err := computeNode.SelectJob(context.Background(), GetProbeData(cid))
+		result, _, _, err := computeNode.SelectJob(context.Background(), GetProbeData(cid))
 		require.NoError(suite.T(), err)
 		require.Equal(suite.T(), result, expectedResult)
 	}
@@ -130,7 +131,7 @@ func (suite *ComputeNodeJobSelectionSuite) TestJobSelectionHttp() {
 		}, noop_executor.ExecutorConfig{})
 		defer cm.Cleanup()
 
-		result, _, err := computeNode.SelectJob(context.Background(), GetProbeData(""))
+		result, _, _, err := computeNode.SelectJob(context.Background(), GetProbeData(""))
 		require.NoError(suite.T(), err)
 		require.Equal(suite.T(), result, expectedResult)
 	}
@@ -152,7 +153,7 @@ func (suite *ComputeNodeJobSelectionSuite) TestJobSelectionExec() {
 		}, noop_executor.ExecutorConfig{})
 		defer cm.Cleanup()
 
-		result, _, err := computeNode.SelectJob(context.Background(), GetProbeData(""))
+		result, _, _, err := computeNode.SelectJob(context.Background(), GetProbeData(""))
 		require.NoError(suite.T(), err)
 		require.Equal(suite.T(), result, expectedResult)
 	}
@@ -165,9 +166,54 @@ func (suite *ComputeNodeJobSelectionSuite) TestJobSelectionEmptySpec() {
 	computeNode, _, _, cm := SetupTestNoop(suite.T(), computenode.ComputeNodeConfig{}, noop_executor.ExecutorConfig{})
 	defer cm.Cleanup()
 
-	_, _, err := computeNode.SelectJob(context.Background(), computenode.JobSelectionPolicyProbeData{
+	_, _, _, err := computeNode.SelectJob(context.Background(), computenode.JobSelectionPolicyProbeData{
 		NodeID: "test",
 		JobID:  "test",
 	})
 	require.Error(suite.T(), err)
 }
+
+func (suite *ComputeNodeJobSelectionSuite) TestJobSelectionImagePolicy() {
+	runTest := func(policy docker.ImagePolicy, image string, expectedResult bool) {
+		computeNode, _, _, cm := SetupTestNoop(suite.T(), computenode.ComputeNodeConfig{
+			ImagePolicy: policy,
+		}, noop_executor.ExecutorConfig{})
+		defer cm.Cleanup()
+
+		probeData := GetProbeData("")
+		probeData.Spec.Docker.Image = image
+		result, _, _, err := computeNode.SelectJob(context.Background(), probeData)
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expectedResult, result, "%s %+v", image, policy)
+	}
+
+	pinned := "ubuntu@sha256:26c68657ccce2cb0a31b330cb0be2b5e108d467f641c62e13ab40cbec258c68d"
+
+	runTest(docker.ImagePolicy{}, "ubuntu", true)
+	runTest(docker.ImagePolicy{Allow: []string{"ubuntu"}}, "ubuntu", true)
+	runTest(docker.ImagePolicy{Allow: []string{"registry.example.com/*"}}, "ubuntu", false)
+	runTest(docker.ImagePolicy{Deny: []string{"ubuntu"}}, "ubuntu:22.04", false)
+	runTest(docker.ImagePolicy{RequireDigest: true}, "ubuntu", false)
+	runTest(docker.ImagePolicy{RequireDigest: true}, pinned, true)
+}
+
+func (suite *ComputeNodeJobSelectionSuite) TestJobSelectionNodeSelector() {
+	runTest := func(nodeLabels map[string]string, selector string, expectedResult bool) {
+		computeNode, _, _, cm := SetupTestNoop(suite.T(), computenode.ComputeNodeConfig{
+			Labels: nodeLabels,
+		}, noop_executor.ExecutorConfig{})
+		defer cm.Cleanup()
+
+		probeData := GetProbeData("")
+		probeData.Spec.NodeSelector = selector
+		result, _, _, err := computeNode.SelectJob(context.Background(), probeData)
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), expectedResult, result, "%s %+v", selector, nodeLabels)
+	}
+
+	runTest(map[string]string{}, "", true)
+	runTest(map[string]string{}, "region=us-east-1", false)
+	runTest(map[string]string{"region": "us-east-1"}, "region=us-east-1", true)
+	runTest(map[string]string{"region": "us-east-1", "gpu": "a100"}, "gpu in (a100, h100)", true)
+	runTest(map[string]string{"region": "us-east-1", "spot": "true"}, "region=us-east-1,!spot", false)
+}
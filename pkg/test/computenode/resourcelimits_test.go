@@ -17,7 +17,10 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/controller"
 	devstack "github.com/filecoin-project/bacalhau/pkg/devstack"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	docker_executor "github.com/filecoin-project/bacalhau/pkg/executor/docker"
 	noop_executor "github.com/filecoin-project/bacalhau/pkg/executor/noop"
+	"github.com/filecoin-project/bacalhau/pkg/executor/podman"
+	pythonwasm "github.com/filecoin-project/bacalhau/pkg/executor/python_wasm"
 	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
 	"github.com/filecoin-project/bacalhau/pkg/job"
 	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
@@ -73,7 +76,7 @@ func (suite *ComputeNodeResourceLimitsSuite) TestJobResourceLimits() {
 		job := GetProbeData("")
 		job.Spec.Resources = jobResources
 
-		result, _, err := computeNode.SelectJob(context.Background(), job)
+		result, _, _, err := computeNode.SelectJob(context.Background(), job)
 		require.NoError(suite.T(), err)
 
 		require.Equal(suite.T(), expectedResult, result, fmt.Sprintf("the expcted result was %v, but got %v -- %+v vs %+v", expectedResult, result, jobResources, jobResourceLimits))
@@ -477,7 +480,7 @@ func (suite *ComputeNodeResourceLimitsSuite) TestDockerResourceLimitsDisk() {
 
 		cid, err := ipfsStack.AddTextToNodes(1, []byte(text))
 
-		result, _, err := computeNode.SelectJob(context.Background(), computenode.JobSelectionPolicyProbeData{
+		result, _, _, err := computeNode.SelectJob(context.Background(), computenode.JobSelectionPolicyProbeData{
 			NodeID: "test",
 			JobID:  "test",
 			Spec: executor.JobSpec{
@@ -538,7 +541,8 @@ func (suite *ComputeNodeResourceLimitsSuite) TestGetVolumeSize() {
 		storageProviders, err := executor_util.NewStandardStorageProviders(cm, apiAddress)
 		require.NoError(suite.T(), err)
 
-		executors, err := executor_util.NewStandardExecutors(cm, apiAddress, "devstacknode0")
+		executors, err := executor_util.NewStandardExecutors(cm, apiAddress, "devstacknode0", docker_executor.ExecutorConfig{},
+			podman.ExecutorConfig{}, pythonwasm.ExecutorConfig{})
 		require.NoError(suite.T(), err)
 
 		verifiers, err := verifier_util.NewIPFSVerifiers(
@@ -546,6 +550,7 @@ func (suite *ComputeNodeResourceLimitsSuite) TestGetVolumeSize() {
 			apiAddress,
 			job.NewNoopJobLoader(),
 			job.NewNoopStateLoader(),
+			verifier_util.VerifierConfig{},
 		)
 		require.NoError(suite.T(), err)
 
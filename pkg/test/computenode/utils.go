@@ -11,7 +11,10 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/controller"
 	devstack "github.com/filecoin-project/bacalhau/pkg/devstack"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	docker_executor "github.com/filecoin-project/bacalhau/pkg/executor/docker"
 	noop_executor "github.com/filecoin-project/bacalhau/pkg/executor/noop"
+	"github.com/filecoin-project/bacalhau/pkg/executor/podman"
+	pythonwasm "github.com/filecoin-project/bacalhau/pkg/executor/python_wasm"
 	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
 	"github.com/filecoin-project/bacalhau/pkg/job"
 	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
@@ -50,6 +53,9 @@ func SetupTestDockerIpfs(
 		cm,
 		apiAddress,
 		fmt.Sprintf("devstacknode0-%s", ipfsID),
+		docker_executor.ExecutorConfig{},
+		podman.ExecutorConfig{},
+		pythonwasm.ExecutorConfig{},
 	)
 	require.NoError(t, err)
 
@@ -58,6 +64,7 @@ func SetupTestDockerIpfs(
 		apiAddress,
 		job.NewNoopJobLoader(),
 		job.NewNoopStateLoader(),
+		verifier_util.VerifierConfig{},
 	)
 	require.NoError(t, err)
 
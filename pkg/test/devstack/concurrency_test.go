@@ -87,7 +87,7 @@ func (suite *DevstackConcurrencySuite) TestConcurrencyLimit() {
 			executor.JobStateError,
 		}),
 		job.WaitForJobStates(map[executor.JobStateType]int{
-			executor.JobStateComplete:  2,
+			executor.JobStateFinalized: 2,
 			executor.JobStateCancelled: 1,
 		}),
 	)
@@ -1,7 +1,6 @@
 package devstack
 
 import (
-	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -11,6 +10,9 @@ import (
 	"github.com/filecoin-project/bacalhau/pkg/controller"
 	"github.com/filecoin-project/bacalhau/pkg/devstack"
 	"github.com/filecoin-project/bacalhau/pkg/executor"
+	docker_executor "github.com/filecoin-project/bacalhau/pkg/executor/docker"
+	"github.com/filecoin-project/bacalhau/pkg/executor/podman"
+	pythonwasm "github.com/filecoin-project/bacalhau/pkg/executor/python_wasm"
 	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
 	_ "github.com/filecoin-project/bacalhau/pkg/logger"
 	"github.com/filecoin-project/bacalhau/pkg/storage"
@@ -50,6 +52,9 @@ func SetupTest(
 			cm,
 			ipfsMultiAddress,
 			fmt.Sprintf("devstacknode%d-%s", nodeIndex, ipfsSuffix),
+			docker_executor.ExecutorConfig{},
+			podman.ExecutorConfig{},
+			pythonwasm.ExecutorConfig{},
 		)
 	}
 	getVerifiers := func(
@@ -60,13 +65,7 @@ func SetupTest(
 		map[verifier.VerifierType]verifier.Verifier,
 		error,
 	) {
-		jobLoader := func(ctx context.Context, id string) (executor.Job, error) {
-			return ctrl.GetJob(ctx, id)
-		}
-		stateLoader := func(ctx context.Context, id string) (executor.JobState, error) {
-			return ctrl.GetJobState(ctx, id)
-		}
-		return verifier_util.NewIPFSVerifiers(cm, ipfsMultiAddress, jobLoader, stateLoader)
+		return verifier_util.NewStandardVerifiers(cm, ipfsMultiAddress, ctrl, verifier_util.VerifierConfig{})
 	}
 	stack, err := devstack.NewDevStack(
 		cm,
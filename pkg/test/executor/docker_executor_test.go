@@ -70,7 +70,9 @@ func dockerExecutorStorageTest(
 			fmt.Sprintf("dockertest-%s", stack.Nodes[0].IpfsNode.ID()),
 			map[storage.StorageSourceType]storage.StorageProvider{
 				storage.StorageSourceIPFS: storageDriver,
-			})
+			},
+			docker.ExecutorConfig{},
+		)
 		require.NoError(t, err)
 
 		inputStorageList, err := testCase.SetupStorage(
@@ -0,0 +1,167 @@
+package requesternode
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
+	_ "github.com/filecoin-project/bacalhau/pkg/logger"
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/transport/inprocess"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	verifier_util "github.com/filecoin-project/bacalhau/pkg/verifier/util"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type BidStrategySuite struct {
+	suite.Suite
+}
+
+func TestBidStrategySuite(t *testing.T) {
+	suite.Run(t, new(BidStrategySuite))
+}
+
+// Before each test
+func (suite *BidStrategySuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+}
+
+type testBid struct {
+	nodeID string
+	bid    executor.JobBid
+}
+
+// submit a noop job, bid on it from the given nodes and return
+// the ids of the nodes whose bids were accepted
+func (suite *BidStrategySuite) runBids(typ requesternode.BidStrategyType, concurrency int, bids []testBid) []string {
+	ctx := context.Background()
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	transport, err := inprocess.NewInprocessTransport()
+	require.NoError(suite.T(), err)
+
+	datastore, err := inmemory.NewInMemoryDatastore()
+	require.NoError(suite.T(), err)
+
+	storageProviders, err := executor_util.NewNoopStorageProviders(cm)
+	require.NoError(suite.T(), err)
+
+	ctrl, err := controller.NewController(cm, datastore, transport, storageProviders)
+	require.NoError(suite.T(), err)
+
+	verifiers, err := verifier_util.NewNoopVerifiers(cm)
+	require.NoError(suite.T(), err)
+
+	strategy, err := requesternode.NewBidStrategy(typ)
+	require.NoError(suite.T(), err)
+
+	_, err = requesternode.NewRequesterNode(
+		cm,
+		ctrl,
+		verifiers,
+		requesternode.RequesterNodeConfig{
+			BidCollectionWindow: time.Millisecond * 100,
+			BidStrategy:         strategy,
+		},
+	)
+	require.NoError(suite.T(), err)
+
+	err = ctrl.Start(ctx)
+	require.NoError(suite.T(), err)
+
+	j, err := ctrl.SubmitJob(ctx, executor.JobCreatePayload{
+		ClientID: "123",
+		Spec: executor.JobSpec{
+			Engine:   executor.EngineNoop,
+			Verifier: verifier.VerifierNoop,
+		},
+		Deal: executor.JobDeal{
+			Concurrency: concurrency,
+		},
+	})
+	require.NoError(suite.T(), err)
+
+	for _, testBid := range bids {
+		err = transport.Publish(ctx, executor.JobEvent{
+			JobID:        j.ID,
+			SourceNodeID: testBid.nodeID,
+			EventName:    executor.JobEventBid,
+			JobBid:       testBid.bid,
+			EventTime:    time.Now(),
+		})
+		require.NoError(suite.T(), err)
+	}
+
+	resolver := job.NewStateResolver(ctrl.GetJob, ctrl.GetJobState)
+	resolver.SetWaitTime(100, time.Millisecond*100)
+
+	// a node has one state per shard however many times it bids
+	nodeIDs := map[string]bool{}
+	for _, testBid := range bids {
+		nodeIDs[testBid.nodeID] = true
+	}
+
+	// wait for the collection window to close and every bid to be decided
+	err = resolver.Wait(ctx, j.ID, len(nodeIDs), func(jobState executor.JobState) (bool, error) {
+		shardStates := job.FlattenShardStates(jobState)
+		if len(shardStates) < len(nodeIDs) {
+			return false, nil
+		}
+		for _, shardState := range shardStates {
+			if shardState.State == executor.JobStateBidding {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	require.NoError(suite.T(), err)
+
+	localEvents, err := ctrl.GetJobLocalEvents(ctx, j.ID)
+	require.NoError(suite.T(), err)
+	acceptedNodeIDs := []string{}
+	for _, localEvent := range localEvents {
+		if localEvent.EventName == executor.JobLocalEventBidAccepted {
+			acceptedNodeIDs = append(acceptedNodeIDs, localEvent.TargetNodeID)
+		}
+	}
+	sort.Strings(acceptedNodeIDs)
+	return acceptedNodeIDs
+}
+
+func (suite *BidStrategySuite) TestLocalityStrategyPrefersNodesWithTheData() {
+	accepted := suite.runBids(requesternode.BidStrategyLocality, 2, []testBid{
+		{"node-a", executor.JobBid{Price: 1}},
+		{"node-b", executor.JobBid{Price: 5, LocalInputs: 2}},
+		{"node-c", executor.JobBid{Price: 1, LocalInputs: 1}},
+	})
+	require.Equal(suite.T(), []string{"node-b", "node-c"}, accepted)
+}
+
+func (suite *BidStrategySuite) TestLoadStrategyPrefersIdleNodes() {
+	accepted := suite.runBids(requesternode.BidStrategyLoad, 1, []testBid{
+		{"node-a", executor.JobBid{Load: 0.8}},
+		{"node-b", executor.JobBid{Load: 0.1}},
+		{"node-c", executor.JobBid{Load: 0.5}},
+	})
+	require.Equal(suite.T(), []string{"node-b"}, accepted)
+}
+
+func (suite *BidStrategySuite) TestConcurrentExecutionsLandOnDistinctNodes() {
+	// node-a is the cheapest but bids twice - it only gets one execution
+	// and its second bid is not allowed to reject the first
+	accepted := suite.runBids(requesternode.BidStrategyPrice, 2, []testBid{
+		{"node-a", executor.JobBid{Price: 1}},
+		{"node-a", executor.JobBid{Price: 1}},
+		{"node-b", executor.JobBid{Price: 5}},
+	})
+	require.Equal(suite.T(), []string{"node-a", "node-b"}, accepted)
+}
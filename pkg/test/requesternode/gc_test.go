@@ -0,0 +1,224 @@
+package requesternode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sync "github.com/lukemarsden/golang-mutex-tracer"
+
+	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/transport/inprocess"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	verifier_util "github.com/filecoin-project/bacalhau/pkg/verifier/util"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const contextCID = "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+const resultsCID = "QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"
+const combinedCID = "QmQPeNsJPyVWPFDVHb9Yw8Hf5PSD7wZZBTd4VfzZp2gtZr"
+
+type JobGCSuite struct {
+	suite.Suite
+}
+
+func TestJobGCSuite(t *testing.T) {
+	suite.Run(t, new(JobGCSuite))
+}
+
+// Before each test
+func (suite *JobGCSuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+}
+
+type fakeUnpinner struct {
+	unpinned []string
+	mtx      sync.Mutex
+}
+
+func (f *fakeUnpinner) Unpin(ctx context.Context, cid string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.unpinned = append(f.unpinned, cid)
+	return nil
+}
+
+func (f *fakeUnpinner) getUnpinned() []string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return append([]string{}, f.unpinned...)
+}
+
+// keeps the results itself so they have to be deleted with the job
+type fakeResultsCleaner struct {
+	verifier.Verifier
+	deleted []string
+	mtx     sync.Mutex
+}
+
+func (f *fakeResultsCleaner) DeleteJobResults(ctx context.Context, jobID string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.deleted = append(f.deleted, jobID)
+	return nil
+}
+
+func (f *fakeResultsCleaner) getDeleted() []string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return append([]string{}, f.deleted...)
+}
+
+type gcTestNode struct {
+	ctrl      *controller.Controller
+	transport *inprocess.InProcessTransport
+	unpinner  *fakeUnpinner
+	cleaner   *fakeResultsCleaner
+}
+
+func (suite *JobGCSuite) setupNode(ctx context.Context, cm *system.CleanupManager, retention time.Duration) gcTestNode {
+	transport, err := inprocess.NewInprocessTransport()
+	require.NoError(suite.T(), err)
+
+	datastore, err := inmemory.NewInMemoryDatastore()
+	require.NoError(suite.T(), err)
+
+	storageProviders, err := executor_util.NewNoopStorageProviders(cm)
+	require.NoError(suite.T(), err)
+
+	ctrl, err := controller.NewController(cm, datastore, transport, storageProviders)
+	require.NoError(suite.T(), err)
+
+	verifiers, err := verifier_util.NewNoopVerifiers(cm)
+	require.NoError(suite.T(), err)
+	cleaner := &fakeResultsCleaner{Verifier: verifiers[verifier.VerifierNoop]}
+	verifiers[verifier.VerifierNoop] = cleaner
+
+	unpinner := &fakeUnpinner{}
+	_, err = requesternode.NewRequesterNode(
+		cm,
+		ctrl,
+		verifiers,
+		requesternode.RequesterNodeConfig{
+			JobGC: requesternode.JobGCConfig{
+				Retention: retention,
+				Interval:  time.Millisecond * 50,
+				Unpinner:  unpinner,
+			},
+		},
+	)
+	require.NoError(suite.T(), err)
+
+	err = ctrl.Start(ctx)
+	require.NoError(suite.T(), err)
+
+	return gcTestNode{
+		ctrl:      ctrl,
+		transport: transport,
+		unpinner:  unpinner,
+		cleaner:   cleaner,
+	}
+}
+
+func (suite *JobGCSuite) submit(ctx context.Context, node gcTestNode) executor.Job {
+	j, err := node.ctrl.SubmitJob(ctx, executor.JobCreatePayload{
+		ClientID: "123",
+		Spec: executor.JobSpec{
+			Engine:   executor.EngineNoop,
+			Verifier: verifier.VerifierNoop,
+			Contexts: []storage.StorageSpec{
+				{Engine: storage.StorageSourceIPFS, Cid: contextCID, Path: "/job"},
+			},
+		},
+		Deal: executor.JobDeal{
+			Concurrency: 1,
+		},
+	})
+	require.NoError(suite.T(), err)
+	return j
+}
+
+func (suite *JobGCSuite) publish(ctx context.Context, node gcTestNode, j executor.Job, event executor.JobEvent) {
+	event.JobID = j.ID
+	event.SourceNodeID = "node-a"
+	event.EventTime = time.Now()
+	err := node.transport.Publish(ctx, event)
+	require.NoError(suite.T(), err)
+	time.Sleep(time.Millisecond * 10)
+}
+
+func (suite *JobGCSuite) TestFinishedJobsAreForgotten() {
+	ctx := context.Background()
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	node := suite.setupNode(ctx, cm, time.Millisecond*200)
+	ctrl := node.ctrl
+
+	finishedJob := suite.submit(ctx, node)
+	// nobody bids on this one so it never finishes
+	unfinishedJob := suite.submit(ctx, node)
+
+	suite.publish(ctx, node, finishedJob, executor.JobEvent{EventName: executor.JobEventBid})
+	suite.publish(ctx, node, finishedJob, executor.JobEvent{EventName: executor.JobEventCompleted, ResultsID: resultsCID})
+
+	resolver := job.NewStateResolver(ctrl.GetJob, ctrl.GetJobState)
+	resolver.SetWaitTime(100, time.Millisecond*10)
+	err := resolver.WaitUntilComplete(ctx, finishedJob.ID)
+	require.NoError(suite.T(), err)
+	err = ctrl.RecordCombinedResults(ctx, finishedJob.ID, combinedCID)
+	require.NoError(suite.T(), err)
+
+	require.Eventually(suite.T(), func() bool {
+		_, err := ctrl.GetJob(ctx, finishedJob.ID)
+		return err != nil
+	}, time.Second*5, time.Millisecond*50)
+
+	_, err = ctrl.GetJobState(ctx, finishedJob.ID)
+	require.Error(suite.T(), err)
+	// the job that hasn't finished still uses the build context
+	require.ElementsMatch(suite.T(), []string{resultsCID, combinedCID}, node.unpinner.getUnpinned())
+	require.Equal(suite.T(), []string{finishedJob.ID}, node.cleaner.getDeleted())
+
+	// give it a few more goes at the job that hasn't finished
+	time.Sleep(time.Millisecond * 300)
+	_, err = ctrl.GetJob(ctx, unfinishedJob.ID)
+	require.NoError(suite.T(), err)
+}
+
+func (suite *JobGCSuite) TestRetentionStartsWhenJobFinishes() {
+	ctx := context.Background()
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	node := suite.setupNode(ctx, cm, time.Millisecond*300)
+	ctrl := node.ctrl
+
+	j := suite.submit(ctx, node)
+	suite.publish(ctx, node, j, executor.JobEvent{EventName: executor.JobEventBid})
+	// the job runs for longer than the retention period
+	time.Sleep(time.Millisecond * 400)
+	suite.publish(ctx, node, j, executor.JobEvent{EventName: executor.JobEventCompleted, ResultsID: resultsCID})
+
+	resolver := job.NewStateResolver(ctrl.GetJob, ctrl.GetJobState)
+	resolver.SetWaitTime(100, time.Millisecond*10)
+	err := resolver.WaitUntilComplete(ctx, j.ID)
+	require.NoError(suite.T(), err)
+
+	time.Sleep(time.Millisecond * 100)
+	_, err = ctrl.GetJob(ctx, j.ID)
+	require.NoError(suite.T(), err)
+
+	require.Eventually(suite.T(), func() bool {
+		_, err := ctrl.GetJob(ctx, j.ID)
+		return err != nil
+	}, time.Second*5, time.Millisecond*50)
+}
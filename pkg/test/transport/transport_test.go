@@ -51,7 +51,12 @@ func (suite *TransportSuite) TearDownAllSuite() {
 
 }
 
-func setupTest(t *testing.T) (
+func setupTest(
+	t *testing.T,
+	//nolint:gocritic
+	computeNodeConfig computenode.ComputeNodeConfig,
+	requesterNodeConfig requesternode.RequesterNodeConfig,
+) (
 	*inprocess.InProcessTransport,
 	*executorNoop.Executor,
 	*verifier_noop.Verifier,
@@ -95,7 +100,7 @@ func setupTest(t *testing.T) (
 		ctrl,
 		executors,
 		verifiers,
-		computenode.NewDefaultComputeNodeConfig(),
+		computeNodeConfig,
 	)
 	require.NoError(t, err)
 
@@ -103,7 +108,7 @@ func setupTest(t *testing.T) (
 		cm,
 		ctrl,
 		verifiers,
-		requesternode.RequesterNodeConfig{},
+		requesterNodeConfig,
 	)
 	require.NoError(t, err)
 
@@ -148,7 +153,11 @@ func (suite *TransportSuite) TestTransportSanity() {
 
 func (suite *TransportSuite) TestSchedulerSubmitJob() {
 	ctx := context.Background()
-	_, noopExecutor, _, ctrl, cm := setupTest(suite.T())
+	_, noopExecutor, _, ctrl, cm := setupTest(
+		suite.T(),
+		computenode.NewDefaultComputeNodeConfig(),
+		requesternode.RequesterNodeConfig{},
+	)
 	defer cm.Cleanup()
 
 	spec := executor.JobSpec{
@@ -186,7 +195,11 @@ func (suite *TransportSuite) TestSchedulerSubmitJob() {
 
 func (suite *TransportSuite) TestTransportEvents() {
 	ctx := context.Background()
-	transport, _, _, ctrl, cm := setupTest(suite.T())
+	transport, _, _, ctrl, cm := setupTest(
+		suite.T(),
+		computenode.NewDefaultComputeNodeConfig(),
+		requesternode.RequesterNodeConfig{},
+	)
 	defer cm.Cleanup()
 
 	spec := executor.JobSpec{
@@ -223,6 +236,7 @@ func (suite *TransportSuite) TestTransportEvents() {
 		executor.JobEventBid.String(),
 		executor.JobEventBidAccepted.String(),
 		executor.JobEventCompleted.String(),
+		executor.JobEventResultsAccepted.String(),
 	}
 	actualEventNames := []string{}
 
@@ -235,3 +249,51 @@ func (suite *TransportSuite) TestTransportEvents() {
 
 	require.True(suite.T(), reflect.DeepEqual(expectedEventNames, actualEventNames), "event list is correct")
 }
+
+func (suite *TransportSuite) TestTransportBidTerms() {
+	ctx := context.Background()
+	computeNodeConfig := computenode.NewDefaultComputeNodeConfig()
+	computeNodeConfig.JobSelectionPolicy.ProbeExec = `echo '{"bid_price": 3.5, "eta_seconds": 10, "reason": "test"}'`
+	transport, noopExecutor, _, ctrl, cm := setupTest(
+		suite.T(),
+		computeNodeConfig,
+		requesternode.RequesterNodeConfig{
+			BidCollectionWindow: time.Millisecond * 100,
+		},
+	)
+	defer cm.Cleanup()
+
+	spec := executor.JobSpec{
+		Engine:   executor.EngineNoop,
+		Verifier: verifier.VerifierNoop,
+		Docker: executor.JobSpecDocker{
+			Image:      "image",
+			Entrypoint: []string{"entrypoint"},
+		},
+	}
+
+	payload := executor.JobCreatePayload{
+		ClientID: "123",
+		Spec:     spec,
+		Deal: executor.JobDeal{
+			Concurrency: 1,
+		},
+	}
+
+	_, err := ctrl.SubmitJob(ctx, payload)
+	require.NoError(suite.T(), err)
+	time.Sleep(time.Second * 1)
+
+	// the bid was accepted once the collection window closed
+	require.Equal(suite.T(), 1, len(noopExecutor.Jobs))
+
+	bids := []executor.JobBid{}
+	for _, event := range transport.GetEvents() {
+		if event.EventName == executor.JobEventBid {
+			bids = append(bids, event.JobBid)
+		}
+	}
+	require.Equal(suite.T(), []executor.JobBid{
+		{Price: 3.5, ETASeconds: 10, Reason: "test"},
+	}, bids)
+}
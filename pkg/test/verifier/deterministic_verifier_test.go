@@ -0,0 +1,129 @@
+package verifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
+	_ "github.com/filecoin-project/bacalhau/pkg/logger"
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/transport/inprocess"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/deterministic"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifierDeterministicSuite struct {
+	suite.Suite
+}
+
+func TestVerifierDeterministicSuite(t *testing.T) {
+	suite.Run(t, new(VerifierDeterministicSuite))
+}
+
+// Before each test
+func (suite *VerifierDeterministicSuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+}
+
+func (suite *VerifierDeterministicSuite) TestRequesterAcceptsMajorityResults() {
+	ctx := context.Background()
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	transport, err := inprocess.NewInprocessTransport()
+	require.NoError(suite.T(), err)
+
+	datastore, err := inmemory.NewInMemoryDatastore()
+	require.NoError(suite.T(), err)
+
+	storageProviders, err := executor_util.NewNoopStorageProviders(cm)
+	require.NoError(suite.T(), err)
+
+	ctrl, err := controller.NewController(cm, datastore, transport, storageProviders)
+	require.NoError(suite.T(), err)
+
+	resolver := job.NewStateResolver(
+		func(ctx context.Context, id string) (executor.Job, error) {
+			return ctrl.GetJob(ctx, id)
+		},
+		func(ctx context.Context, id string) (executor.JobState, error) {
+			return ctrl.GetJobState(ctx, id)
+		},
+	)
+	resolver.SetWaitTime(100, time.Millisecond*100)
+
+	// nothing is listening on this address - we never publish anything
+	v, err := deterministic.NewVerifier(cm, "/ip4/127.0.0.1/tcp/1", ctrl.GetJob, ctrl.GetJobState)
+	require.NoError(suite.T(), err)
+
+	_, err = requesternode.NewRequesterNode(
+		cm,
+		ctrl,
+		map[verifier.VerifierType]verifier.Verifier{
+			verifier.VerifierDeterministic: v,
+		},
+		requesternode.RequesterNodeConfig{},
+	)
+	require.NoError(suite.T(), err)
+
+	err = ctrl.Start(ctx)
+	require.NoError(suite.T(), err)
+
+	j, err := ctrl.SubmitJob(ctx, executor.JobCreatePayload{
+		ClientID: "123",
+		Spec: executor.JobSpec{
+			Engine:   executor.EngineNoop,
+			Verifier: verifier.VerifierDeterministic,
+		},
+		Deal: executor.JobDeal{
+			Concurrency: 3,
+		},
+	})
+	require.NoError(suite.T(), err)
+
+	// pretend three compute nodes ran the job and one of them
+	// came back with something different
+	results := map[string]string{
+		"node-a": "apples",
+		"node-b": "oranges",
+		"node-c": "apples",
+	}
+	for nodeID, resultsID := range results {
+		err = transport.Publish(ctx, executor.JobEvent{
+			JobID:        j.ID,
+			SourceNodeID: nodeID,
+			EventName:    executor.JobEventCompleted,
+			ResultsID:    resultsID,
+			EventTime:    time.Now(),
+		})
+		require.NoError(suite.T(), err)
+	}
+
+	err = resolver.WaitUntilComplete(ctx, j.ID)
+	require.NoError(suite.T(), err)
+
+	jobState, err := ctrl.GetJobState(ctx, j.ID)
+	require.NoError(suite.T(), err)
+	for nodeID, resultsID := range results {
+		shardState := jobState.Nodes[nodeID].Shards[0]
+		require.Equal(suite.T(), resultsID == "apples", shardState.ResultsAccepted, nodeID)
+	}
+
+	resultSet, err := v.GetJobResultSet(ctx, j.ID)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 1, len(resultSet))
+	require.Equal(suite.T(), "apples", resultSet[0].Cid)
+
+	shardResults, err := resolver.GetResults(ctx, j.ID)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 1, len(shardResults))
+	require.Equal(suite.T(), "apples", shardResults[0].ResultsID)
+}
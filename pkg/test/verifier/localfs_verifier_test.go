@@ -0,0 +1,149 @@
+package verifier
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
+	"github.com/filecoin-project/bacalhau/pkg/ipfs"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
+	_ "github.com/filecoin-project/bacalhau/pkg/logger"
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/transport/inprocess"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/localfs"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifierLocalFSSuite struct {
+	suite.Suite
+}
+
+func TestVerifierLocalFSSuite(t *testing.T) {
+	suite.Run(t, new(VerifierLocalFSSuite))
+}
+
+// Before each test
+func (suite *VerifierLocalFSSuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+}
+
+func (suite *VerifierLocalFSSuite) TestResultsDownloadOverHTTP() {
+	ctx := context.Background()
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	transport, err := inprocess.NewInprocessTransport()
+	require.NoError(suite.T(), err)
+
+	datastore, err := inmemory.NewInMemoryDatastore()
+	require.NoError(suite.T(), err)
+
+	storageProviders, err := executor_util.NewNoopStorageProviders(cm)
+	require.NoError(suite.T(), err)
+
+	ctrl, err := controller.NewController(cm, datastore, transport, storageProviders)
+	require.NoError(suite.T(), err)
+
+	resolver := job.NewStateResolver(ctrl.GetJob, ctrl.GetJobState)
+	resolver.SetWaitTime(100, time.Millisecond*100)
+
+	v, err := localfs.NewVerifier(cm, ctrl.GetJob, ctrl.GetJobState, localfs.VerifierConfig{})
+	require.NoError(suite.T(), err)
+
+	// stands in for the compute node's api server
+	server := httptest.NewServer(v)
+	defer server.Close()
+	v.Config.URL = server.URL
+
+	_, err = requesternode.NewRequesterNode(
+		cm,
+		ctrl,
+		map[verifier.VerifierType]verifier.Verifier{
+			verifier.VerifierLocalFS: v,
+		},
+		requesternode.RequesterNodeConfig{},
+	)
+	require.NoError(suite.T(), err)
+
+	err = ctrl.Start(ctx)
+	require.NoError(suite.T(), err)
+
+	j, err := ctrl.SubmitJob(ctx, executor.JobCreatePayload{
+		ClientID: "123",
+		Spec: executor.JobSpec{
+			Engine:   executor.EngineNoop,
+			Verifier: verifier.VerifierLocalFS,
+			Outputs: []storage.StorageSpec{
+				{Name: "outputs", Path: "/outputs"},
+			},
+		},
+		Deal: executor.JobDeal{
+			Concurrency: 1,
+		},
+	})
+	require.NoError(suite.T(), err)
+
+	// what an executor would leave behind
+	resultsFolder := suite.T().TempDir()
+	for path, content := range map[string]string{
+		"stdout":            "hello",
+		"stderr":            "",
+		"exitCode":          "0",
+		"outputs/apple.txt": "apples",
+	} {
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(filepath.Join(resultsFolder, path)), 0755))
+		require.NoError(suite.T(), os.WriteFile(filepath.Join(resultsFolder, path), []byte(content), 0644))
+	}
+
+	resultsID, err := v.ProcessShardResults(ctx, j.ID, 0, resultsFolder)
+	require.NoError(suite.T(), err)
+
+	err = transport.Publish(ctx, executor.JobEvent{
+		JobID:        j.ID,
+		SourceNodeID: "node-a",
+		EventName:    executor.JobEventCompleted,
+		ResultsID:    resultsID,
+		EventTime:    time.Now(),
+	})
+	require.NoError(suite.T(), err)
+
+	err = resolver.WaitUntilComplete(ctx, j.ID)
+	require.NoError(suite.T(), err)
+
+	resultSet, err := v.GetJobResultSet(ctx, j.ID)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), []storage.StorageSpec{
+		{
+			Name:   "shard0",
+			Path:   "shard0",
+			Engine: storage.StorageSourceURLDownload,
+			URL:    resultsID,
+		},
+	}, resultSet)
+
+	// this is what bacalhau get does with the results
+	outputDir := suite.T().TempDir()
+	err = ipfs.DownloadJob(cm, j, resultSet, ipfs.DownloadSettings{
+		TimeoutSecs: 10,
+		OutputDir:   outputDir,
+	})
+	require.NoError(suite.T(), err)
+
+	output, err := os.ReadFile(filepath.Join(outputDir, "volumes", "outputs", "apple.txt"))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "apples", string(output))
+	stdout, err := os.ReadFile(filepath.Join(outputDir, "stdout"))
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "hello", string(stdout))
+}
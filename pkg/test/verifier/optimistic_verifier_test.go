@@ -0,0 +1,153 @@
+package verifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
+	_ "github.com/filecoin-project/bacalhau/pkg/logger"
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/transport/inprocess"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/optimistic"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifierOptimisticSuite struct {
+	suite.Suite
+}
+
+func TestVerifierOptimisticSuite(t *testing.T) {
+	suite.Run(t, new(VerifierOptimisticSuite))
+}
+
+// Before each test
+func (suite *VerifierOptimisticSuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+}
+
+func (suite *VerifierOptimisticSuite) TestSampledShardsAvoidDisagreeingNodes() {
+	ctx := context.Background()
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	transport, err := inprocess.NewInprocessTransport()
+	require.NoError(suite.T(), err)
+
+	datastore, err := inmemory.NewInMemoryDatastore()
+	require.NoError(suite.T(), err)
+
+	storageProviders, err := executor_util.NewNoopStorageProviders(cm)
+	require.NoError(suite.T(), err)
+
+	ctrl, err := controller.NewController(cm, datastore, transport, storageProviders)
+	require.NoError(suite.T(), err)
+
+	resolver := job.NewStateResolver(ctrl.GetJob, ctrl.GetJobState)
+	resolver.SetWaitTime(100, time.Millisecond*100)
+
+	// nothing is listening on this address - we never publish anything
+	v, err := optimistic.NewVerifier(cm, "/ip4/127.0.0.1/tcp/1", ctrl.GetJob, ctrl.GetJobState, optimistic.VerifierConfig{
+		SampleRate: 1,
+	})
+	require.NoError(suite.T(), err)
+
+	_, err = requesternode.NewRequesterNode(
+		cm,
+		ctrl,
+		map[verifier.VerifierType]verifier.Verifier{
+			verifier.VerifierOptimistic: v,
+		},
+		requesternode.RequesterNodeConfig{
+			MaxNodeDisagreements: 1,
+		},
+	)
+	require.NoError(suite.T(), err)
+
+	err = ctrl.Start(ctx)
+	require.NoError(suite.T(), err)
+
+	nodeIDs := []string{"node-a", "node-b", "node-c"}
+
+	// every node bids and we get back the ids of the nodes that were accepted
+	submitAndBid := func() (executor.Job, []string) {
+		j, err := ctrl.SubmitJob(ctx, executor.JobCreatePayload{
+			ClientID: "123",
+			Spec: executor.JobSpec{
+				Engine:   executor.EngineNoop,
+				Verifier: verifier.VerifierOptimistic,
+			},
+			Deal: executor.JobDeal{
+				Concurrency: 1,
+			},
+		})
+		require.NoError(suite.T(), err)
+
+		for _, nodeID := range nodeIDs {
+			err = transport.Publish(ctx, executor.JobEvent{
+				JobID:        j.ID,
+				SourceNodeID: nodeID,
+				EventName:    executor.JobEventBid,
+				EventTime:    time.Now(),
+			})
+			require.NoError(suite.T(), err)
+		}
+
+		err = resolver.Wait(ctx, j.ID, len(nodeIDs), func(jobState executor.JobState) (bool, error) {
+			for _, shardState := range job.FlattenShardStates(jobState) {
+				if shardState.State == executor.JobStateBidding {
+					return false, nil
+				}
+			}
+			return len(job.FlattenShardStates(jobState)) == len(nodeIDs), nil
+		})
+		require.NoError(suite.T(), err)
+
+		jobState, err := ctrl.GetJobState(ctx, j.ID)
+		require.NoError(suite.T(), err)
+		acceptedNodeIDs := []string{}
+		for _, shardState := range job.GetFilteredShardStates(jobState, executor.JobStateWaiting) {
+			acceptedNodeIDs = append(acceptedNodeIDs, shardState.NodeID)
+		}
+		return j, acceptedNodeIDs
+	}
+
+	// the shard is sampled so it runs on one more node than the deal asks for
+	j, acceptedNodeIDs := submitAndBid()
+	require.Equal(suite.T(), 2, len(acceptedNodeIDs))
+
+	// and the two nodes come back with different results
+	for i, nodeID := range acceptedNodeIDs {
+		err = transport.Publish(ctx, executor.JobEvent{
+			JobID:        j.ID,
+			SourceNodeID: nodeID,
+			EventName:    executor.JobEventCompleted,
+			ResultsID:    []string{"apples", "oranges"}[i],
+			EventTime:    time.Now(),
+		})
+		require.NoError(suite.T(), err)
+	}
+
+	err = resolver.WaitUntilComplete(ctx, j.ID)
+	require.NoError(suite.T(), err)
+
+	resultSet, err := v.GetJobResultSet(ctx, j.ID)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 1, len(resultSet))
+
+	for _, nodeID := range acceptedNodeIDs {
+		require.Equal(suite.T(), 1, v.GetDisagreements(nodeID))
+	}
+
+	// now only the node that has never disagreed gets the next job
+	_, acceptedNodeIDs = submitAndBid()
+	require.Equal(suite.T(), 1, len(acceptedNodeIDs))
+	require.Equal(suite.T(), 0, v.GetDisagreements(acceptedNodeIDs[0]))
+}
@@ -0,0 +1,124 @@
+package verifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	executor_util "github.com/filecoin-project/bacalhau/pkg/executor/util"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/localdb/inmemory"
+	_ "github.com/filecoin-project/bacalhau/pkg/logger"
+	"github.com/filecoin-project/bacalhau/pkg/requesternode"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/transport/inprocess"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/resulthash"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifierResultHashSuite struct {
+	suite.Suite
+}
+
+func TestVerifierResultHashSuite(t *testing.T) {
+	suite.Run(t, new(VerifierResultHashSuite))
+}
+
+// Before each test
+func (suite *VerifierResultHashSuite) SetupTest() {
+	system.InitConfigForTesting(suite.T())
+}
+
+func (suite *VerifierResultHashSuite) TestRequesterComparesResultHashes() {
+	ctx := context.Background()
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	transport, err := inprocess.NewInprocessTransport()
+	require.NoError(suite.T(), err)
+
+	datastore, err := inmemory.NewInMemoryDatastore()
+	require.NoError(suite.T(), err)
+
+	storageProviders, err := executor_util.NewNoopStorageProviders(cm)
+	require.NoError(suite.T(), err)
+
+	ctrl, err := controller.NewController(cm, datastore, transport, storageProviders)
+	require.NoError(suite.T(), err)
+
+	resolver := job.NewStateResolver(ctrl.GetJob, ctrl.GetJobState)
+	resolver.SetWaitTime(100, time.Millisecond*100)
+
+	// nothing is listening on this address - we never publish anything
+	v, err := resulthash.NewVerifier(cm, "/ip4/127.0.0.1/tcp/1", ctrl.GetJob, ctrl.GetJobState, resulthash.VerifierConfig{})
+	require.NoError(suite.T(), err)
+
+	_, err = requesternode.NewRequesterNode(
+		cm,
+		ctrl,
+		map[verifier.VerifierType]verifier.Verifier{
+			verifier.VerifierResultHash: v,
+		},
+		requesternode.RequesterNodeConfig{},
+	)
+	require.NoError(suite.T(), err)
+
+	err = ctrl.Start(ctx)
+	require.NoError(suite.T(), err)
+
+	j, err := ctrl.SubmitJob(ctx, executor.JobCreatePayload{
+		ClientID: "123",
+		Spec: executor.JobSpec{
+			Engine:   executor.EngineNoop,
+			Verifier: verifier.VerifierResultHash,
+		},
+		Deal: executor.JobDeal{
+			Concurrency: 3,
+		},
+	})
+	require.NoError(suite.T(), err)
+
+	// every node has a different results id because their logs differ
+	// but two of them produced the same output
+	type result struct {
+		resultsID   string
+		resultsHash string
+	}
+	results := map[string]result{
+		"node-a": {"cid-a", "apples"},
+		"node-b": {"cid-b", "oranges"},
+		"node-c": {"cid-c", "apples"},
+	}
+	for nodeID, r := range results {
+		err = transport.Publish(ctx, executor.JobEvent{
+			JobID:        j.ID,
+			SourceNodeID: nodeID,
+			EventName:    executor.JobEventCompleted,
+			ResultsID:    r.resultsID,
+			ResultsHash:  r.resultsHash,
+			EventTime:    time.Now(),
+		})
+		require.NoError(suite.T(), err)
+	}
+
+	err = resolver.WaitUntilComplete(ctx, j.ID)
+	require.NoError(suite.T(), err)
+
+	// clients can see the hash each node reported
+	jobState, err := ctrl.GetJobState(ctx, j.ID)
+	require.NoError(suite.T(), err)
+	for nodeID, r := range results {
+		shardState := jobState.Nodes[nodeID].Shards[0]
+		require.Equal(suite.T(), r.resultsHash, shardState.ResultsHash, nodeID)
+		require.Equal(suite.T(), r.resultsHash == "apples", shardState.ResultsAccepted, nodeID)
+	}
+
+	resultSet, err := v.GetJobResultSet(ctx, j.ID)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 1, len(resultSet))
+	require.Contains(suite.T(), []string{"cid-a", "cid-c"}, resultSet[0].Cid)
+}
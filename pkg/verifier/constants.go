@@ -12,6 +12,10 @@ const (
 	verifierUnknown VerifierType = iota // must be first
 	VerifierNoop
 	VerifierIpfs
+	VerifierDeterministic
+	VerifierOptimistic
+	VerifierResultHash
+	VerifierLocalFS
 	verifierDone // must be last
 )
 
@@ -0,0 +1,167 @@
+package deterministic
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/bacalhau/pkg/ipfs"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// a verifier for jobs that are expected to produce identical results every
+// time they run - the results are published to IPFS so the CID doubles as a
+// hash of the results and the requester node accepts the results the majority
+// of executions agreed on and rejects the rest
+type Verifier struct {
+	IPFSClient  *ipfs.Client
+	JobLoader   job.JobLoader
+	StateLoader job.StateLoader
+}
+
+func NewVerifier(
+	cm *system.CleanupManager,
+	ipfsAPIAddr string,
+	jobLoader job.JobLoader,
+	stateLoader job.StateLoader,
+) (*Verifier, error) {
+	cl, err := ipfs.NewClient(ipfsAPIAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug().Msgf("Deterministic verifier initialized for node: %s", ipfsAPIAddr)
+	return &Verifier{
+		IPFSClient:  cl,
+		JobLoader:   jobLoader,
+		StateLoader: stateLoader,
+	}, nil
+}
+
+func (v *Verifier) IsInstalled(ctx context.Context) (bool, error) {
+	ctx, span := newSpan(ctx, "IsInstalled")
+	defer span.End()
+
+	_, err := v.IPFSClient.ID(ctx)
+	return err == nil, err
+}
+
+func (v *Verifier) ProcessShardResults(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	resultsFolder string,
+) (string, error) {
+	ctx, span := newSpan(ctx, "ProcessResultsFolder")
+	defer span.End()
+
+	log.Debug().Msgf("Uploading results folder to ipfs: %s %s", jobID, resultsFolder)
+	return v.IPFSClient.Put(ctx, resultsFolder)
+}
+
+// only results the requester node has accepted are returned
+// so this will error until every shard has been verified
+func (v *Verifier) GetJobResultSet(
+	ctx context.Context,
+	jobID string,
+) ([]storage.StorageSpec, error) {
+	results := []storage.StorageSpec{}
+	ctx, span := newSpan(ctx, "GetJobResultSet")
+	defer span.End()
+
+	shardResults, err := v.getStateResolver().GetAcceptedResults(ctx, jobID)
+	if err != nil {
+		return results, err
+	}
+	for _, shardResult := range shardResults {
+		results = append(results, storage.StorageSpec{
+			Name:   fmt.Sprintf("shard%d", shardResult.ShardIndex),
+			Path:   fmt.Sprintf("shard%d", shardResult.ShardIndex),
+			Engine: storage.StorageSourceIPFS,
+			Cid:    shardResult.ResultsID,
+		})
+	}
+	return results, nil
+}
+
+// merge the result set into a single directory on ipfs
+func (v *Verifier) CombineJobResults(
+	ctx context.Context,
+	jobID string,
+) (storage.StorageSpec, error) {
+	ctx, span := newSpan(ctx, "CombineJobResults")
+	defer span.End()
+
+	return v.IPFSClient.CombineJobResults(ctx, jobID, v.JobLoader, v)
+}
+
+// accept the results the majority of executions agreed on and reject the rest
+func (v *Verifier) VerifyShard(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	results []verifier.ShardResult,
+) ([]verifier.VerificationResult, error) {
+	ctx, span := newSpan(ctx, "VerifyShard")
+	defer span.End()
+
+	j, err := v.JobLoader(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	verdict := countVotes(results, j.Deal.Concurrency)
+	if verdict == "" {
+		log.Warn().Msgf("no majority result for job: %s shard: %d - rejecting all results", jobID, shardIndex)
+	}
+
+	verificationResults := []verifier.VerificationResult{}
+	for _, result := range results {
+		verificationResults = append(verificationResults, verifier.VerificationResult{
+			NodeID:   result.NodeID,
+			Accepted: result.ResultsID != "" && result.ResultsID == verdict,
+		})
+	}
+	return verificationResults, nil
+}
+
+// return the results that more than half of the executions agreed on
+// executions that errored have no results but still count towards the total
+// so it's possible there is no majority - in which case this returns ""
+func countVotes(results []verifier.ShardResult, executions int) string {
+	votes := map[string]int{}
+	for _, result := range results {
+		if result.ResultsID == "" {
+			continue
+		}
+		votes[result.ResultsID]++
+	}
+	if len(results) > executions {
+		executions = len(results)
+	}
+	for resultsID, count := range votes {
+		if count*2 > executions {
+			return resultsID
+		}
+	}
+	return ""
+}
+
+func (v *Verifier) getStateResolver() *job.StateResolver {
+	return job.NewStateResolver(
+		v.JobLoader,
+		v.StateLoader,
+	)
+}
+
+func newSpan(ctx context.Context, apiName string) (context.Context, trace.Span) {
+	return system.Span(ctx, "verifier/deterministic", apiName)
+}
+
+// Compile-time check that Verifier implements the correct interface:
+var _ verifier.Verifier = (*Verifier)(nil)
+var _ verifier.ResultsCombiner = (*Verifier)(nil)
@@ -0,0 +1,83 @@
+package deterministic
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountVotes(t *testing.T) {
+	result := func(nodeID, resultsID string) verifier.ShardResult {
+		return verifier.ShardResult{
+			NodeID:    nodeID,
+			ResultsID: resultsID,
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		results    []verifier.ShardResult
+		executions int
+		expected   string
+	}{
+		{
+			name:       "single execution",
+			results:    []verifier.ShardResult{result("a", "apples")},
+			executions: 1,
+			expected:   "apples",
+		},
+		{
+			name: "majority",
+			results: []verifier.ShardResult{
+				result("a", "apples"),
+				result("b", "oranges"),
+				result("c", "apples"),
+			},
+			executions: 3,
+			expected:   "apples",
+		},
+		{
+			name: "tie",
+			results: []verifier.ShardResult{
+				result("a", "apples"),
+				result("b", "oranges"),
+			},
+			executions: 2,
+			expected:   "",
+		},
+		{
+			name:       "errors count towards the total",
+			results:    []verifier.ShardResult{result("a", "apples")},
+			executions: 3,
+			expected:   "",
+		},
+		{
+			name: "more results than the concurrency",
+			results: []verifier.ShardResult{
+				result("a", "apples"),
+				result("b", "oranges"),
+				result("c", "oranges"),
+				result("d", "pears"),
+			},
+			executions: 2,
+			expected:   "",
+		},
+		{
+			name: "missing results never win",
+			results: []verifier.ShardResult{
+				result("a", ""),
+				result("b", ""),
+				result("c", "apples"),
+			},
+			executions: 3,
+			expected:   "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, countVotes(testCase.results, testCase.executions))
+		})
+	}
+}
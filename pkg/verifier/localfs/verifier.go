@@ -0,0 +1,247 @@
+package localfs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// where the node's api server serves the results this verifier keeps
+const URLPath = "/localfs/"
+
+var resultsIDPattern = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+
+// <job id>-<shard index>-<uuid> as made by ProcessShardResults
+var resultsIDParts = regexp.MustCompile(
+	`^(.+)-([0-9]+)-[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`,
+)
+
+type VerifierConfig struct {
+	// the folder shard results are copied into - a temporary
+	// folder is used if this is not given
+	ResultsDir string
+	// the base URL of this node's api server that clients
+	// can reach it on (e.g. http://10.0.0.1:1234)
+	URL string
+}
+
+// a verifier for when there is no IPFS - results are copied into a folder
+// on the compute node and served from its api server so clients can
+// download them over plain HTTP
+type Verifier struct {
+	JobLoader   job.JobLoader
+	StateLoader job.StateLoader
+	Config      VerifierConfig
+}
+
+func NewVerifier(
+	cm *system.CleanupManager,
+	jobLoader job.JobLoader,
+	stateLoader job.StateLoader,
+	config VerifierConfig,
+) (*Verifier, error) {
+	if config.ResultsDir == "" {
+		dir, err := os.MkdirTemp("", "bacalhau-localfs-results")
+		if err != nil {
+			return nil, err
+		}
+		cm.RegisterCallback(func() error {
+			return os.RemoveAll(dir)
+		})
+		config.ResultsDir = dir
+	}
+	err := os.MkdirAll(config.ResultsDir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug().Msgf("Local filesystem verifier initialized with results dir: %s", config.ResultsDir)
+	return &Verifier{
+		JobLoader:   jobLoader,
+		StateLoader: stateLoader,
+		Config:      config,
+	}, nil
+}
+
+func (v *Verifier) IsInstalled(ctx context.Context) (bool, error) {
+	_, span := newSpan(ctx, "IsInstalled")
+	defer span.End()
+
+	info, err := os.Stat(v.Config.ResultsDir)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir() && v.Config.URL != "", nil
+}
+
+// copy the results into our results folder and return the URL they can
+// be downloaded from - the folder name is random so executions that
+// share a results folder never clash
+func (v *Verifier) ProcessShardResults(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	resultsFolder string,
+) (string, error) {
+	_, span := newSpan(ctx, "ProcessResultsFolder")
+	defer span.End()
+
+	if v.Config.URL == "" {
+		return "", fmt.Errorf("localfs verifier: no URL configured to serve results from")
+	}
+
+	suffix, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	resultsID := fmt.Sprintf("%s-%d-%s", jobID, shardIndex, suffix)
+	targetFolder := filepath.Join(v.Config.ResultsDir, resultsID)
+
+	log.Debug().Msgf("Copying results folder: %s %s -> %s", jobID, resultsFolder, targetFolder)
+	err = system.RunCommand("cp", []string{"-r", resultsFolder, targetFolder})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(v.Config.URL, "/") + URLPath + resultsID, nil
+}
+
+// only results the requester node has accepted are returned
+// so this will error until every shard has been verified
+func (v *Verifier) GetJobResultSet(
+	ctx context.Context,
+	jobID string,
+) ([]storage.StorageSpec, error) {
+	results := []storage.StorageSpec{}
+	ctx, span := newSpan(ctx, "GetJobResultSet")
+	defer span.End()
+
+	shardResults, err := v.getStateResolver().GetAcceptedResults(ctx, jobID)
+	if err != nil {
+		return results, err
+	}
+	for _, shardResult := range shardResults {
+		results = append(results, storage.StorageSpec{
+			Name:   fmt.Sprintf("shard%d", shardResult.ShardIndex),
+			Path:   fmt.Sprintf("shard%d", shardResult.ShardIndex),
+			Engine: storage.StorageSourceURLDownload,
+			URL:    shardResult.ResultsID,
+		})
+	}
+	return results, nil
+}
+
+// like the ipfs verifier we only make the results available so
+// every result is accepted
+func (v *Verifier) VerifyShard(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	results []verifier.ShardResult,
+) ([]verifier.VerificationResult, error) {
+	return verifier.AcceptAllResults(results), nil
+}
+
+// remove the results we copied for every shard of the job
+func (v *Verifier) DeleteJobResults(
+	ctx context.Context,
+	jobID string,
+) error {
+	_, span := newSpan(ctx, "DeleteJobResults")
+	defer span.End()
+
+	if !resultsIDPattern.MatchString(jobID) {
+		return fmt.Errorf("invalid job id: %s", jobID)
+	}
+	resultsFolders, err := filepath.Glob(filepath.Join(v.Config.ResultsDir, jobID+"-*"))
+	if err != nil {
+		return err
+	}
+	for _, resultsFolder := range resultsFolders {
+		log.Debug().Msgf("Removing results folder: %s %s", jobID, resultsFolder)
+		err = os.RemoveAll(resultsFolder)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serve the results folder for the id at the end of the path as a tar.gz
+// - only results the requester node has accepted are served
+func (v *Verifier) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	resultsID := strings.TrimPrefix(req.URL.Path, URLPath)
+	if !resultsIDPattern.MatchString(resultsID) {
+		http.Error(res, fmt.Sprintf("invalid results id: %s", resultsID), http.StatusBadRequest)
+		return
+	}
+	resultsFolder := filepath.Join(v.Config.ResultsDir, resultsID)
+	if !v.isAccepted(req.Context(), resultsID) {
+		http.Error(res, fmt.Sprintf("results not found: %s", resultsID), http.StatusNotFound)
+		return
+	}
+	if _, err := os.Stat(resultsFolder); err != nil {
+		http.Error(res, fmt.Sprintf("results not found: %s", resultsID), http.StatusNotFound)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/gzip")
+	res.WriteHeader(http.StatusOK)
+	if err := writeArchive(resultsFolder, res); err != nil {
+		// too late to change the status code - the client will
+		// see a truncated archive
+		log.Error().Msgf("error sending results %s: %s", resultsID, err)
+	}
+}
+
+// have the results with this id been accepted for their job shard
+func (v *Verifier) isAccepted(ctx context.Context, resultsID string) bool {
+	parts := resultsIDParts.FindStringSubmatch(resultsID)
+	if parts == nil {
+		return false
+	}
+	jobID := parts[1]
+	shardIndex, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return false
+	}
+	jobState, err := v.StateLoader(ctx, jobID)
+	if err != nil {
+		log.Debug().Msgf("error getting job state for results %s: %s", resultsID, err)
+		return false
+	}
+	for _, shardState := range job.GetCompletedShardStates(jobState) {
+		if shardState.ShardIndex == shardIndex && strings.HasSuffix(shardState.ResultsID, URLPath+resultsID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) getStateResolver() *job.StateResolver {
+	return job.NewStateResolver(
+		v.JobLoader,
+		v.StateLoader,
+	)
+}
+
+func newSpan(ctx context.Context, apiName string) (context.Context, trace.Span) {
+	return system.Span(ctx, "verifier/localfs", apiName)
+}
+
+// Compile-time check that Verifier implements the correct interface:
+var _ verifier.Verifier = (*Verifier)(nil)
+var _ verifier.ResultsCleaner = (*Verifier)(nil)
+var _ http.Handler = (*Verifier)(nil)
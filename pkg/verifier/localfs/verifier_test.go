@@ -0,0 +1,156 @@
+package localfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/executor"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeAndDownloadResults(t *testing.T) {
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	// results id -> whether the requester node accepted them
+	shardResults := map[string]bool{}
+	stateLoader := func(ctx context.Context, id string) (executor.JobState, error) {
+		jobState := executor.JobState{Nodes: map[string]executor.JobNodeState{}}
+		for resultsID, accepted := range shardResults {
+			nodeID := fmt.Sprintf("node-%d", len(jobState.Nodes))
+			jobState.Nodes[nodeID] = executor.JobNodeState{Shards: map[int]executor.JobShardState{
+				0: {
+					NodeID:          nodeID,
+					State:           executor.JobStateFinalized,
+					ResultsID:       resultsID,
+					ResultsAccepted: accepted,
+				},
+			}}
+		}
+		return jobState, nil
+	}
+
+	v, err := NewVerifier(cm, nil, stateLoader, VerifierConfig{})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(v)
+	defer server.Close()
+	v.Config.URL = server.URL
+
+	installed, err := v.IsInstalled(context.Background())
+	require.NoError(t, err)
+	require.True(t, installed)
+
+	resultsFolder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(resultsFolder, "stdout"), []byte("hello"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(resultsFolder, "outputs", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(resultsFolder, "outputs", "sub", "a.txt"), []byte("apples"), 0644))
+
+	resultsURL, err := v.ProcessShardResults(context.Background(), "job", 0, resultsFolder)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(resultsURL, server.URL+URLPath), resultsURL)
+	rejectedURL, err := v.ProcessShardResults(context.Background(), "job", 0, resultsFolder)
+	require.NoError(t, err)
+
+	// nothing is served until the requester node has accepted it
+	downloadFolder := filepath.Join(t.TempDir(), "download")
+	require.Error(t, DownloadResults(context.Background(), resultsURL, downloadFolder))
+	shardResults[resultsURL] = true
+	shardResults[rejectedURL] = false
+
+	downloadFolder = filepath.Join(t.TempDir(), "download")
+	err = DownloadResults(context.Background(), resultsURL, downloadFolder)
+	require.NoError(t, err)
+
+	stdout, err := os.ReadFile(filepath.Join(downloadFolder, "stdout"))
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(stdout))
+	output, err := os.ReadFile(filepath.Join(downloadFolder, "outputs", "sub", "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "apples", string(output))
+
+	// only the accepted results we keep can be downloaded
+	for path, status := range map[string]int{
+		URLPath + "missing":                         http.StatusNotFound,
+		strings.TrimPrefix(rejectedURL, server.URL): http.StatusNotFound,
+		URLPath + "..%2f..%2fetc":                   http.StatusBadRequest,
+		URLPath + "job-0-x/../other":                http.StatusBadRequest,
+	} {
+		res, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, status, res.StatusCode, path)
+	}
+	err = DownloadResults(context.Background(), server.URL+URLPath+"missing", downloadFolder)
+	require.Error(t, err)
+}
+
+func TestProcessShardResultsNeedsURL(t *testing.T) {
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	v, err := NewVerifier(cm, nil, nil, VerifierConfig{})
+	require.NoError(t, err)
+
+	installed, err := v.IsInstalled(context.Background())
+	require.NoError(t, err)
+	require.False(t, installed)
+
+	_, err = v.ProcessShardResults(context.Background(), "job", 0, t.TempDir())
+	require.Error(t, err)
+}
+
+func TestDeleteJobResults(t *testing.T) {
+	cm := system.NewCleanupManager()
+	defer cm.Cleanup()
+
+	v, err := NewVerifier(cm, nil, nil, VerifierConfig{URL: "http://127.0.0.1:1234"})
+	require.NoError(t, err)
+
+	resultsFolder := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(resultsFolder, "stdout"), []byte("hello"), 0644))
+	for _, jobID := range []string{"job-a", "job-a", "job-b"} {
+		_, err = v.ProcessShardResults(context.Background(), jobID, 0, resultsFolder)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, v.DeleteJobResults(context.Background(), "job-a"))
+	remaining, err := os.ReadDir(v.Config.ResultsDir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(remaining))
+	require.True(t, strings.HasPrefix(remaining[0].Name(), "job-b-"), remaining[0].Name())
+
+	require.Error(t, v.DeleteJobResults(context.Background(), "../job-b"))
+}
+
+func TestExtractArchiveRejectsPathTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	content := []byte("evil")
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name:     "../evil.txt",
+		Mode:     0644,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+
+	dst := filepath.Join(t.TempDir(), "dst")
+	require.Error(t, extractArchive(&buf, dst))
+	_, err = os.Stat(filepath.Join(filepath.Dir(dst), "evil.txt"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -34,5 +34,15 @@ func (v *Verifier) GetJobResultSet(
 	return []storage.StorageSpec{}, nil
 }
 
+// the noop verifier has no way of telling good results from bad
+func (v *Verifier) VerifyShard(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	results []verifier.ShardResult,
+) ([]verifier.VerificationResult, error) {
+	return verifier.AcceptAllResults(results), nil
+}
+
 // Compile-time check that Verifier implements the correct interface:
 var _ verifier.Verifier = (*Verifier)(nil)
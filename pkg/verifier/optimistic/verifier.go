@@ -0,0 +1,230 @@
+package optimistic
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"time"
+
+	sync "github.com/lukemarsden/golang-mutex-tracer"
+
+	"github.com/filecoin-project/bacalhau/pkg/ipfs"
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/storage"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the fraction of shards we run a second time if not configured
+const DefaultSampleRate = 0.1
+
+type VerifierConfig struct {
+	// the fraction of shards (0 - 1) that are run on one more node than
+	// the deal asks for so we can compare the results
+	SampleRate float64
+}
+
+// a verifier for jobs where running everything several times is too
+// expensive - the results are published to IPFS and a single result
+// is accepted but a sample of shards are run on another node too and
+// if the results disagree we remember which nodes were involved
+type Verifier struct {
+	IPFSClient  *ipfs.Client
+	JobLoader   job.JobLoader
+	StateLoader job.StateLoader
+	Config      VerifierConfig
+	// node id -> how many times its results have disagreed with another node
+	disagreements map[string]int
+	mtx           sync.Mutex
+}
+
+func NewVerifier(
+	cm *system.CleanupManager,
+	ipfsAPIAddr string,
+	jobLoader job.JobLoader,
+	stateLoader job.StateLoader,
+	config VerifierConfig,
+) (*Verifier, error) {
+	cl, err := ipfs.NewClient(ipfsAPIAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Verifier{
+		IPFSClient:    cl,
+		JobLoader:     jobLoader,
+		StateLoader:   stateLoader,
+		Config:        config,
+		disagreements: map[string]int{},
+	}
+	v.mtx.EnableTracerWithOpts(sync.Opts{
+		Threshold: 10 * time.Millisecond,
+		Id:        "OptimisticVerifier.mtx",
+	})
+
+	log.Debug().Msgf("Optimistic verifier initialized for node: %s", ipfsAPIAddr)
+	return v, nil
+}
+
+func (v *Verifier) IsInstalled(ctx context.Context) (bool, error) {
+	ctx, span := newSpan(ctx, "IsInstalled")
+	defer span.End()
+
+	_, err := v.IPFSClient.ID(ctx)
+	return err == nil, err
+}
+
+func (v *Verifier) ProcessShardResults(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	resultsFolder string,
+) (string, error) {
+	ctx, span := newSpan(ctx, "ProcessResultsFolder")
+	defer span.End()
+
+	log.Debug().Msgf("Uploading results folder to ipfs: %s %s", jobID, resultsFolder)
+	return v.IPFSClient.Put(ctx, resultsFolder)
+}
+
+func (v *Verifier) GetJobResultSet(
+	ctx context.Context,
+	jobID string,
+) ([]storage.StorageSpec, error) {
+	results := []storage.StorageSpec{}
+	ctx, span := newSpan(ctx, "GetJobResultSet")
+	defer span.End()
+
+	shardResults, err := v.getStateResolver().GetAcceptedResults(ctx, jobID)
+	if err != nil {
+		return results, err
+	}
+	for _, shardResult := range shardResults {
+		results = append(results, storage.StorageSpec{
+			Name:   fmt.Sprintf("shard%d", shardResult.ShardIndex),
+			Path:   fmt.Sprintf("shard%d", shardResult.ShardIndex),
+			Engine: storage.StorageSourceIPFS,
+			Cid:    shardResult.ResultsID,
+		})
+	}
+	return results, nil
+}
+
+// merge the result set into a single directory on ipfs
+func (v *Verifier) CombineJobResults(
+	ctx context.Context,
+	jobID string,
+) (storage.StorageSpec, error) {
+	ctx, span := newSpan(ctx, "CombineJobResults")
+	defer span.End()
+
+	return v.IPFSClient.CombineJobResults(ctx, jobID, v.JobLoader, v)
+}
+
+// shards that are not sampled only have one result which we accept as is
+// otherwise we accept the results most nodes agreed on and if there is
+// no majority we go with the node that has disagreed the least in the past
+func (v *Verifier) VerifyShard(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	results []verifier.ShardResult,
+) ([]verifier.VerificationResult, error) {
+	_, span := newSpan(ctx, "VerifyShard")
+	defer span.End()
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	votes := map[string]int{}
+	for _, result := range results {
+		votes[result.ResultsID]++
+	}
+	if len(votes) <= 1 {
+		return verifier.AcceptAllResults(results), nil
+	}
+
+	acceptedResultsID := ""
+	for resultsID, count := range votes {
+		if count*2 > len(results) {
+			acceptedResultsID = resultsID
+		}
+	}
+	hasMajority := acceptedResultsID != ""
+
+	if !hasMajority {
+		candidates := make([]verifier.ShardResult, len(results))
+		copy(candidates, results)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if v.disagreements[candidates[i].NodeID] != v.disagreements[candidates[j].NodeID] {
+				return v.disagreements[candidates[i].NodeID] < v.disagreements[candidates[j].NodeID]
+			}
+			return candidates[i].NodeID < candidates[j].NodeID
+		})
+		acceptedResultsID = candidates[0].ResultsID
+	}
+
+	log.Warn().Msgf("results disagree for job: %s shard: %d - accepting %s", jobID, shardIndex, acceptedResultsID)
+
+	verificationResults := []verifier.VerificationResult{}
+	for _, result := range results {
+		// with no majority every node was involved in the disagreement
+		// even the one whose results we went with
+		if result.ResultsID != acceptedResultsID || !hasMajority {
+			v.disagreements[result.NodeID]++
+		}
+		verificationResults = append(verificationResults, verifier.VerificationResult{
+			NodeID:   result.NodeID,
+			Accepted: result.ResultsID == acceptedResultsID,
+		})
+	}
+	return verificationResults, nil
+}
+
+func (v *Verifier) GetExtraExecutions(jobID string, shardIndex int) int {
+	if isSampled(jobID, shardIndex, v.Config.SampleRate) {
+		return 1
+	}
+	return 0
+}
+
+func (v *Verifier) GetDisagreements(nodeID string) int {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	return v.disagreements[nodeID]
+}
+
+func (v *Verifier) getStateResolver() *job.StateResolver {
+	return job.NewStateResolver(
+		v.JobLoader,
+		v.StateLoader,
+	)
+}
+
+// job ids are random so hashing them with the shard index gives us a
+// random sample that is the same every time we ask about the same shard
+func isSampled(jobID string, shardIndex int, sampleRate float64) bool {
+	if sampleRate <= 0 {
+		return false
+	}
+	if sampleRate >= 1 {
+		return true
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(fmt.Sprintf("%s:%d", jobID, shardIndex)))
+	return float64(hash.Sum32())/math.MaxUint32 < sampleRate
+}
+
+func newSpan(ctx context.Context, apiName string) (context.Context, trace.Span) {
+	return system.Span(ctx, "verifier/optimistic", apiName)
+}
+
+// Compile-time check that Verifier implements the correct interface:
+var _ verifier.Verifier = (*Verifier)(nil)
+var _ verifier.ShardSampler = (*Verifier)(nil)
+var _ verifier.DisagreementTracker = (*Verifier)(nil)
+var _ verifier.ResultsCombiner = (*Verifier)(nil)
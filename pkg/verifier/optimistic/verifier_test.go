@@ -0,0 +1,87 @@
+package optimistic
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSampled(t *testing.T) {
+	require.False(t, isSampled("job", 0, 0))
+	require.True(t, isSampled("job", 0, 1))
+
+	sampled := 0
+	for shardIndex := 0; shardIndex < 1000; shardIndex++ {
+		require.Equal(t, isSampled("job", shardIndex, 0.1), isSampled("job", shardIndex, 0.1))
+		if isSampled("job", shardIndex, 0.1) {
+			sampled++
+		}
+	}
+	require.InDelta(t, 100, sampled, 50, fmt.Sprintf("%d of 1000 shards sampled", sampled))
+}
+
+func TestVerifyShard(t *testing.T) {
+	ctx := context.Background()
+	v := &Verifier{
+		disagreements: map[string]int{},
+	}
+
+	result := func(nodeID, resultsID string) verifier.ShardResult {
+		return verifier.ShardResult{
+			NodeID:    nodeID,
+			ResultsID: resultsID,
+		}
+	}
+
+	accepted := func(verificationResults []verifier.VerificationResult) []string {
+		ret := []string{}
+		for _, verificationResult := range verificationResults {
+			if verificationResult.Accepted {
+				ret = append(ret, verificationResult.NodeID)
+			}
+		}
+		return ret
+	}
+
+	// a single unsampled result is accepted without question
+	verificationResults, err := v.VerifyShard(ctx, "job", 0, []verifier.ShardResult{
+		result("a", "apples"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, accepted(verificationResults))
+
+	// sampled results that agree are all accepted
+	verificationResults, err = v.VerifyShard(ctx, "job", 1, []verifier.ShardResult{
+		result("a", "apples"),
+		result("b", "apples"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, accepted(verificationResults))
+	require.Equal(t, 0, v.GetDisagreements("a"))
+	require.Equal(t, 0, v.GetDisagreements("b"))
+
+	// the odd one out is rejected and remembered
+	verificationResults, err = v.VerifyShard(ctx, "job", 2, []verifier.ShardResult{
+		result("a", "apples"),
+		result("b", "apples"),
+		result("c", "oranges"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, accepted(verificationResults))
+	require.Equal(t, 0, v.GetDisagreements("a"))
+	require.Equal(t, 1, v.GetDisagreements("c"))
+
+	// with no majority both nodes disagreed and we go with
+	// the one that has disagreed the least before
+	verificationResults, err = v.VerifyShard(ctx, "job", 3, []verifier.ShardResult{
+		result("c", "oranges"),
+		result("a", "apples"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, accepted(verificationResults))
+	require.Equal(t, 1, v.GetDisagreements("a"))
+	require.Equal(t, 2, v.GetDisagreements("c"))
+}
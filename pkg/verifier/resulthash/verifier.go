@@ -0,0 +1,190 @@
+package resulthash
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/filecoin-project/bacalhau/pkg/job"
+	"github.com/filecoin-project/bacalhau/pkg/system"
+	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/deterministic"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the files every executor writes that are not part of what the job produced
+var DefaultIgnoreFiles = []string{"stdout", "stderr", "exitCode"}
+
+type VerifierConfig struct {
+	// glob patterns (matched against the name of each file and its path
+	// within the results folder) for files that are left out of the hash
+	IgnoreFiles []string
+}
+
+// the same as the deterministic verifier except that rather than comparing
+// the CIDs of the whole results folder - which differ if anything in it
+// does, including logs - we compare a hash of the job's output volumes
+// that leaves out the files we know are different every time
+type Verifier struct {
+	*deterministic.Verifier
+	Config VerifierConfig
+}
+
+func NewVerifier(
+	cm *system.CleanupManager,
+	ipfsAPIAddr string,
+	jobLoader job.JobLoader,
+	stateLoader job.StateLoader,
+	config VerifierConfig,
+) (*Verifier, error) {
+	deterministicVerifier, err := deterministic.NewVerifier(cm, ipfsAPIAddr, jobLoader, stateLoader)
+	if err != nil {
+		return nil, err
+	}
+	if config.IgnoreFiles == nil {
+		config.IgnoreFiles = DefaultIgnoreFiles
+	}
+
+	log.Debug().Msgf("Result hash verifier initialized for node: %s", ipfsAPIAddr)
+	return &Verifier{
+		Verifier: deterministicVerifier,
+		Config:   config,
+	}, nil
+}
+
+// only the folders for the job's output volumes are hashed - if the
+// job has no output volumes we hash everything in the results folder
+func (v *Verifier) HashShardResults(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	resultsPath string,
+) (string, error) {
+	ctx, span := newSpan(ctx, "HashShardResults")
+	defer span.End()
+
+	j, err := v.JobLoader(ctx, jobID)
+	if err != nil {
+		return "", err
+	}
+	folders := []string{}
+	for _, output := range j.Spec.Outputs {
+		folders = append(folders, output.Name)
+	}
+	if len(folders) == 0 {
+		folders = append(folders, ".")
+	}
+
+	log.Debug().Msgf("Hashing results folder: %s %d %s %v", jobID, shardIndex, resultsPath, folders)
+	return hashFolders(resultsPath, folders, v.Config.IgnoreFiles)
+}
+
+// compare the hashes each node reported rather than the results ids
+func (v *Verifier) VerifyShard(
+	ctx context.Context,
+	jobID string,
+	shardIndex int,
+	results []verifier.ShardResult,
+) ([]verifier.VerificationResult, error) {
+	hashResults := []verifier.ShardResult{}
+	for _, result := range results {
+		hashResults = append(hashResults, verifier.ShardResult{
+			NodeID:      result.NodeID,
+			ResultsID:   result.ResultsHash,
+			ResultsHash: result.ResultsHash,
+		})
+	}
+	return v.Verifier.VerifyShard(ctx, jobID, shardIndex, hashResults)
+}
+
+// the hash covers the path and content of every file in the given folders
+// (in a fixed order) and nothing else - so permissions, timestamps and
+// the order the filesystem lists things in make no difference
+func hashFolders(root string, folders, ignoreFiles []string) (string, error) {
+	files := []string{}
+	for _, folder := range folders {
+		folderPath := filepath.Join(root, folder)
+		if _, err := os.Stat(folderPath); os.IsNotExist(err) {
+			// an output volume the job never wrote to
+			continue
+		}
+		err := filepath.WalkDir(folderPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			relativePath, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			ignored, err := isIgnored(relativePath, ignoreFiles)
+			if err != nil {
+				return err
+			}
+			if !ignored {
+				files = append(files, filepath.ToSlash(relativePath))
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	sort.Strings(files)
+
+	hash := sha256.New()
+	for _, file := range files {
+		fileHash, err := hashFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(hash, "%s\x00%s\n", file, fileHash)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func isIgnored(relativePath string, ignoreFiles []string) (bool, error) {
+	for _, pattern := range ignoreFiles {
+		for _, name := range []string{filepath.Base(relativePath), filepath.ToSlash(relativePath)} {
+			matched, err := filepath.Match(pattern, name)
+			if err != nil {
+				return false, fmt.Errorf("resulthash: bad ignore pattern '%s': %v", pattern, err)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func newSpan(ctx context.Context, apiName string) (context.Context, trace.Span) {
+	return system.Span(ctx, "verifier/resulthash", apiName)
+}
+
+// Compile-time check that Verifier implements the correct interface:
+var _ verifier.Verifier = (*Verifier)(nil)
+var _ verifier.ResultsHasher = (*Verifier)(nil)
+var _ verifier.ResultsCombiner = (*Verifier)(nil)
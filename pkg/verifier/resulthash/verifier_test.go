@@ -0,0 +1,84 @@
+package resulthash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for path, content := range files {
+		fullPath := filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	return root
+}
+
+func TestHashFolders(t *testing.T) {
+	outputs := []string{"outputs"}
+
+	hash := func(files map[string]string, folders, ignoreFiles []string) string {
+		h, err := hashFolders(writeFiles(t, files), folders, ignoreFiles)
+		require.NoError(t, err)
+		return h
+	}
+
+	original := hash(map[string]string{
+		"stdout":            "run at 10:00",
+		"outputs/a.txt":     "apples",
+		"outputs/sub/b.txt": "oranges",
+	}, outputs, DefaultIgnoreFiles)
+
+	// logs outside the output volume make no difference
+	require.Equal(t, original, hash(map[string]string{
+		"stdout":            "run at 11:00",
+		"exitCode":          "0",
+		"outputs/a.txt":     "apples",
+		"outputs/sub/b.txt": "oranges",
+	}, outputs, DefaultIgnoreFiles))
+
+	// but the contents and names of the outputs do
+	require.NotEqual(t, original, hash(map[string]string{
+		"outputs/a.txt":     "apples",
+		"outputs/sub/b.txt": "pears",
+	}, outputs, DefaultIgnoreFiles))
+	require.NotEqual(t, original, hash(map[string]string{
+		"outputs/a.txt":     "apples",
+		"outputs/sub/c.txt": "oranges",
+	}, outputs, DefaultIgnoreFiles))
+
+	// ignored files are left out wherever they are
+	require.Equal(t, original, hash(map[string]string{
+		"outputs/a.txt":       "apples",
+		"outputs/sub/b.txt":   "oranges",
+		"outputs/timings.log": "took 3s",
+	}, outputs, append([]string{"*.log"}, DefaultIgnoreFiles...)))
+
+	// with no output volumes the whole folder is hashed bar the ignored files
+	require.Equal(t,
+		hash(map[string]string{"stdout": "a", "result.txt": "apples"}, []string{"."}, DefaultIgnoreFiles),
+		hash(map[string]string{"stdout": "b", "result.txt": "apples"}, []string{"."}, DefaultIgnoreFiles),
+	)
+
+	_, err := hashFolders(writeFiles(t, map[string]string{"a": "b"}), []string{"."}, []string{"["})
+	require.Error(t, err)
+}
+
+func TestHashFoldersIgnoresModTimes(t *testing.T) {
+	files := map[string]string{"outputs/a.txt": "apples"}
+	first := writeFiles(t, files)
+	second := writeFiles(t, files)
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(second, "outputs/a.txt"), past, past))
+
+	firstHash, err := hashFolders(first, []string{"outputs"}, DefaultIgnoreFiles)
+	require.NoError(t, err)
+	secondHash, err := hashFolders(second, []string{"outputs"}, DefaultIgnoreFiles)
+	require.NoError(t, err)
+	require.Equal(t, firstHash, secondHash)
+}
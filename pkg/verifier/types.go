@@ -35,4 +35,86 @@ type Verifier interface {
 		ctx context.Context,
 		jobID string,
 	) ([]storage.StorageSpec, error)
+
+	// once every execution of a shard has completed the requester node
+	// hands the verifier the results each compute node produced and
+	// the verifier decides which of them to accept
+	VerifyShard(
+		ctx context.Context,
+		jobID string,
+		shardIndex int,
+		results []ShardResult,
+	) ([]VerificationResult, error)
+}
+
+// the results a compute node produced for a shard
+type ShardResult struct {
+	NodeID    string
+	ResultsID string
+	// only set if the verifier is a ResultsHasher
+	ResultsHash string
+}
+
+// what the verifier made of the results a compute node produced
+type VerificationResult struct {
+	NodeID   string
+	Accepted bool
+}
+
+// a verifier that wants some shards run on more nodes than the deal
+// asks for so it has results to compare
+type ShardSampler interface {
+	// how many executions of the shard to run on top of the deal's concurrency
+	GetExtraExecutions(jobID string, shardIndex int) int
+}
+
+// a verifier that remembers which compute nodes have produced results
+// that disagreed with other nodes - requester nodes use this to
+// avoid accepting bids from those nodes
+type DisagreementTracker interface {
+	GetDisagreements(nodeID string) int
+}
+
+// a verifier that hashes the results folder in a way that is the same
+// wherever the job ran - compute nodes report the hash alongside the
+// results so nodes can be compared without fetching the results
+type ResultsHasher interface {
+	HashShardResults(
+		ctx context.Context,
+		jobID string,
+		shardIndex int,
+		resultsPath string,
+	) (string, error)
+}
+
+// a verifier whose results can be merged into a single result so that
+// a whole job's output can be passed on as one input
+type ResultsCombiner interface {
+	// build one result out of the result set of every shard - this
+	// errors in the same cases GetJobResultSet does
+	CombineJobResults(
+		ctx context.Context,
+		jobID string,
+	) (storage.StorageSpec, error)
+}
+
+// a verifier that keeps the results itself rather than publishing them
+// somewhere like IPFS - they are deleted when the job is garbage collected
+type ResultsCleaner interface {
+	DeleteJobResults(
+		ctx context.Context,
+		jobID string,
+	) error
+}
+
+// a verifier that does not check results accepts all of them
+func AcceptAllResults(results []ShardResult) []VerificationResult {
+	ret := []VerificationResult{}
+	for _, result := range results {
+		ret = append(ret, VerificationResult{
+			NodeID:   result.NodeID,
+			Accepted: true,
+		})
+	}
+	return ret
 }
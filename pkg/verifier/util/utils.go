@@ -1,18 +1,34 @@
 package util
 
 import (
+	"context"
+
+	"github.com/filecoin-project/bacalhau/pkg/controller"
+	"github.com/filecoin-project/bacalhau/pkg/executor"
 	"github.com/filecoin-project/bacalhau/pkg/job"
 	"github.com/filecoin-project/bacalhau/pkg/system"
 	"github.com/filecoin-project/bacalhau/pkg/verifier"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/deterministic"
 	"github.com/filecoin-project/bacalhau/pkg/verifier/ipfs"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/localfs"
 	"github.com/filecoin-project/bacalhau/pkg/verifier/noop"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/optimistic"
+	"github.com/filecoin-project/bacalhau/pkg/verifier/resulthash"
 )
 
+// the settings for the verifiers that have any
+type VerifierConfig struct {
+	Optimistic optimistic.VerifierConfig
+	ResultHash resulthash.VerifierConfig
+	LocalFS    localfs.VerifierConfig
+}
+
 func NewIPFSVerifiers(
 	cm *system.CleanupManager,
 	ipfsMultiAddress string,
 	jobLoader job.JobLoader,
 	stateLoader job.StateLoader,
+	config VerifierConfig,
 ) (map[verifier.VerifierType]verifier.Verifier, error) {
 	noopVerifier, err := noop.NewVerifier()
 	if err != nil {
@@ -24,12 +40,53 @@ func NewIPFSVerifiers(
 		return nil, err
 	}
 
+	deterministicVerifier, err := deterministic.NewVerifier(cm, ipfsMultiAddress, jobLoader, stateLoader)
+	if err != nil {
+		return nil, err
+	}
+
+	optimisticVerifier, err := optimistic.NewVerifier(cm, ipfsMultiAddress, jobLoader, stateLoader, config.Optimistic)
+	if err != nil {
+		return nil, err
+	}
+
+	resultHashVerifier, err := resulthash.NewVerifier(cm, ipfsMultiAddress, jobLoader, stateLoader, config.ResultHash)
+	if err != nil {
+		return nil, err
+	}
+
+	localfsVerifier, err := localfs.NewVerifier(cm, jobLoader, stateLoader, config.LocalFS)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[verifier.VerifierType]verifier.Verifier{
-		verifier.VerifierNoop: noopVerifier,
-		verifier.VerifierIpfs: ipfsVerifier,
+		verifier.VerifierNoop:          noopVerifier,
+		verifier.VerifierIpfs:          ipfsVerifier,
+		verifier.VerifierDeterministic: deterministicVerifier,
+		verifier.VerifierOptimistic:    optimisticVerifier,
+		verifier.VerifierResultHash:    resultHashVerifier,
+		verifier.VerifierLocalFS:       localfsVerifier,
 	}, nil
 }
 
+// the verifiers a full node runs with the job and state
+// loaders wired up to the controller
+func NewStandardVerifiers(
+	cm *system.CleanupManager,
+	ipfsMultiAddress string,
+	c *controller.Controller,
+	config VerifierConfig,
+) (map[verifier.VerifierType]verifier.Verifier, error) {
+	jobLoader := func(ctx context.Context, id string) (executor.Job, error) {
+		return c.GetJob(ctx, id)
+	}
+	stateLoader := func(ctx context.Context, id string) (executor.JobState, error) {
+		return c.GetJobState(ctx, id)
+	}
+	return NewIPFSVerifiers(cm, ipfsMultiAddress, jobLoader, stateLoader, config)
+}
+
 func NewNoopVerifiers(cm *system.CleanupManager) (map[verifier.VerifierType]verifier.Verifier, error) {
 	noopVerifier, err := noop.NewVerifier()
 	if err != nil {
@@ -37,7 +94,11 @@ func NewNoopVerifiers(cm *system.CleanupManager) (map[verifier.VerifierType]veri
 	}
 
 	return map[verifier.VerifierType]verifier.Verifier{
-		verifier.VerifierNoop: noopVerifier,
-		verifier.VerifierIpfs: noopVerifier,
+		verifier.VerifierNoop:          noopVerifier,
+		verifier.VerifierIpfs:          noopVerifier,
+		verifier.VerifierDeterministic: noopVerifier,
+		verifier.VerifierOptimistic:    noopVerifier,
+		verifier.VerifierResultHash:    noopVerifier,
+		verifier.VerifierLocalFS:       noopVerifier,
 	}, nil
 }
@@ -11,12 +11,16 @@ func _() {
 	_ = x[verifierUnknown-0]
 	_ = x[VerifierNoop-1]
 	_ = x[VerifierIpfs-2]
-	_ = x[verifierDone-3]
+	_ = x[VerifierDeterministic-3]
+	_ = x[VerifierOptimistic-4]
+	_ = x[VerifierResultHash-5]
+	_ = x[VerifierLocalFS-6]
+	_ = x[verifierDone-7]
 }
 
-const _VerifierType_name = "verifierUnknownNoopIpfsverifierDone"
+const _VerifierType_name = "verifierUnknownNoopIpfsDeterministicOptimisticResultHashLocalFSverifierDone"
 
-var _VerifierType_index = [...]uint8{0, 15, 19, 23, 35}
+var _VerifierType_index = [...]uint8{0, 15, 19, 23, 36, 46, 56, 63, 75}
 
 func (i VerifierType) String() string {
 	if i < 0 || i >= VerifierType(len(_VerifierType_index)-1) {